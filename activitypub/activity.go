@@ -0,0 +1,245 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/chdorner/submarine/data"
+)
+
+const (
+	// ContentType is used for ActivityPub requests and responses.
+	ContentType = "application/activity+json"
+	// Public addresses an activity to everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+	// Username is the name of the actor, as in acct:submarine@example.com.
+	Username = "submarine"
+)
+
+var activityContext = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context                   interface{} `json:"@context,omitempty"`
+	ID                        string      `json:"id"`
+	Type                      string      `json:"type"`
+	PreferredUsername         string      `json:"preferredUsername"`
+	Name                      string      `json:"name,omitempty"`
+	Summary                   string      `json:"summary,omitempty"`
+	URL                       string      `json:"url,omitempty"`
+	Inbox                     string      `json:"inbox"`
+	Outbox                    string      `json:"outbox,omitempty"`
+	Followers                 string      `json:"followers,omitempty"`
+	Endpoints                 *Endpoints  `json:"endpoints,omitempty"`
+	ManuallyApprovesFollowers bool        `json:"manuallyApprovesFollowers"`
+	PublicKey                 PublicKey   `json:"publicKey"`
+}
+
+type Hashtag struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Href string `json:"href"`
+}
+
+type Note struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Name         string      `json:"name,omitempty"`
+	Content      string      `json:"content"`
+	URL          string      `json:"url"`
+	Published    string      `json:"published"`
+	Updated      string      `json:"updated,omitempty"`
+	To           []string    `json:"to"`
+	Cc           []string    `json:"cc"`
+	Tag          []Hashtag   `json:"tag"`
+}
+
+type Activity struct {
+	Context   interface{} `json:"@context,omitempty"`
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Actor     string      `json:"actor"`
+	Object    interface{} `json:"object"`
+	Published string      `json:"published,omitempty"`
+	To        []string    `json:"to,omitempty"`
+	Cc        []string    `json:"cc,omitempty"`
+}
+
+type OrderedCollection struct {
+	Context    interface{} `json:"@context,omitempty"`
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	TotalItems int64       `json:"totalItems"`
+	First      string      `json:"first,omitempty"`
+}
+
+type OrderedCollectionPage struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	PartOf       string      `json:"partOf"`
+	Next         string      `json:"next,omitempty"`
+	Prev         string      `json:"prev,omitempty"`
+	OrderedItems []Activity  `json:"orderedItems"`
+}
+
+// IncomingActivity is an activity received in the inbox, the object is
+// either embedded or referenced by its ID.
+type IncomingActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectID returns the ID of the object, whether it's embedded or not.
+func (a *IncomingActivity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	object := a.EmbeddedObject()
+	if object == nil {
+		return ""
+	}
+	return object.ID
+}
+
+// EmbeddedObject returns the object if it is embedded into the activity.
+func (a *IncomingActivity) EmbeddedObject() *IncomingActivity {
+	var object IncomingActivity
+	if json.Unmarshal(a.Object, &object) != nil {
+		return nil
+	}
+	return &object
+}
+
+func ActorID(baseURL string) string {
+	return baseURL + "/actor"
+}
+
+// BaseURL returns the base URL of a local actor ID.
+func BaseURL(actorID string) string {
+	return strings.TrimSuffix(actorID, "/actor")
+}
+
+func KeyID(actorID string) string {
+	return actorID + "#main-key"
+}
+
+func NoteID(baseURL string, bookmark *data.Bookmark) string {
+	return fmt.Sprintf("%s/bookmarks/%d", baseURL, bookmark.ID)
+}
+
+func NewActor(baseURL, publicKeyPem string) Actor {
+	id := ActorID(baseURL)
+	return Actor{
+		Context:           activityContext,
+		ID:                id,
+		Type:              "Service",
+		PreferredUsername: Username,
+		Name:              "Submarine",
+		Summary:           "Public bookmarks",
+		URL:               baseURL + "/",
+		Inbox:             baseURL + "/inbox",
+		Outbox:            baseURL + "/outbox",
+		Followers:         baseURL + "/followers",
+		Endpoints:         &Endpoints{SharedInbox: baseURL + "/inbox"},
+		PublicKey: PublicKey{
+			ID:           KeyID(id),
+			Owner:        id,
+			PublicKeyPem: publicKeyPem,
+		},
+	}
+}
+
+// NewNote renders a bookmark as a note linking to the bookmarked page.
+func NewNote(baseURL string, bookmark *data.Bookmark) Note {
+	title := bookmark.Title
+	if title == "" {
+		title = bookmark.URL
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, `<p><a href="%s">%s</a></p>`, html.EscapeString(bookmark.URL), html.EscapeString(title))
+	if bookmark.Description != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(bookmark.Description))
+	}
+
+	hashtags := []Hashtag{}
+	links := []string{}
+	for _, tag := range bookmark.Tags {
+		href := fmt.Sprintf("%s/tags/%s", baseURL, tag.Name)
+		hashtags = append(hashtags, Hashtag{Type: "Hashtag", Name: "#" + tag.Name, Href: href})
+		links = append(links, fmt.Sprintf(`<a href="%s" class="mention hashtag" rel="tag">#<span>%s</span></a>`,
+			html.EscapeString(href), html.EscapeString(tag.Name)))
+	}
+	if len(links) > 0 {
+		fmt.Fprintf(&content, "<p>%s</p>", strings.Join(links, " "))
+	}
+
+	return Note{
+		ID:           NoteID(baseURL, bookmark),
+		Type:         "Note",
+		AttributedTo: ActorID(baseURL),
+		Name:         title,
+		Content:      content.String(),
+		URL:          bookmark.URL,
+		Published:    bookmark.CreatedAt.UTC().Format(time.RFC3339),
+		Updated:      bookmark.UpdatedAt.UTC().Format(time.RFC3339),
+		To:           []string{Public},
+		Cc:           []string{baseURL + "/followers"},
+		Tag:          hashtags,
+	}
+}
+
+func NewCreate(baseURL string, bookmark *data.Bookmark) Activity {
+	note := NewNote(baseURL, bookmark)
+	return Activity{
+		ID:        note.ID + "#create",
+		Type:      "Create",
+		Actor:     note.AttributedTo,
+		Object:    note,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+	}
+}
+
+// NewAccept accepts the follow request of the follower.
+func NewAccept(follower *data.Follower) Activity {
+	return Activity{
+		ID:    fmt.Sprintf("%s#accepts/%d", follower.LocalActorID, follower.ID),
+		Type:  "Accept",
+		Actor: follower.LocalActorID,
+		Object: Activity{
+			ID:     follower.FollowID,
+			Type:   "Follow",
+			Actor:  follower.ActorID,
+			Object: follower.LocalActorID,
+		},
+	}
+}
+
+// WithContext adds the JSON-LD context to a top-level object.
+func WithContext(activity Activity) Activity {
+	activity.Context = activityContext
+	return activity
+}
@@ -0,0 +1,98 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResponseSize limits how much of a remote response is read.
+const maxResponseSize = 1024 * 1024
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// FetchActor retrieves the actor document of a remote actor.
+func FetchActor(actorID string) (*Actor, error) {
+	req, err := http.NewRequest(http.MethodGet, actorID, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("User-Agent", "submarine")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching actor %s failed with status code %d", actorID, resp.StatusCode)
+	}
+
+	var actor Actor
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&actor)
+	if err != nil {
+		return nil, err
+	}
+	if actor.ID != actorID || actor.Inbox == "" {
+		return nil, fmt.Errorf("actor %s is invalid", actorID)
+	}
+	return &actor, nil
+}
+
+// FetchKey is a KeyFetcher resolving keys through the actor document of
+// their owner.
+func FetchKey(keyID string) (*rsa.PublicKey, string, error) {
+	actorID, _, _ := strings.Cut(keyID, "#")
+	actor, err := FetchActor(actorID)
+	if err != nil {
+		return nil, "", err
+	}
+	if actor.PublicKey.ID != keyID || actor.PublicKey.Owner != actor.ID {
+		return nil, "", errors.New("key doesn't belong to the actor")
+	}
+
+	key, err := ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, actor.ID, nil
+}
+
+// Deliver posts the activity to the inbox, signed with the key of the local
+// actor. It returns the status code of the response.
+func Deliver(inbox, keyID string, key *rsa.PrivateKey, activity Activity) (int, error) {
+	body, err := json.Marshal(WithContext(activity))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", "submarine")
+	err = SignRequest(req, keyID, key, body)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,161 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// signedHeaders are signed on every request, following what Mastodon
+// expects for deliveries.
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// maxClockSkew is how far the Date header of a signed request may be off.
+const maxClockSkew = 12 * time.Hour
+
+// KeyFetcher resolves a key ID to the public key and the ID of the actor
+// owning it.
+type KeyFetcher func(keyID string) (*rsa.PublicKey, string, error)
+
+// SignRequest adds Date, Digest and Signature headers to the request as
+// described by the HTTP signatures draft used in the fediverse.
+func SignRequest(req *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", digest(body))
+	if req.Host == "" {
+		req.Host = req.URL.Host
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, signedHeaders)))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// VerifyRequest checks the signature and digest of a request and returns
+// the ID of the actor which signed it.
+func VerifyRequest(req *http.Request, body []byte, fetch KeyFetcher) (string, error) {
+	params := parseSignature(req.Header.Get("Signature"))
+	keyID := params["keyId"]
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if keyID == "" || err != nil || len(signature) == 0 {
+		return "", errors.New("signature is missing or malformed")
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return "", fmt.Errorf("signature algorithm %s is not supported", algorithm)
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	for _, required := range signedHeaders {
+		if !containsString(headers, required) {
+			return "", fmt.Errorf("signature doesn't cover %s", required)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", errors.New("date is missing or malformed")
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", errors.New("date is too far off")
+	}
+	if req.Header.Get("Digest") != digest(body) {
+		return "", errors.New("digest doesn't match the body")
+	}
+
+	key, owner, err := fetch(keyID)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(signingString(req, headers)))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature)
+	if err != nil {
+		return "", errors.New("signature is invalid")
+	}
+	return owner, nil
+}
+
+func ParsePrivateKey(value string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func ParsePublicKey(value string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := []string{}
+	for _, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s",
+				strings.ToLower(req.Method), req.URL.RequestURI()))
+		case "host":
+			lines = append(lines, "host: "+req.Host)
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", header, req.Header.Get(header)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseSignature(value string) map[string]string {
+	params := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if found {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+func digest(body []byte) string {
+	hash := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package activitypub_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/activitypub"
+)
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyID := "https://bookmarks.example/actor#main-key"
+	fetch := func(id string) (*rsa.PublicKey, string, error) {
+		if id != keyID {
+			return nil, "", errors.New("unknown key")
+		}
+		return &key.PublicKey, "https://bookmarks.example/actor", nil
+	}
+	body := []byte(`{"type":"Follow"}`)
+
+	signed := func() *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "https://social.example/inbox", bytes.NewReader(body))
+		require.NoError(t, err)
+		require.NoError(t, activitypub.SignRequest(req, keyID, key, body))
+		return req
+	}
+
+	// valid
+	req := signed()
+	require.Equal(t, "social.example", req.Host)
+	require.True(t, strings.HasPrefix(req.Header.Get("Digest"), "SHA-256="))
+	require.Contains(t, req.Header.Get("Signature"), `headers="(request-target) host date digest"`)
+	owner, err := activitypub.VerifyRequest(req, body, fetch)
+	require.NoError(t, err)
+	require.Equal(t, "https://bookmarks.example/actor", owner)
+
+	// tampered body
+	_, err = activitypub.VerifyRequest(signed(), []byte(`{"type":"Undo"}`), fetch)
+	require.EqualError(t, err, "digest doesn't match the body")
+
+	// different path
+	req = signed()
+	req.URL.Path = "/other"
+	_, err = activitypub.VerifyRequest(req, body, fetch)
+	require.EqualError(t, err, "signature is invalid")
+
+	// signed by another key
+	req = signed()
+	require.NoError(t, activitypub.SignRequest(req, keyID, otherKey, body))
+	_, err = activitypub.VerifyRequest(req, body, fetch)
+	require.EqualError(t, err, "signature is invalid")
+
+	// old date
+	req = signed()
+	req.Header.Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	_, err = activitypub.VerifyRequest(req, body, fetch)
+	require.EqualError(t, err, "date is too far off")
+
+	// missing signature
+	req = httptest.NewRequest(http.MethodPost, "/inbox", bytes.NewReader(body))
+	_, err = activitypub.VerifyRequest(req, body, fetch)
+	require.EqualError(t, err, "signature is missing or malformed")
+}
+
+func TestParseKeys(t *testing.T) {
+	_, err := activitypub.ParsePrivateKey("invalid")
+	require.Error(t, err)
+	_, err = activitypub.ParsePublicKey("invalid")
+	require.Error(t, err)
+}
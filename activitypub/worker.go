@@ -0,0 +1,164 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+)
+
+const (
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts = 8
+
+	initialBackoff = time.Minute
+	maxBackoff     = 6 * time.Hour
+	batchSize      = 50
+)
+
+// Worker delivers queued activities to the inboxes of followers and
+// reschedules failed attempts with an exponential backoff.
+type Worker struct {
+	repo      *data.ActivityPubRepository
+	bookmarks *data.BookmarkRepository
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		repo:      data.NewActivityPubRepository(db),
+		bookmarks: data.NewBookmarkRepository(db),
+		interval:  15 * time.Second,
+		now:       time.Now,
+	}
+}
+
+// SetClock replaces the clock used to schedule attempts.
+func (w *Worker) SetClock(now func() time.Time) {
+	w.now = now
+}
+
+// Run delivers due activities periodically until the context is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		err := w.DeliverDue()
+		if err != nil {
+			logrus.WithError(err).Error("failed to deliver activities")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery that is due.
+func (w *Worker) DeliverDue() error {
+	deliveries, err := w.repo.DueDeliveries(w.now(), batchSize)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+
+	actorKey, err := w.repo.ActorKey()
+	if err != nil {
+		return err
+	}
+	key, err := ParsePrivateKey(actorKey.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		w.attempt(delivery, key)
+		err = w.repo.SaveAttempt(delivery)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) attempt(delivery *data.ActivityDelivery, key *rsa.PrivateKey) {
+	delivery.Attempts++
+
+	activity, err := w.render(delivery)
+	if err != nil {
+		// the activity can't be rendered anymore, retrying won't help
+		delivery.State = data.ActivityDeliveryFailed
+		delivery.LastError = err.Error()
+		return
+	}
+
+	status, err := Deliver(delivery.Inbox, KeyID(delivery.LocalActorID), key, activity)
+	delivery.LastStatus = status
+
+	now := w.now()
+	if err == nil {
+		delivery.State = data.ActivityDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	logrus.
+		WithError(err).
+		WithField("delivery", delivery.ID).
+		WithField("attempts", delivery.Attempts).
+		Warn("activity delivery failed")
+
+	if delivery.Attempts >= MaxAttempts {
+		delivery.State = data.ActivityDeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+func (w *Worker) render(delivery *data.ActivityDelivery) (Activity, error) {
+	switch delivery.Type {
+	case data.ActivityAccept:
+		follower, err := w.repo.GetFollower(delivery.FollowerID)
+		if err != nil {
+			return Activity{}, err
+		}
+		if follower == nil {
+			return Activity{}, errors.New("follower doesn't exist anymore")
+		}
+		return NewAccept(follower), nil
+	case data.ActivityCreate:
+		bookmark, err := w.bookmarks.Get(delivery.BookmarkID)
+		if err != nil {
+			return Activity{}, err
+		}
+		if bookmark == nil || !bookmark.IsPublic() {
+			return Activity{}, errors.New("bookmark isn't public anymore")
+		}
+		return NewCreate(BaseURL(delivery.LocalActorID), bookmark), nil
+	}
+	return Activity{}, errors.New("activity type is not supported")
+}
+
+// Backoff returns the delay before the next attempt, doubling from one
+// minute up to six hours.
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,162 @@
+package activitypub_test
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/activitypub"
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestWorkerDeliverDue(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+
+	actorKey, err := repo.ActorKey()
+	require.NoError(t, err)
+	publicKey, err := activitypub.ParsePublicKey(actorKey.PublicKey)
+	require.NoError(t, err)
+	fetch := func(keyID string) (*rsa.PublicKey, string, error) {
+		return publicKey, keyID, nil
+	}
+
+	// stand-in inbox which verifies the signatures
+	received := []map[string]interface{}{}
+	signers := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signer, err := activitypub.VerifyRequest(r, body, fetch)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var activity map[string]interface{}
+		_ = json.Unmarshal(body, &activity)
+		received = append(received, activity)
+		signers = append(signers, signer)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	_, err = repo.Follow(data.FollowerCreate{
+		ActorID:      server.URL + "/users/alice",
+		Inbox:        server.URL + "/users/alice/inbox",
+		FollowID:     server.URL + "/follows/1",
+		LocalActorID: "https://bookmarks.example/actor",
+	})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{
+		URL:    "https://example.com",
+		Title:  "Example",
+		Public: true,
+		Tags:   "go",
+	})
+	require.NoError(t, err)
+
+	err = activitypub.NewWorker(db).DeliverDue()
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+	require.Equal(t, []string{"https://bookmarks.example/actor#main-key", "https://bookmarks.example/actor#main-key"}, signers)
+
+	accept := received[0]
+	require.Equal(t, "Accept", accept["type"])
+	require.Equal(t, "https://bookmarks.example/actor", accept["actor"])
+	require.Equal(t, server.URL+"/follows/1", accept["object"].(map[string]interface{})["id"])
+
+	create := received[1]
+	require.Equal(t, "Create", create["type"])
+	note := create["object"].(map[string]interface{})
+	require.Equal(t, "Note", note["type"])
+	require.Equal(t, "https://bookmarks.example/bookmarks/1", note["id"])
+	require.Equal(t, "https://example.com", note["url"])
+	require.Contains(t, note["content"], `<a href="https://example.com">Example</a>`)
+	require.Contains(t, note["content"], "#<span>go</span>")
+	require.Equal(t, []interface{}{activitypub.Public}, note["to"])
+
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	for _, delivery := range deliveries {
+		require.Equal(t, data.ActivityDeliveryDelivered, delivery.State)
+		require.Equal(t, http.StatusAccepted, delivery.LastStatus)
+	}
+}
+
+func TestWorkerRetries(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := repo.Follow(data.FollowerCreate{
+		ActorID:      server.URL + "/users/alice",
+		Inbox:        server.URL + "/users/alice/inbox",
+		LocalActorID: "https://bookmarks.example/actor",
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	worker := activitypub.NewWorker(db)
+	worker.SetClock(func() time.Time { return now })
+	require.NoError(t, worker.DeliverDue())
+	require.Equal(t, 1, requests)
+
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Equal(t, data.ActivityDeliveryPending, deliveries[0].State)
+	require.Equal(t, "unexpected status code 503", deliveries[0].LastError)
+	require.WithinDuration(t, now.Add(activitypub.Backoff(1)), deliveries[0].NextAttemptAt, time.Second)
+
+	// the follower is gone before the retry
+	require.NoError(t, repo.Unfollow(server.URL+"/users/alice"))
+	now = now.Add(time.Hour)
+	require.NoError(t, worker.DeliverDue())
+	require.Equal(t, 1, requests)
+}
+
+func TestWorkerSkipsUnavailableBookmarks(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+	bookmarkRepo := data.NewBookmarkRepository(db)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	_, err := repo.Follow(data.FollowerCreate{
+		ActorID:      server.URL + "/users/alice",
+		Inbox:        server.URL + "/users/alice/inbox",
+		LocalActorID: "https://bookmarks.example/actor",
+	})
+	require.NoError(t, err)
+	bookmark, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com", Public: true})
+	require.NoError(t, err)
+	require.NoError(t, bookmarkRepo.Delete(bookmark.ID))
+
+	require.NoError(t, activitypub.NewWorker(db).DeliverDue())
+	require.Equal(t, 1, requests)
+
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Equal(t, data.ActivityCreate, deliveries[0].Type)
+	require.Equal(t, data.ActivityDeliveryFailed, deliveries[0].State)
+	require.Equal(t, "bookmark isn't public anymore", deliveries[0].LastError)
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseSize limits how much of a response is read.
+const maxResponseSize = 10 * 1024 * 1024
+
+type Bookmark struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Public      bool      `json:"public"`
+	Tags        []string  `json:"tags"`
+	Visits      uint      `json:"visits"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type BookmarkForm struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Public      bool     `json:"public,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type BookmarkList struct {
+	Items      []Bookmark `json:"items"`
+	Prev       string     `json:"prev,omitempty"`
+	Next       string     `json:"next,omitempty"`
+	Suggestion string     `json:"suggestion,omitempty"`
+}
+
+// NextCursor returns the cursor of the next page, empty on the last page.
+func (l *BookmarkList) NextCursor() string {
+	next, err := url.Parse(l.Next)
+	if err != nil {
+		return ""
+	}
+	return next.Query().Get("cursor")
+}
+
+type TagCount struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Count       int64  `json:"count"`
+}
+
+type ListOptions struct {
+	Privacy  string
+	Tag      string
+	Untagged bool
+	Domain   string
+	Sort     string
+	PerPage  int
+	Cursor   string
+}
+
+type SearchOptions struct {
+	Query   string
+	Sort    string
+	PerPage int
+	Cursor  string
+}
+
+// Error is returned for responses with an error status code, Fields is set
+// for invalid bookmarks.
+type Error struct {
+	StatusCode int               `json:"-"`
+	Message    string            `json:"error"`
+	Fields     map[string]string `json:"fields"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status code %d", e.StatusCode)
+	}
+	return e.Message
+}
+
+// Client talks to the JSON API of a submarine server, authenticated with an
+// API token.
+type Client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func New(server, token string) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", server)
+	}
+	if token == "" {
+		return nil, errors.New("an API token is required")
+	}
+
+	return &Client{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *Client) ListBookmarks(opts ListOptions) (*BookmarkList, error) {
+	params := url.Values{}
+	setParam(params, "privacy", opts.Privacy)
+	setParam(params, "tag", opts.Tag)
+	setParam(params, "domain", opts.Domain)
+	setParam(params, "sort", opts.Sort)
+	setParam(params, "cursor", opts.Cursor)
+	if opts.Untagged {
+		params.Set("untagged", "true")
+	}
+	if opts.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+
+	var list BookmarkList
+	err := c.do(http.MethodGet, "/api/v1/bookmarks?"+params.Encode(), nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) SearchBookmarks(opts SearchOptions) (*BookmarkList, error) {
+	params := url.Values{}
+	params.Set("q", opts.Query)
+	setParam(params, "sort", opts.Sort)
+	setParam(params, "cursor", opts.Cursor)
+	if opts.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+
+	var list BookmarkList
+	err := c.do(http.MethodGet, "/api/v1/bookmarks/search?"+params.Encode(), nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetBookmark returns nil without an error when the bookmark doesn't exist.
+func (c *Client) GetBookmark(id uint) (*Bookmark, error) {
+	var bookmark Bookmark
+	err := c.do(http.MethodGet, fmt.Sprintf("/api/v1/bookmarks/%d", id), nil, &bookmark)
+	if apiErr, ok := err.(*Error); ok && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+func (c *Client) CreateBookmark(form BookmarkForm) (*Bookmark, error) {
+	var bookmark Bookmark
+	err := c.do(http.MethodPost, "/api/v1/bookmarks", form, &bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// UpdateBookmark replaces all fields of the bookmark.
+func (c *Client) UpdateBookmark(id uint, form BookmarkForm) (*Bookmark, error) {
+	var bookmark Bookmark
+	err := c.do(http.MethodPut, fmt.Sprintf("/api/v1/bookmarks/%d", id), form, &bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+func (c *Client) DeleteBookmark(id uint) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/api/v1/bookmarks/%d", id), nil, nil)
+}
+
+func (c *Client) ListTags() ([]TagCount, error) {
+	tags := []TagCount{}
+	err := c.do(http.MethodGet, "/api/v1/tags", nil, &tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (c *Client) do(method, path string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("User-Agent", "submarine")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody := io.LimitReader(resp.Body, maxResponseSize)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		// not every error response is JSON, e.g. from a proxy in front
+		_ = json.NewDecoder(respBody).Decode(apiErr)
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(respBody).Decode(result)
+}
+
+func setParam(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
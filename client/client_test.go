@@ -0,0 +1,122 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/client"
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestNew(t *testing.T) {
+	_, err := client.New("bookmarks.example.com", "secret")
+	require.EqualError(t, err, `invalid server URL "bookmarks.example.com"`)
+
+	_, err = client.New("https://bookmarks.example.com", "")
+	require.Error(t, err)
+
+	_, err = client.New("https://bookmarks.example.com/", "secret")
+	require.NoError(t, err)
+}
+
+func TestClient(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	server := httptest.NewServer(router.New(db))
+	defer server.Close()
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "laptop", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	c, err := client.New(server.URL+"/", secret)
+	require.NoError(t, err)
+
+	// create
+	bookmark, err := c.CreateBookmark(client.BookmarkForm{
+		URL:   "https://go.dev",
+		Title: "The Go Programming Language",
+		Tags:  []string{"Go", "languages"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://go.dev", bookmark.URL)
+	require.Equal(t, []string{"Go", "languages"}, bookmark.Tags)
+	require.False(t, bookmark.Public)
+
+	_, err = c.CreateBookmark(client.BookmarkForm{URL: "https://www.rust-lang.org", Title: "Rust Programming Language", Public: true})
+	require.NoError(t, err)
+
+	_, err = c.CreateBookmark(client.BookmarkForm{URL: "invalid"})
+	require.IsType(t, &client.Error{}, err)
+	apiErr := err.(*client.Error)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	require.Equal(t, "Bookmark is invalid", apiErr.Error())
+	require.Contains(t, apiErr.Fields, "URL")
+
+	// get
+	fetched, err := c.GetBookmark(bookmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, bookmark.Title, fetched.Title)
+
+	fetched, err = c.GetBookmark(999)
+	require.NoError(t, err)
+	require.Nil(t, fetched)
+
+	// list
+	list, err := c.ListBookmarks(client.ListOptions{PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "Rust Programming Language", list.Items[0].Title)
+	require.NotEmpty(t, list.NextCursor())
+
+	list, err = c.ListBookmarks(client.ListOptions{PerPage: 1, Cursor: list.NextCursor()})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "The Go Programming Language", list.Items[0].Title)
+	require.Empty(t, list.NextCursor())
+
+	list, err = c.ListBookmarks(client.ListOptions{Tag: "go"})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, bookmark.ID, list.Items[0].ID)
+
+	list, err = c.ListBookmarks(client.ListOptions{Privacy: "public"})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "Rust Programming Language", list.Items[0].Title)
+
+	// search
+	list, err = c.SearchBookmarks(client.SearchOptions{Query: "programing", Sort: "title"})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 2)
+	require.Equal(t, "programming", list.Suggestion)
+	require.Equal(t, "Rust Programming Language", list.Items[0].Title)
+
+	// update
+	updated, err := c.UpdateBookmark(bookmark.ID, client.BookmarkForm{URL: "https://go.dev", Title: "Go", Public: true})
+	require.NoError(t, err)
+	require.Equal(t, "Go", updated.Title)
+	require.True(t, updated.Public)
+	require.Empty(t, updated.Tags)
+
+	// tags
+	tags, err := c.ListTags()
+	require.NoError(t, err)
+	require.Empty(t, tags)
+
+	// delete
+	err = c.DeleteBookmark(bookmark.ID)
+	require.NoError(t, err)
+	fetched, err = c.GetBookmark(bookmark.ID)
+	require.NoError(t, err)
+	require.Nil(t, fetched)
+
+	// invalid token
+	c, err = client.New(server.URL, "invalid")
+	require.NoError(t, err)
+	_, err = c.ListTags()
+	require.EqualError(t, err, "Unauthorized")
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/client"
+	"github.com/chdorner/submarine/data"
+)
+
+// bookmarkBackend is what the bookmark and tag commands work with, either
+// the local database or a server through its JSON API.
+type bookmarkBackend interface {
+	ListBookmarks(opts client.ListOptions) (*client.BookmarkList, error)
+	SearchBookmarks(opts client.SearchOptions) (*client.BookmarkList, error)
+	GetBookmark(id uint) (*client.Bookmark, error)
+	CreateBookmark(form client.BookmarkForm) (*client.Bookmark, error)
+	UpdateBookmark(id uint, form client.BookmarkForm) (*client.Bookmark, error)
+	DeleteBookmark(id uint) error
+	ListTags() ([]client.TagCount, error)
+}
+
+// cliConfig is read from config.json in the submarine directory of the user
+// config directory, e.g. ~/.config/submarine/config.json:
+//
+//	{
+//	  "defaultProfile": "home",
+//	  "profiles": {
+//	    "home": {"server": "https://bookmarks.example.com", "token": "..."}
+//	  }
+//	}
+type cliConfig struct {
+	DefaultProfile string                `json:"defaultProfile"`
+	Profiles       map[string]cliProfile `json:"profiles"`
+}
+
+type cliProfile struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+func addBackendFlags(cmd *cobra.Command) {
+	fl := cmd.PersistentFlags()
+	fl.Bool("json", false, "print JSON instead of tables")
+	fl.String("server", "", "URL of a submarine server to use instead of the database")
+	fl.String("token", "", "API token for --server, defaults to $SUBMARINE_TOKEN")
+	fl.String("profile", "", "server profile from the config file")
+}
+
+// newBookmarkBackend talks to a server when --server or --profile is given,
+// or when the config file has a default profile. An explicit --db always
+// uses the local database.
+func newBookmarkBackend(flags *pflag.FlagSet) (bookmarkBackend, error) {
+	server, _ := flags.GetString("server")
+	token, _ := flags.GetString("token")
+	profileName, _ := flags.GetString("profile")
+
+	if flags.Changed("db") {
+		if server != "" || profileName != "" {
+			return nil, errors.New("--db can't be combined with --server or --profile")
+		}
+		return &localBackend{db: initDBConn(flags, true)}, nil
+	}
+
+	if token == "" {
+		token = os.Getenv("SUBMARINE_TOKEN")
+	}
+	if server == "" {
+		profile, err := loadProfile(profileName)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			server = profile.Server
+			if token == "" {
+				token = profile.Token
+			}
+		}
+	}
+
+	if server == "" {
+		return &localBackend{db: initDBConn(flags, true)}, nil
+	}
+	return client.New(server, token)
+}
+
+// loadProfile returns the named profile or the default profile when name is
+// empty, nil if there is no default.
+func loadProfile(name string) (*cliProfile, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		if name == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	path := filepath.Join(dir, "submarine", "config.json")
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && name == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config cliConfig
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = config.DefaultProfile
+		if name == "" {
+			return nil, nil
+		}
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	return &profile, nil
+}
+
+// localBackend works directly on the database and returns the same types as
+// the JSON API.
+type localBackend struct {
+	db *gorm.DB
+}
+
+func (b *localBackend) ListBookmarks(opts client.ListOptions) (*client.BookmarkList, error) {
+	req := data.BookmarkListRequest{
+		Privacy:              data.BookmarkPrivacyQueryAll,
+		Untagged:             opts.Untagged,
+		Domain:               opts.Domain,
+		Cursor:               opts.Cursor,
+		PerPage:              data.ClampPerPage(opts.PerPage),
+		Sort:                 data.BookmarkSort(opts.Sort),
+		PaginationPathPrefix: "?",
+	}
+	if opts.Privacy != "" {
+		req.Privacy = data.BookmarkPrivacy(opts.Privacy)
+	}
+	if opts.Tag != "" {
+		tag, err := data.NewTagRepository(b.db).GetByName(opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			return &client.BookmarkList{Items: []client.Bookmark{}}, nil
+		}
+		req.TagID = tag.ID
+	}
+
+	result, err := data.NewBookmarkRepository(b.db).List(req)
+	if err != nil {
+		return nil, err
+	}
+
+	list := newBookmarkList(result.Items)
+	if result.HasNext {
+		list.Next = result.NextURL
+	}
+	return list, nil
+}
+
+func (b *localBackend) SearchBookmarks(opts client.SearchOptions) (*client.BookmarkList, error) {
+	result, err := data.NewBookmarkRepository(b.db).Search(data.BookmarkSearchRequest{
+		Query:                opts.Query,
+		Cursor:               opts.Cursor,
+		PerPage:              data.ClampPerPage(opts.PerPage),
+		Sort:                 data.BookmarkSort(opts.Sort),
+		PaginationPathPrefix: "?",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := newBookmarkList(result.Items)
+	list.Suggestion = result.Suggestion
+	if result.HasNext {
+		list.Next = result.NextURL
+	}
+	return list, nil
+}
+
+func (b *localBackend) GetBookmark(id uint) (*client.Bookmark, error) {
+	bookmark, err := data.NewBookmarkRepository(b.db).Get(id)
+	if err != nil || bookmark == nil {
+		return nil, err
+	}
+	return newBookmark(bookmark), nil
+}
+
+func (b *localBackend) CreateBookmark(form client.BookmarkForm) (*client.Bookmark, error) {
+	bookmarkForm := newBookmarkForm(form)
+	validationErr := bookmarkForm.IsValid()
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	bookmark, err := data.NewBookmarkRepository(b.db).Create(bookmarkForm)
+	if err != nil {
+		return nil, err
+	}
+	return newBookmark(bookmark), nil
+}
+
+func (b *localBackend) UpdateBookmark(id uint, form client.BookmarkForm) (*client.Bookmark, error) {
+	bookmarkForm := newBookmarkForm(form)
+	validationErr := bookmarkForm.IsValid()
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	repo := data.NewBookmarkRepository(b.db)
+	err := repo.Update(id, bookmarkForm)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBookmark(id)
+}
+
+func (b *localBackend) DeleteBookmark(id uint) error {
+	return data.NewBookmarkRepository(b.db).Delete(id)
+}
+
+func (b *localBackend) ListTags() ([]client.TagCount, error) {
+	counts, err := data.NewTagRepository(b.db).Counts()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []client.TagCount{}
+	for _, count := range counts {
+		tags = append(tags, client.TagCount{
+			Name:        count.Name,
+			DisplayName: count.DisplayName,
+			Count:       count.Count,
+		})
+	}
+	return tags, nil
+}
+
+func newBookmark(bookmark *data.Bookmark) *client.Bookmark {
+	tags := []string{}
+	for _, tag := range bookmark.Tags {
+		tags = append(tags, tag.DisplayName)
+	}
+	return &client.Bookmark{
+		ID:          bookmark.ID,
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Public:      bookmark.IsPublic(),
+		Tags:        tags,
+		Visits:      bookmark.Visits,
+		CreatedAt:   bookmark.CreatedAt,
+		UpdatedAt:   bookmark.UpdatedAt,
+	}
+}
+
+func newBookmarkList(bookmarks []data.Bookmark) *client.BookmarkList {
+	list := &client.BookmarkList{Items: []client.Bookmark{}}
+	for i := range bookmarks {
+		list.Items = append(list.Items, *newBookmark(&bookmarks[i]))
+	}
+	return list
+}
+
+func newBookmarkForm(form client.BookmarkForm) data.BookmarkForm {
+	return data.BookmarkForm{
+		URL:         form.URL,
+		Title:       form.Title,
+		Description: form.Description,
+		Public:      form.Public,
+		Tags:        strings.Join(form.Tags, ","),
+	}
+}
@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chdorner/submarine/client"
+	"github.com/chdorner/submarine/data"
+)
+
+func NewBookmarksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bookmarks",
+		Short: "Bookmark management, locally or on a server",
+	}
+	addBackendFlags(cmd)
+	cmd.AddCommand(NewBookmarksAddCmd())
+	cmd.AddCommand(NewBookmarksListCmd())
+	cmd.AddCommand(NewBookmarksShowCmd())
+	cmd.AddCommand(NewBookmarksEditCmd())
+	cmd.AddCommand(NewBookmarksDeleteCmd())
+	cmd.AddCommand(NewBookmarksSearchCmd())
+	return cmd
+}
+
+func NewBookmarksAddCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "add <url>",
+		Short: "Add a bookmark",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fl := cmd.Flags()
+			form := client.BookmarkForm{URL: args[0]}
+			form.Title, _ = fl.GetString("title")
+			form.Description, _ = fl.GetString("description")
+			form.Public, _ = fl.GetBool("public")
+			tags, _ := fl.GetString("tags")
+			form.Tags = splitTags(tags)
+
+			bookmark, err := backend.CreateBookmark(form)
+			if err != nil {
+				printValidationError(err)
+				return err
+			}
+
+			if asJSON {
+				return printJSON(bookmark)
+			}
+			fmt.Printf("Successfully added bookmark %d\n", bookmark.ID)
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringP("title", "t", "", "title of the bookmark")
+	fl.String("description", "", "description of the bookmark")
+	fl.String("tags", "", "comma separated tags")
+	fl.Bool("public", false, "make the bookmark public")
+
+	return cmd
+}
+
+func NewBookmarksListCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List bookmarks",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fl := cmd.Flags()
+			opts := client.ListOptions{}
+			opts.Tag, _ = fl.GetString("tag")
+			opts.Untagged, _ = fl.GetBool("untagged")
+			opts.Domain, _ = fl.GetString("domain")
+			opts.Cursor, _ = fl.GetString("cursor")
+			perPage, _ := fl.GetInt("limit")
+			opts.PerPage = data.ClampPerPage(perPage)
+
+			privacy, _ := fl.GetString("privacy")
+			switch data.BookmarkPrivacy(privacy) {
+			case data.BookmarkPrivacyQueryAll:
+			case data.BookmarkPrivacyPublic, data.BookmarkPrivacyPrivate:
+				opts.Privacy = privacy
+			default:
+				return fmt.Errorf("invalid privacy %q, must be one of all, public, private", privacy)
+			}
+
+			sortValue, _ := fl.GetString("sort")
+			sort, ok := data.ParseBookmarkSort(sortValue)
+			if !ok || sort == data.BookmarkSortRelevance {
+				return fmt.Errorf("invalid sort order %q", sortValue)
+			}
+			opts.Sort = string(sort)
+
+			list, err := backend.ListBookmarks(opts)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(newBookmarkListOutput(list))
+			}
+			return printBookmarkList(newBookmarkListOutput(list))
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.String("privacy", string(data.BookmarkPrivacyQueryAll), "only list bookmarks with this privacy (all, public, private)")
+	fl.String("tag", "", "only list bookmarks with this tag")
+	fl.Bool("untagged", false, "only list bookmarks without tags")
+	fl.String("domain", "", "only list bookmarks of this domain")
+	fl.String("sort", string(data.BookmarkSortNewest), "sort order (newest, oldest, title, updated, visited)")
+	fl.IntP("limit", "l", data.DefaultPerPage, fmt.Sprintf("number of bookmarks to list, at most %d", data.MaxPerPage))
+	fl.String("cursor", "", "continue a previous listing")
+
+	return cmd
+}
+
+func NewBookmarksShowCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	return &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a bookmark",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmark, err := getBookmark(backend, args[0])
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(bookmark)
+			}
+			return printBookmark(bookmark)
+		},
+	}
+}
+
+func NewBookmarksEditCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "edit <id>",
+		Short: "Edit a bookmark, only the given fields are changed",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmark, err := getBookmark(backend, args[0])
+			if err != nil {
+				return err
+			}
+
+			form := client.BookmarkForm{
+				URL:         bookmark.URL,
+				Title:       bookmark.Title,
+				Description: bookmark.Description,
+				Public:      bookmark.Public,
+				Tags:        bookmark.Tags,
+			}
+			fl := cmd.Flags()
+			if fl.Changed("url") {
+				form.URL, _ = fl.GetString("url")
+			}
+			if fl.Changed("title") {
+				form.Title, _ = fl.GetString("title")
+			}
+			if fl.Changed("description") {
+				form.Description, _ = fl.GetString("description")
+			}
+			if fl.Changed("tags") {
+				tags, _ := fl.GetString("tags")
+				form.Tags = splitTags(tags)
+			}
+			if fl.Changed("public") {
+				form.Public, _ = fl.GetBool("public")
+			}
+
+			bookmark, err = backend.UpdateBookmark(bookmark.ID, form)
+			if err != nil {
+				printValidationError(err)
+				return err
+			}
+
+			if asJSON {
+				return printJSON(bookmark)
+			}
+			fmt.Printf("Successfully updated bookmark %d\n", bookmark.ID)
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.String("url", "", "URL of the bookmark")
+	fl.StringP("title", "t", "", "title of the bookmark")
+	fl.String("description", "", "description of the bookmark")
+	fl.String("tags", "", "comma separated tags, replaces all tags")
+	fl.Bool("public", false, "make the bookmark public, --public=false makes it private")
+
+	return cmd
+}
+
+func NewBookmarksDeleteCmd() *cobra.Command {
+	var backend bookmarkBackend
+
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a bookmark",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmark, err := getBookmark(backend, args[0])
+			if err != nil {
+				return err
+			}
+
+			err = backend.DeleteBookmark(bookmark.ID)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Successfully deleted bookmark %d\n", bookmark.ID)
+			return nil
+		},
+	}
+}
+
+func NewBookmarksSearchCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search bookmarks",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fl := cmd.Flags()
+			opts := client.SearchOptions{Query: strings.Join(args, " ")}
+			opts.Cursor, _ = fl.GetString("cursor")
+			perPage, _ := fl.GetInt("limit")
+			opts.PerPage = data.ClampPerPage(perPage)
+
+			sortValue, _ := fl.GetString("sort")
+			sort, ok := data.ParseBookmarkSort(sortValue)
+			if !ok {
+				return fmt.Errorf("invalid sort order %q", sortValue)
+			}
+			opts.Sort = string(sort)
+
+			list, err := backend.SearchBookmarks(opts)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(newBookmarkListOutput(list))
+			}
+			return printBookmarkList(newBookmarkListOutput(list))
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.String("sort", string(data.BookmarkSortRelevance), "sort order (relevance, newest, oldest, title, updated, visited)")
+	fl.IntP("limit", "l", data.DefaultPerPage, fmt.Sprintf("number of bookmarks to list, at most %d", data.MaxPerPage))
+	fl.String("cursor", "", "continue a previous search")
+
+	return cmd
+}
+
+func getBookmark(backend bookmarkBackend, arg string) (*client.Bookmark, error) {
+	id, err := parseID(arg, "bookmark")
+	if err != nil {
+		return nil, err
+	}
+	bookmark, err := backend.GetBookmark(id)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark == nil {
+		return nil, fmt.Errorf("bookmark with id %d not found", id)
+	}
+	return bookmark, nil
+}
+
+func splitTags(tags string) []string {
+	names := []string{}
+	for _, name := range strings.Split(tags, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
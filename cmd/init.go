@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
@@ -10,24 +12,36 @@ import (
 
 func NewInitCmd() *cobra.Command {
 	var db *gorm.DB
-	var password string
 
 	cmd := &cobra.Command{
 		Use:   "init",
-		Short: "Initialize or update submarine settings",
+		Short: "Initialize submarine and set the password",
+		Long: `Initialize submarine and set the password.
+
+The password is read from --password-file, $SUBMARINE_PASSWORD or stdin,
+and prompted for when running in a terminal. Once initialized, the password
+is only changed when given explicitly, by a flag, the environment variable or
+piped to stdin, use "submarine passwd" to reset it.`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			ctx := createContext(cmd.Flags())
 			configureLogging(ctx)
 
 			db = initDBConn(cmd.Flags(), true)
-			password, _ = cmd.Flags().GetString("password")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			repo := data.NewSettingsRepository(db)
-			settings := data.SettingsUpsert{
-				Password: password,
+			if repo.IsInitialized() && !hasPasswordSource(cmd.Flags(), os.Stdin) {
+				logrus.Info("submarine is already initialized, use `submarine passwd` to change the password")
+				return nil
+			}
+
+			password, err := readPassword(cmd.Flags(), os.Stdin)
+			if err != nil {
+				return err
 			}
-			err := repo.Upsert(settings)
+			err = repo.Upsert(data.SettingsUpsert{
+				Password: password,
+			})
 			if err != nil {
 				return err
 			}
@@ -37,8 +51,7 @@ func NewInitCmd() *cobra.Command {
 		},
 	}
 
-	fl := cmd.Flags()
-	fl.StringP("password", "p", "", "set password")
+	addPasswordFlags(cmd.Flags())
 
 	return cmd
 }
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chdorner/submarine/client"
+	"github.com/chdorner/submarine/data"
+)
+
+// bookmarkListOutput is the JSON representation of a page of bookmarks.
+type bookmarkListOutput struct {
+	Items      []client.Bookmark `json:"items"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+	Suggestion string            `json:"suggestion,omitempty"`
+}
+
+func newBookmarkListOutput(list *client.BookmarkList) bookmarkListOutput {
+	return bookmarkListOutput{
+		Items:      list.Items,
+		NextCursor: list.NextCursor(),
+		Suggestion: list.Suggestion,
+	}
+}
+
+func bookmarkPrivacy(bookmark *client.Bookmark) data.BookmarkPrivacy {
+	if bookmark.Public {
+		return data.BookmarkPrivacyPublic
+	}
+	return data.BookmarkPrivacyPrivate
+}
+
+func printJSON(value interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+func printBookmark(bookmark *client.Bookmark) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", bookmark.ID)
+	fmt.Fprintf(w, "URL:\t%s\n", bookmark.URL)
+	fmt.Fprintf(w, "Title:\t%s\n", bookmark.Title)
+	fmt.Fprintf(w, "Description:\t%s\n", bookmark.Description)
+	fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(bookmark.Tags, ", "))
+	fmt.Fprintf(w, "Privacy:\t%s\n", bookmarkPrivacy(bookmark))
+	fmt.Fprintf(w, "Visits:\t%d\n", bookmark.Visits)
+	fmt.Fprintf(w, "Created:\t%s\n", bookmark.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Updated:\t%s\n", bookmark.UpdatedAt.Format(time.RFC3339))
+	return w.Flush()
+}
+
+func printBookmarkList(list bookmarkListOutput) error {
+	if list.Suggestion != "" {
+		fmt.Printf("Showing results for %q\n", list.Suggestion)
+	}
+	if len(list.Items) == 0 {
+		fmt.Println("No bookmarks found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tURL\tTAGS\tPRIVACY\tCREATED")
+	for i := range list.Items {
+		bookmark := &list.Items[i]
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			bookmark.ID,
+			truncate(bookmark.Title, 50),
+			truncate(bookmark.URL, 60),
+			strings.Join(bookmark.Tags, ", "),
+			bookmarkPrivacy(bookmark),
+			bookmark.CreatedAt.Format("2006-01-02"),
+		)
+	}
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if list.NextCursor != "" {
+		fmt.Printf("\nMore bookmarks available, continue with --cursor %s\n", list.NextCursor)
+	}
+	return nil
+}
+
+func printTags(tags []client.TagCount) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDISPLAY NAME\tBOOKMARKS")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", tag.Name, tag.DisplayName, tag.Count)
+	}
+	return w.Flush()
+}
+
+// printValidationError prints the invalid fields of a bookmark, whether they
+// were reported by the database or a server.
+func printValidationError(err error) {
+	var fields map[string]string
+	switch err := err.(type) {
+	case *data.ValidationError:
+		fields = err.Fields
+	case *client.Error:
+		fields = err.Fields
+	}
+	for field, msg := range fields {
+		fmt.Printf("%s: %s\n", field, msg)
+	}
+}
+
+// truncate shortens s to at most max characters for table columns.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func parseID(arg, kind string) (uint, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s id %q", kind, arg)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+)
+
+func NewPasswdCmd() *cobra.Command {
+	var db *gorm.DB
+
+	cmd := &cobra.Command{
+		Use:   "passwd",
+		Short: "Reset the password and log out all sessions",
+		Long: `Reset the password and log out all sessions.
+
+The password is read from --password-file, $SUBMARINE_PASSWORD or stdin,
+and prompted for when running in a terminal.`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			db = initDBConn(cmd.Flags(), true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo := data.NewSettingsRepository(db)
+			if !repo.IsInitialized() {
+				return errors.New("submarine is not initialized yet, run `submarine init` first")
+			}
+
+			password, err := readPassword(cmd.Flags(), os.Stdin)
+			if err != nil {
+				return err
+			}
+			err = repo.Upsert(data.SettingsUpsert{
+				Password: password,
+			})
+			if err != nil {
+				return err
+			}
+			err = data.NewSessionRepository(db).DeleteAll()
+			if err != nil {
+				return err
+			}
+
+			logrus.Info("Successfully changed password, all sessions were logged out")
+			return nil
+		},
+	}
+
+	addPasswordFlags(cmd.Flags())
+
+	return cmd
+}
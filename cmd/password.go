@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/chdorner/submarine/data"
+)
+
+// passwordEnv is read when no password flag is given, e.g. by provisioning
+// scripts.
+const passwordEnv = "SUBMARINE_PASSWORD"
+
+func addPasswordFlags(fl *pflag.FlagSet) {
+	fl.StringP("password", "p", "", "set password")
+	_ = fl.MarkDeprecated("password", "it leaks into shell history and process listings, use --password-file, $"+passwordEnv+" or the prompt instead")
+	fl.String("password-file", "", "read the password from the first line of this file, - for stdin")
+}
+
+// hasPasswordSource reports whether a password was given explicitly, without
+// prompting for it. Stdin counts when a pipe or file is redirected to it, but
+// not when it is a terminal or a device like /dev/null.
+func hasPasswordSource(fl *pflag.FlagSet, stdin *os.File) bool {
+	if fl.Changed("password") || fl.Changed("password-file") || os.Getenv(passwordEnv) != "" {
+		return true
+	}
+	info, err := stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
+// readPassword returns the password from --password-file, the deprecated
+// --password, $SUBMARINE_PASSWORD or stdin, in that order. On a terminal it
+// prompts without echo and asks for confirmation.
+func readPassword(fl *pflag.FlagSet, stdin *os.File) (string, error) {
+	password, err := readPasswordSource(fl, stdin)
+	if err != nil {
+		return "", err
+	}
+	if !data.IsValidPassword(password) {
+		return "", fmt.Errorf("password must be at least %d characters long", data.MinPasswordLength)
+	}
+	return password, nil
+}
+
+func readPasswordSource(fl *pflag.FlagSet, stdin *os.File) (string, error) {
+	if fl.Changed("password") && fl.Changed("password-file") {
+		return "", errors.New("--password can't be combined with --password-file")
+	}
+
+	if path, _ := fl.GetString("password-file"); path != "" {
+		if path == "-" {
+			return readPasswordLine(stdin)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return readPasswordLine(f)
+	}
+	if fl.Changed("password") {
+		return fl.GetString("password")
+	}
+	if password := os.Getenv(passwordEnv); password != "" {
+		return password, nil
+	}
+
+	fd := int(stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readPasswordLine(stdin)
+	}
+	return promptPassword(fd)
+}
+
+func promptPassword(fd int) (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm password: ")
+	confirmation, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	if string(password) != string(confirmation) {
+		return "", errors.New("passwords don't match")
+	}
+	return string(password), nil
+}
+
+// readPasswordLine reads the first line, without its line break.
+func readPasswordLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
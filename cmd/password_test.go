@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// pipedStdin returns the read end of a pipe containing input, or /dev/null
+// when input is empty.
+func pipedStdin(t *testing.T, input string) *os.File {
+	if input == "" {
+		f, err := os.Open(os.DevNull)
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	_, err = w.WriteString(input)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return r
+}
+
+func passwordFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	fl := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fl.SetOutput(io.Discard)
+	addPasswordFlags(fl)
+	require.NoError(t, fl.Parse(args))
+	return fl
+}
+
+func TestReadPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\nsecond line\n"), 0600))
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		env      string
+		stdin    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "password file before everything else",
+			args:     []string{"--password-file", path},
+			env:      "from-env",
+			stdin:    "from-stdin\n",
+			expected: "from-file",
+		},
+		{
+			name:     "password file from stdin",
+			args:     []string{"--password-file", "-"},
+			env:      "from-env",
+			stdin:    "from-stdin\r\n",
+			expected: "from-stdin",
+		},
+		{
+			name:     "deprecated flag before environment",
+			args:     []string{"-p", "from-flag"},
+			env:      "from-env",
+			stdin:    "from-stdin\n",
+			expected: "from-flag",
+		},
+		{
+			name:     "environment before stdin",
+			env:      "from-env",
+			stdin:    "from-stdin\n",
+			expected: "from-env",
+		},
+		{
+			name:     "stdin without line break",
+			stdin:    "from-stdin",
+			expected: "from-stdin",
+		},
+		{
+			name: "flag and file",
+			args: []string{"-p", "from-flag", "--password-file", path},
+			err:  "--password can't be combined with --password-file",
+		},
+		{
+			name: "missing file",
+			args: []string{"--password-file", filepath.Join(t.TempDir(), "missing")},
+			err:  "no such file or directory",
+		},
+		{
+			name:  "too short",
+			stdin: "short\n",
+			err:   "password must be at least 8 characters long",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(passwordEnv, tc.env)
+			password, err := readPassword(passwordFlags(t, tc.args...), pipedStdin(t, tc.stdin))
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, password)
+		})
+	}
+}
+
+func TestHasPasswordSource(t *testing.T) {
+	t.Setenv(passwordEnv, "")
+	require.False(t, hasPasswordSource(passwordFlags(t), pipedStdin(t, "")))
+	require.True(t, hasPasswordSource(passwordFlags(t), pipedStdin(t, "from-stdin\n")))
+	require.True(t, hasPasswordSource(passwordFlags(t, "--password-file", "-"), pipedStdin(t, "")))
+
+	t.Setenv(passwordEnv, "from-env")
+	require.True(t, hasPasswordSource(passwordFlags(t), pipedStdin(t, "")))
+}
@@ -31,6 +31,11 @@ func init() {
 	rootCmd.AddCommand(NewServeCmd())
 	rootCmd.AddCommand(NewDBCmd())
 	rootCmd.AddCommand(NewInitCmd())
+	rootCmd.AddCommand(NewPasswdCmd())
+	rootCmd.AddCommand(NewTwoFactorCmd())
+	rootCmd.AddCommand(NewTokensCmd())
+	rootCmd.AddCommand(NewBookmarksCmd())
+	rootCmd.AddCommand(NewTagsCmd())
 	rootCmd.AddCommand(NewVersionCommand())
 }
 
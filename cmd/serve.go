@@ -1,14 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 
+	"github.com/chdorner/submarine/activitypub"
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/webhook"
 )
 
 func NewServeCmd() *cobra.Command {
@@ -31,6 +34,11 @@ func NewServeCmd() *cobra.Command {
 				return errors.New("submarine is not initialized yet, run `submarine init` first")
 			}
 
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go webhook.NewWorker(db).Run(ctx)
+			go activitypub.NewWorker(db).Run(ctx)
+
 			e := router.New(db)
 			logrus.WithField("addr", addr).Info("starting submarine")
 			return e.Start(addr)
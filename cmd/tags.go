@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewTagsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags",
+		Short: "Tag management, locally or on a server",
+	}
+	addBackendFlags(cmd)
+	cmd.AddCommand(NewTagsListCmd())
+	return cmd
+}
+
+func NewTagsListCmd() *cobra.Command {
+	var backend bookmarkBackend
+	var asJSON bool
+
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List tags with their number of bookmarks",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			backend, err = newBookmarkBackend(cmd.Flags())
+			asJSON, _ = cmd.Flags().GetBool("json")
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tags, err := backend.ListTags()
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(tags)
+			}
+			return printTags(tags)
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+)
+
+func NewTokensCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "API token management",
+	}
+	cmd.AddCommand(NewTokensCreateCmd())
+	cmd.AddCommand(NewTokensListCmd())
+	cmd.AddCommand(NewTokensRevokeCmd())
+	return cmd
+}
+
+func NewTokensCreateCmd() *cobra.Command {
+	var db *gorm.DB
+	var name string
+	var scopes []string
+	var expiresIn int
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new API token",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			db = initDBConn(cmd.Flags(), true)
+			name, _ = cmd.Flags().GetString("name")
+			scopes, _ = cmd.Flags().GetStringSlice("scope")
+			expiresIn, _ = cmd.Flags().GetInt("expires-in")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := data.TokenCreate{Name: name}
+			for _, scope := range scopes {
+				req.Scopes = append(req.Scopes, data.TokenScope(scope))
+			}
+			if expiresIn > 0 {
+				expiresAt := time.Now().AddDate(0, 0, expiresIn)
+				req.ExpiresAt = &expiresAt
+			}
+
+			repo := data.NewTokenRepository(db)
+			_, secret, err := repo.Create(req)
+			if err != nil {
+				if validationErr, ok := err.(*data.ValidationError); ok {
+					for field, msg := range validationErr.Fields {
+						fmt.Printf("%s: %s\n", field, msg)
+					}
+				}
+				return err
+			}
+
+			fmt.Println("Successfully created token, it won't be shown again:")
+			fmt.Println(secret)
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringP("name", "n", "", "name of the token")
+	fl.StringSliceP("scope", "s", []string{string(data.TokenScopeRead), string(data.TokenScopeWrite)}, "scopes of the token (read, write)")
+	fl.Int("expires-in", 0, "days until the token expires, never expires when 0")
+
+	return cmd
+}
+
+func NewTokensListCmd() *cobra.Command {
+	var db *gorm.DB
+
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			db = initDBConn(cmd.Flags(), true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo := data.NewTokenRepository(db)
+			tokens, err := repo.List()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED\tLAST USED\tEXPIRES")
+			for _, token := range tokens {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+					token.ID,
+					token.Name,
+					token.Scopes,
+					token.CreatedAt.Format(time.RFC3339),
+					formatOptionalTime(token.LastUsedAt, "never"),
+					formatOptionalTime(token.ExpiresAt, "never"),
+				)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func NewTokensRevokeCmd() *cobra.Command {
+	var db *gorm.DB
+
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			db = initDBConn(cmd.Flags(), true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid token id %q", args[0])
+			}
+
+			repo := data.NewTokenRepository(db)
+			err = repo.Revoke(uint(id))
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Successfully revoked token")
+			return nil
+		},
+	}
+}
+
+func formatOptionalTime(t *time.Time, fallback string) string {
+	if t == nil {
+		return fallback
+	}
+	return t.Format(time.RFC3339)
+}
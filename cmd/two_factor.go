@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+)
+
+func NewTwoFactorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "2fa",
+		Short: "Two-factor authentication management",
+	}
+	cmd.AddCommand(NewTwoFactorDisableCmd())
+	return cmd
+}
+
+func NewTwoFactorDisableCmd() *cobra.Command {
+	var db *gorm.DB
+
+	return &cobra.Command{
+		Use:   "disable",
+		Short: "Disable two-factor authentication",
+		Long: `Disable two-factor authentication.
+
+Use this to regain access after losing both the authenticator app and the
+recovery codes, it can be enabled again in the settings after logging in.`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			ctx := createContext(cmd.Flags())
+			configureLogging(ctx)
+
+			db = initDBConn(cmd.Flags(), true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo := data.NewSettingsRepository(db)
+			settings, err := repo.Get()
+			if err != nil {
+				return err
+			}
+			if settings == nil || !settings.TwoFactorEnabled() {
+				logrus.Info("Two-factor authentication is not enabled")
+				return nil
+			}
+
+			err = repo.DisableTwoFactor()
+			if err != nil {
+				return err
+			}
+
+			logrus.Info("Successfully disabled two-factor authentication")
+			return nil
+		},
+	}
+}
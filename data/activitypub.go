@@ -0,0 +1,96 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ActivityType string
+
+const (
+	ActivityAccept ActivityType = "Accept"
+	ActivityCreate ActivityType = "Create"
+)
+
+type ActivityDeliveryState string
+
+const (
+	ActivityDeliveryPending   ActivityDeliveryState = "pending"
+	ActivityDeliveryDelivered ActivityDeliveryState = "delivered"
+	ActivityDeliveryFailed    ActivityDeliveryState = "failed"
+)
+
+// ActorKey is the key pair of the ActivityPub actor, used for signing
+// deliveries. It is generated when it is first needed.
+type ActorKey struct {
+	gorm.Model
+	PrivateKey string
+	PublicKey  string
+}
+
+// Follower is a remote ActivityPub actor following this instance.
+// LocalActorID is the actor ID it followed, which decides the base URL of
+// the activities delivered to it.
+type Follower struct {
+	gorm.Model
+	ActorID      string `gorm:"unique"`
+	Inbox        string
+	SharedInbox  string
+	FollowID     string
+	LocalActorID string
+}
+
+type FollowerCreate struct {
+	ActorID      string
+	Inbox        string
+	SharedInbox  string
+	FollowID     string
+	LocalActorID string
+}
+
+// ActivityDelivery is an activity queued for delivery to a remote inbox. The
+// activity itself is rendered when it is sent, Accept deliveries reference
+// the follower and Create deliveries the bookmark.
+type ActivityDelivery struct {
+	gorm.Model
+	Type          ActivityType
+	Inbox         string
+	LocalActorID  string
+	FollowerID    uint
+	BookmarkID    uint
+	State         ActivityDeliveryState `gorm:"index;default:'pending'"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastStatus    int
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+// DeliveryInbox prefers the shared inbox, so that a server hosting several
+// followers receives every activity once.
+func (f *Follower) DeliveryInbox() string {
+	if f.SharedInbox != "" {
+		return f.SharedInbox
+	}
+	return f.Inbox
+}
+
+func (req *FollowerCreate) IsValid() *ValidationError {
+	isErr := false
+	fields := make(map[string]string)
+
+	if req.ActorID == "" {
+		isErr = true
+		fields["ActorID"] = "Actor is required"
+	}
+	if req.Inbox == "" {
+		isErr = true
+		fields["Inbox"] = "Inbox is required"
+	}
+
+	if isErr {
+		return NewValidationError("Follower is invalid", fields)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ActivityPubRepository struct {
+	db *gorm.DB
+}
+
+func NewActivityPubRepository(db *gorm.DB) *ActivityPubRepository {
+	return &ActivityPubRepository{db}
+}
+
+// ActorKey returns the key pair of the actor, generating it on first use.
+func (r *ActivityPubRepository) ActorKey() (*ActorKey, error) {
+	var key ActorKey
+	result := r.db.Order("id asc").Limit(1).Find(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &key, nil
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key = ActorKey{
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		})),
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "PUBLIC KEY",
+			Bytes: publicKey,
+		})),
+	}
+	result = r.db.Create(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// Follow stores the follower, or updates it when it follows again, and
+// queues the Accept activity for it.
+func (r *ActivityPubRepository) Follow(req FollowerCreate) (*Follower, error) {
+	validationErr := req.IsValid()
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	follower := &Follower{
+		ActorID:      req.ActorID,
+		Inbox:        req.Inbox,
+		SharedInbox:  req.SharedInbox,
+		FollowID:     req.FollowID,
+		LocalActorID: req.LocalActorID,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "actor_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"updated_at", "inbox", "shared_inbox", "follow_id", "local_actor_id"}),
+		}).Create(follower).Error
+		if err != nil {
+			return err
+		}
+		// the ID isn't returned on conflict
+		err = tx.Where("actor_id = ?", req.ActorID).First(follower).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&ActivityDelivery{
+			Type:          ActivityAccept,
+			Inbox:         follower.Inbox,
+			LocalActorID:  follower.LocalActorID,
+			FollowerID:    follower.ID,
+			State:         ActivityDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return follower, nil
+}
+
+// Unfollow removes the follower together with its pending Accept
+// deliveries, it's not an error if the actor isn't following.
+func (r *ActivityPubRepository) Unfollow(actorID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var follower Follower
+		result := tx.Where("actor_id = ?", actorID).Limit(1).Find(&follower)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		err := tx.Where("follower_id = ? AND state = ?", follower.ID, ActivityDeliveryPending).
+			Delete(&ActivityDelivery{}).
+			Error
+		if err != nil {
+			return err
+		}
+		// hard delete so that the actor can follow again
+		return tx.Unscoped().Delete(&follower).Error
+	})
+}
+
+func (r *ActivityPubRepository) Followers() ([]Follower, error) {
+	var followers []Follower
+	err := r.db.Order("created_at asc").Find(&followers).Error
+	if err != nil {
+		return nil, err
+	}
+	return followers, nil
+}
+
+func (r *ActivityPubRepository) GetFollower(id uint) (*Follower, error) {
+	var follower Follower
+	result := r.db.Limit(1).Find(&follower, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &follower, nil
+}
+
+func (r *ActivityPubRepository) Deliveries(limit int) ([]ActivityDelivery, error) {
+	var deliveries []ActivityDelivery
+	err := r.db.Order("created_at desc, id desc").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// DueDeliveries returns pending deliveries which should be attempted at the
+// given time.
+func (r *ActivityPubRepository) DueDeliveries(now time.Time, limit int) ([]ActivityDelivery, error) {
+	var deliveries []ActivityDelivery
+	err := r.db.
+		Where("state = ?", ActivityDeliveryPending).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveAttempt stores the outcome of a delivery attempt.
+func (r *ActivityPubRepository) SaveAttempt(delivery *ActivityDelivery) error {
+	return r.db.Model(delivery).
+		Select("State", "Attempts", "NextAttemptAt", "LastStatus", "LastError", "DeliveredAt").
+		Updates(delivery).
+		Error
+}
+
+// enqueueBookmarkActivity queues a Create activity of a public bookmark for
+// every inbox of the followers, it is called within the transaction which
+// creates the bookmark.
+func enqueueBookmarkActivity(tx *gorm.DB, bookmark *Bookmark) error {
+	if !bookmark.IsPublic() {
+		return nil
+	}
+
+	var followers []Follower
+	err := tx.Order("id asc").Find(&followers).Error
+	if err != nil {
+		return err
+	}
+
+	type target struct{ inbox, localActorID string }
+	seen := map[target]bool{}
+	deliveries := []ActivityDelivery{}
+	now := time.Now()
+	for _, follower := range followers {
+		t := target{follower.DeliveryInbox(), follower.LocalActorID}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		deliveries = append(deliveries, ActivityDelivery{
+			Type:          ActivityCreate,
+			Inbox:         t.inbox,
+			LocalActorID:  t.localActorID,
+			BookmarkID:    bookmark.ID,
+			State:         ActivityDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
@@ -0,0 +1,122 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestActivityPubRepositoryActorKey(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+
+	key, err := repo.ActorKey()
+	require.NoError(t, err)
+	require.Contains(t, key.PrivateKey, "BEGIN RSA PRIVATE KEY")
+	require.Contains(t, key.PublicKey, "BEGIN PUBLIC KEY")
+
+	again, err := repo.ActorKey()
+	require.NoError(t, err)
+	require.Equal(t, key.ID, again.ID)
+	require.Equal(t, key.PublicKey, again.PublicKey)
+}
+
+func TestActivityPubRepositoryFollow(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+
+	req := data.FollowerCreate{
+		ActorID:      "https://social.example/users/alice",
+		Inbox:        "https://social.example/users/alice/inbox",
+		SharedInbox:  "https://social.example/inbox",
+		FollowID:     "https://social.example/follows/1",
+		LocalActorID: "https://bookmarks.example/actor",
+	}
+	follower, err := repo.Follow(req)
+	require.NoError(t, err)
+	require.NotZero(t, follower.ID)
+	require.Equal(t, "https://social.example/inbox", follower.DeliveryInbox())
+
+	// following again updates the follower
+	req.FollowID = "https://social.example/follows/2"
+	again, err := repo.Follow(req)
+	require.NoError(t, err)
+	require.Equal(t, follower.ID, again.ID)
+	require.Equal(t, "https://social.example/follows/2", again.FollowID)
+
+	followers, err := repo.Followers()
+	require.NoError(t, err)
+	require.Len(t, followers, 1)
+
+	deliveries, err := repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, data.ActivityAccept, deliveries[0].Type)
+	require.Equal(t, "https://social.example/users/alice/inbox", deliveries[0].Inbox)
+	require.Equal(t, follower.ID, deliveries[0].FollowerID)
+
+	// invalid
+	_, err = repo.Follow(data.FollowerCreate{})
+	require.EqualError(t, err, "Follower is invalid")
+
+	// unfollow removes pending deliveries and allows following again
+	err = repo.Unfollow(req.ActorID)
+	require.NoError(t, err)
+	followers, err = repo.Followers()
+	require.NoError(t, err)
+	require.Empty(t, followers)
+	deliveries, err = repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	err = repo.Unfollow(req.ActorID)
+	require.NoError(t, err)
+	_, err = repo.Follow(req)
+	require.NoError(t, err)
+}
+
+func TestActivityPubBookmarkDeliveries(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+	bookmarkRepo := data.NewBookmarkRepository(db)
+
+	for _, req := range []data.FollowerCreate{
+		{ActorID: "https://social.example/users/alice", Inbox: "https://social.example/users/alice/inbox", SharedInbox: "https://social.example/inbox"},
+		{ActorID: "https://social.example/users/bob", Inbox: "https://social.example/users/bob/inbox", SharedInbox: "https://social.example/inbox"},
+		{ActorID: "https://other.example/carol", Inbox: "https://other.example/carol/inbox"},
+	} {
+		req.LocalActorID = "https://bookmarks.example/actor"
+		_, err := repo.Follow(req)
+		require.NoError(t, err)
+	}
+
+	// private bookmarks are not delivered
+	_, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com/private"})
+	require.NoError(t, err)
+	deliveries, err := repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+
+	// public bookmarks are delivered once per shared inbox
+	bookmark, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com", Public: true})
+	require.NoError(t, err)
+	deliveries, err = repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 5)
+
+	inboxes := []string{}
+	for _, delivery := range deliveries[3:] {
+		require.Equal(t, data.ActivityCreate, delivery.Type)
+		require.Equal(t, bookmark.ID, delivery.BookmarkID)
+		require.Equal(t, "https://bookmarks.example/actor", delivery.LocalActorID)
+		inboxes = append(inboxes, delivery.Inbox)
+	}
+	require.Equal(t, []string{"https://social.example/inbox", "https://other.example/carol/inbox"}, inboxes)
+}
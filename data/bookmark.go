@@ -2,6 +2,8 @@ package data
 
 import (
 	"net/url"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,12 +16,40 @@ const (
 	BookmarkPrivacyQueryAll BookmarkPrivacy = "all"
 )
 
+type BookmarkSort string
+
+const (
+	BookmarkSortNewest    BookmarkSort = "newest"
+	BookmarkSortOldest    BookmarkSort = "oldest"
+	BookmarkSortTitle     BookmarkSort = "title"
+	BookmarkSortUpdated   BookmarkSort = "updated"
+	BookmarkSortVisited   BookmarkSort = "visited"
+	BookmarkSortRelevance BookmarkSort = "relevance"
+)
+
+const (
+	DefaultPerPage = 10
+	MaxPerPage     = 100
+)
+
+// BookmarkSorts are the sort options for bookmark lists, search results can
+// additionally be sorted by BookmarkSortRelevance.
+var BookmarkSorts = []BookmarkSort{
+	BookmarkSortNewest,
+	BookmarkSortOldest,
+	BookmarkSortTitle,
+	BookmarkSortUpdated,
+	BookmarkSortVisited,
+}
+
 type Bookmark struct {
 	gorm.Model
 	URL         string `gorm:"not null;default:null"`
 	Title       string
 	Description string
 	Privacy     BookmarkPrivacy `gorm:"default:'private'"`
+	Visits      uint
+	Domain      string `gorm:"index"`
 
 	Tags []Tag `gorm:"many2many:bookmark_tags;"`
 }
@@ -33,37 +63,117 @@ type BookmarkForm struct {
 }
 
 type BookmarkListRequest struct {
-	Privacy BookmarkPrivacy
-	TagID   uint
-	Offset  int
-	Order   string
+	Privacy      BookmarkPrivacy
+	TagID        uint
+	Untagged     bool
+	Domain       string
+	CreatedFrom  time.Time
+	CreatedUntil time.Time
+	Cursor       string
+	PerPage      int
+	Sort         BookmarkSort
 
 	PaginationPathPrefix string
 }
 
 type BookmarkListResult struct {
 	Items   []Bookmark
-	Count   int64
 	HasPrev bool
 	PrevURL string
 	HasNext bool
 	NextURL string
 }
 
+type BookmarkMonthCount struct {
+	Year  int
+	Month time.Month
+	Count int64
+}
+
+type BookmarkWeekCount struct {
+	Week  time.Time
+	Count int64
+}
+
+type BookmarkDomainCount struct {
+	Domain string
+	Count  int64
+}
+
 type BookmarkSearchRequest struct {
-	Query  string
-	Offset int
+	Query   string
+	Cursor  string
+	PerPage int
+	Sort    BookmarkSort
 
 	PaginationPathPrefix string
 }
 
 type BookmarkSearchResponse struct {
-	Items   []Bookmark
-	Count   int64
-	HasPrev bool
-	PrevURL string
-	HasNext bool
-	NextURL string
+	Items      []Bookmark
+	Suggestion string
+	HasPrev    bool
+	PrevURL    string
+	HasNext    bool
+	NextURL    string
+}
+
+func ParseBookmarkSort(value string) (BookmarkSort, bool) {
+	sort := BookmarkSort(value)
+	if sort == BookmarkSortRelevance {
+		return sort, true
+	}
+	for _, valid := range BookmarkSorts {
+		if sort == valid {
+			return sort, true
+		}
+	}
+	return "", false
+}
+
+func (s BookmarkSort) Label() string {
+	switch s {
+	case BookmarkSortOldest:
+		return "Oldest"
+	case BookmarkSortTitle:
+		return "Title"
+	case BookmarkSortUpdated:
+		return "Last updated"
+	case BookmarkSortVisited:
+		return "Most visited"
+	case BookmarkSortRelevance:
+		return "Relevance"
+	default:
+		return "Newest"
+	}
+}
+
+// ClampPerPage returns the default page size for unset values and caps
+// the page size at MaxPerPage.
+func ClampPerPage(perPage int) int {
+	if perPage <= 0 {
+		return DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		return MaxPerPage
+	}
+	return perPage
+}
+
+func (b *Bookmark) BeforeSave(tx *gorm.DB) error {
+	b.Domain = ExtractDomain(b.URL)
+	return nil
+}
+
+// ExtractDomain returns the lowercased host of rawURL without port and
+// leading "www.", an empty string is returned for unparseable URLs.
+func ExtractDomain(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	return strings.TrimPrefix(host, "www.")
 }
 
 func (b *Bookmark) IsPublic() bool {
@@ -3,6 +3,7 @@ package data
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -27,6 +28,19 @@ func (r *BookmarkRepository) Get(id uint) (*Bookmark, error) {
 	return &bookmark, nil
 }
 
+// GetByURL returns the most recently created bookmark with the given URL.
+func (r *BookmarkRepository) GetByURL(url string) (*Bookmark, error) {
+	var bookmark Bookmark
+	result := r.db.Preload("Tags").Where("url = ?", url).Order("created_at desc").First(&bookmark)
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &bookmark, nil
+}
+
 func (r *BookmarkRepository) Create(form BookmarkForm) (*Bookmark, error) {
 	var bookmark *Bookmark
 	err := r.db.Transaction(func(tx *gorm.DB) error {
@@ -51,7 +65,12 @@ func (r *BookmarkRepository) Create(form BookmarkForm) (*Bookmark, error) {
 			return result.Error
 		}
 
-		return nil
+		err = enqueueBookmarkActivity(tx, bookmark)
+		if err != nil {
+			return err
+		}
+
+		return enqueueWebhookEvent(tx, WebhookEventBookmarkCreated, NewWebhookBookmark(bookmark))
 	})
 
 	return bookmark, err
@@ -59,97 +78,242 @@ func (r *BookmarkRepository) Create(form BookmarkForm) (*Bookmark, error) {
 
 func (r *BookmarkRepository) List(req BookmarkListRequest) (*BookmarkListResult, error) {
 	query := r.db.Model(&Bookmark{}).Preload("Tags")
-
-	if req.Privacy != BookmarkPrivacyQueryAll {
-		privacy := BookmarkPrivacyPublic
-		if req.Privacy != "" {
-			privacy = req.Privacy
-		}
-		query = query.Where("privacy = ?", privacy)
-	}
+	query = wherePrivacy(query, req.Privacy)
 
 	if req.TagID != 0 {
 		query = query.Joins("inner join bookmark_tags bt on bt.bookmark_id = bookmarks.id").
 			Where("bt.tag_id = ?", req.TagID)
 	}
 
-	if req.Order != "" {
-		query = query.Order(req.Order)
+	if req.Untagged {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM bookmark_tags bt WHERE bt.bookmark_id = bookmarks.id)")
 	}
 
-	var count int64
-	result := query.Count(&count)
-	if result.Error != nil {
-		return nil, result.Error
+	if req.Domain != "" {
+		query = query.Where("bookmarks.domain = ?", strings.ToLower(req.Domain))
 	}
 
-	query.Offset(req.Offset)
-	limit := 10
-	query = query.Limit(limit)
+	if !req.CreatedFrom.IsZero() {
+		query = query.Where("bookmarks.created_at >= ?", req.CreatedFrom)
+	}
+	if !req.CreatedUntil.IsZero() {
+		query = query.Where("bookmarks.created_at < ?", req.CreatedUntil)
+	}
 
-	var bookmarks []Bookmark
-	result = query.Find(&bookmarks)
-	if result.Error != nil {
-		return nil, result.Error
+	ks := keysetForSort(r.db, req.Sort, "")
+	p, err := paginate(query, ks, req.Cursor, ClampPerPage(req.PerPage))
+	if err != nil {
+		return nil, err
 	}
 
 	return &BookmarkListResult{
-		Items:   bookmarks,
-		Count:   count,
-		HasPrev: req.Offset > 0,
-		PrevURL: fmt.Sprintf("%soffset=%d", req.PaginationPathPrefix, req.Offset-limit),
-		HasNext: int64(req.Offset+limit) < count,
-		NextURL: fmt.Sprintf("%soffset=%d", req.PaginationPathPrefix, req.Offset+limit),
+		Items:   p.Items,
+		HasPrev: p.HasPrev,
+		PrevURL: paginationURL(req.PaginationPathPrefix, p.PrevCursor),
+		HasNext: p.HasNext,
+		NextURL: paginationURL(req.PaginationPathPrefix, p.NextCursor),
 	}, nil
 }
 
-func (r *BookmarkRepository) Search(req BookmarkSearchRequest) (*BookmarkSearchResponse, error) {
-	var bookmarks []Bookmark
+// MonthCounts returns the number of bookmarks created per month, most
+// recent month first.
+func (r *BookmarkRepository) MonthCounts(privacy BookmarkPrivacy) ([]BookmarkMonthCount, error) {
+	var counts []BookmarkMonthCount
+	err := wherePrivacy(r.db.Model(&Bookmark{}), privacy).
+		Select("CAST(substr(created_at, 1, 4) AS INTEGER) AS year, " +
+			"CAST(substr(created_at, 6, 2) AS INTEGER) AS month, " +
+			"count(*) AS count").
+		Group("year, month").
+		Order("year desc, month desc").
+		Scan(&counts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
 
-	limit := 10
-	query := r.db.Model(&Bookmark{}).Preload("Tags").
-		Joins("JOIN bookmarks_fts on bookmarks_fts.rowid = bookmarks.id").
-		Where("bookmarks_fts MATCH ?", req.Query).
-		Order("bookmarks_fts.rank")
+// WeekCounts returns the number of bookmarks created per week, starting on
+// Mondays, for all weeks since the given time with at least one bookmark.
+func (r *BookmarkRepository) WeekCounts(privacy BookmarkPrivacy, since time.Time) ([]BookmarkWeekCount, error) {
+	var rows []struct {
+		Week  string
+		Count int64
+	}
+	err := wherePrivacy(r.db.Model(&Bookmark{}), privacy).
+		Select("date(substr(created_at, 1, 10), '-6 days', 'weekday 1') AS week, count(*) AS count").
+		Where("created_at >= ?", since).
+		Group("week").
+		Order("week desc").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
 
-	var count int64
-	err := query.Count(&count).Error
+	counts := make([]BookmarkWeekCount, 0, len(rows))
+	for _, row := range rows {
+		week, err := time.ParseInLocation("2006-01-02", row.Week, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, BookmarkWeekCount{Week: week, Count: row.Count})
+	}
+	return counts, nil
+}
+
+// PrivacyCounts returns the number of bookmarks per privacy setting.
+func (r *BookmarkRepository) PrivacyCounts() (map[BookmarkPrivacy]int64, error) {
+	var rows []struct {
+		Privacy BookmarkPrivacy
+		Count   int64
+	}
+	err := r.db.Model(&Bookmark{}).
+		Select("privacy, count(*) AS count").
+		Group("privacy").
+		Scan(&rows).
+		Error
 	if err != nil {
 		return nil, err
 	}
 
-	err = query.Offset(req.Offset).
-		Limit(limit).
-		Find(&bookmarks).
+	counts := map[BookmarkPrivacy]int64{
+		BookmarkPrivacyPublic:  0,
+		BookmarkPrivacyPrivate: 0,
+	}
+	for _, row := range rows {
+		counts[row.Privacy] = row.Count
+	}
+	return counts, nil
+}
+
+// CountUntagged returns the number of bookmarks without any tags.
+func (r *BookmarkRepository) CountUntagged() (int64, error) {
+	var count int64
+	err := r.db.Model(&Bookmark{}).
+		Where("NOT EXISTS (SELECT 1 FROM bookmark_tags bt WHERE bt.bookmark_id = bookmarks.id)").
+		Count(&count).
 		Error
+	return count, err
+}
+
+// DomainCounts returns the number of bookmarks per domain, most bookmarked
+// domain first.
+func (r *BookmarkRepository) DomainCounts(privacy BookmarkPrivacy) ([]BookmarkDomainCount, error) {
+	var counts []BookmarkDomainCount
+	err := wherePrivacy(r.db.Model(&Bookmark{}), privacy).
+		Select("domain, count(*) AS count").
+		Where("domain != ''").
+		Group("domain").
+		Order("count desc, domain asc").
+		Scan(&counts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *BookmarkRepository) Search(req BookmarkSearchRequest) (*BookmarkSearchResponse, error) {
+	result, err := r.search(req)
+	if err != nil || len(result.Items) > 0 || req.Query == "" {
+		return result, err
+	}
+
+	// retry with typos corrected when nothing matched the query as entered
+	suggestion, err := r.suggestQuery(req.Query)
+	if err != nil || suggestion == "" {
+		return result, err
+	}
+	req.Query = suggestion
+	suggested, err := r.search(req)
+	if err != nil {
+		return result, err
+	}
+	// terms corrected to a tag name may not match any bookmark text, the
+	// suggestion still leads to the tag
+	suggested.Suggestion = suggestion
+	return suggested, nil
+}
+
+func (r *BookmarkRepository) search(req BookmarkSearchRequest) (*BookmarkSearchResponse, error) {
+	query := r.db.Model(&Bookmark{}).Preload("Tags").
+		Joins("JOIN bookmarks_fts on bookmarks_fts.rowid = bookmarks.id").
+		Where("bookmarks_fts MATCH ?", req.Query)
 
+	sort := req.Sort
+	if sort == "" {
+		sort = BookmarkSortRelevance
+	}
+	ks := keysetForSort(r.db, sort, req.Query)
+	p, err := paginate(query, ks, req.Cursor, ClampPerPage(req.PerPage))
 	if err != nil {
 		return nil, err
 	}
 
 	return &BookmarkSearchResponse{
-		Items:   bookmarks,
-		Count:   count,
-		HasPrev: req.Offset > 0,
-		PrevURL: fmt.Sprintf("%soffset=%d", req.PaginationPathPrefix, req.Offset-limit),
-		HasNext: int64(req.Offset+limit) < count,
-		NextURL: fmt.Sprintf("%soffset=%d", req.PaginationPathPrefix, req.Offset+limit),
+		Items:   p.Items,
+		HasPrev: p.HasPrev,
+		PrevURL: paginationURL(req.PaginationPathPrefix, p.PrevCursor),
+		HasNext: p.HasNext,
+		NextURL: paginationURL(req.PaginationPathPrefix, p.NextCursor),
 	}, nil
 }
 
-func (r *BookmarkRepository) Delete(id uint) error {
-	result := r.db.Delete(&Bookmark{}, id)
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("bookmark with id %d not found", id)
+func (r *BookmarkRepository) Suggest(query string, limit int) ([]Bookmark, error) {
+	match := prefixQuery(query)
+	if match == "" {
+		return []Bookmark{}, nil
 	}
 
+	var bookmarks []Bookmark
+	err := r.db.Model(&Bookmark{}).
+		Joins("JOIN bookmarks_fts on bookmarks_fts.rowid = bookmarks.id").
+		Where("bookmarks_fts MATCH ?", "{url title}: ("+match+")").
+		Order("bookmarks_fts.rank").
+		Limit(limit).
+		Find(&bookmarks).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+// Visit counts a visit of the bookmark without touching its update time.
+func (r *BookmarkRepository) Visit(id uint) error {
+	result := r.db.Model(&Bookmark{}).
+		Where("id = ?", id).
+		UpdateColumn("visits", gorm.Expr("visits + ?", 1))
 	if result.Error != nil {
 		return result.Error
 	}
-
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("bookmark with id %d not found", id)
+	}
 	return nil
 }
 
+func (r *BookmarkRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var bookmark Bookmark
+		result := tx.Preload("Tags").Limit(1).Find(&bookmark, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("bookmark with id %d not found", id)
+		}
+
+		result = tx.Delete(&bookmark)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return enqueueWebhookEvent(tx, WebhookEventBookmarkDeleted, NewWebhookBookmark(&bookmark))
+	})
+}
+
 func (r *BookmarkRepository) Update(id uint, form BookmarkForm) error {
 	bookmark, err := r.Get(id)
 	if err != nil {
@@ -181,11 +345,70 @@ func (r *BookmarkRepository) Update(id uint, form BookmarkForm) error {
 		if err != nil {
 			return err
 		}
-		return nil
+
+		bookmark.Tags = updatedTags
+		return enqueueWebhookEvent(tx, WebhookEventBookmarkUpdated, NewWebhookBookmark(bookmark))
 	})
 	return err
 }
 
+// suggestQuery replaces each term of query which doesn't appear in any
+// bookmark title or tag name with the closest term that does, an empty string
+// is returned when there is nothing to correct.
+func (r *BookmarkRepository) suggestQuery(query string) (string, error) {
+	terms := queryTerms(query)
+	corrected := false
+
+	for i, term := range terms {
+		term = strings.ToLower(term)
+		maxDistance := maxEditDistance(term)
+		length := len([]rune(term))
+
+		var candidates []fuzzyCandidate
+		err := r.db.Raw(`SELECT term, sum(weight) AS weight FROM (
+				SELECT term, doc AS weight FROM bookmarks_fts_vocab WHERE col = 'title'
+				UNION ALL
+				SELECT term, doc AS weight FROM tags_fts_vocab
+			) WHERE length(term) BETWEEN ? AND ?
+			GROUP BY term ORDER BY weight DESC LIMIT ?`, length-maxDistance, length+maxDistance, maxFuzzyCandidates).
+			Scan(&candidates).
+			Error
+		if err != nil {
+			return "", err
+		}
+
+		matches := fuzzyMatches(term, candidates)
+		if len(matches) == 0 || matches[0].Distance == 0 {
+			continue
+		}
+		terms[i] = matches[0].Term
+		corrected = true
+	}
+
+	if !corrected {
+		return "", nil
+	}
+	return strings.Join(terms, " "), nil
+}
+
+// wherePrivacy filters by privacy, defaulting to public bookmarks only.
+func wherePrivacy(query *gorm.DB, privacy BookmarkPrivacy) *gorm.DB {
+	if privacy == BookmarkPrivacyQueryAll {
+		return query
+	}
+	if privacy == "" {
+		privacy = BookmarkPrivacyPublic
+	}
+	return query.Where("privacy = ?", privacy)
+}
+
+func paginationURL(prefix, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf("%scursor=%s", prefix, cursor)
+}
+
 func publicToPrivacy(public bool) BookmarkPrivacy {
 	if public {
 		return BookmarkPrivacyPublic
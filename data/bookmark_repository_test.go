@@ -2,11 +2,14 @@ package data_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/test"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestBookmarkRepositoryGet(t *testing.T) {
@@ -28,6 +31,26 @@ func TestBookmarkRepositoryGet(t *testing.T) {
 	require.Nil(t, actual)
 }
 
+func TestBookmarkRepositoryGetByURL(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	bookmark, err := repo.GetByURL("https://example.com")
+	require.NoError(t, err)
+	require.Nil(t, bookmark)
+
+	created, err := repo.Create(data.BookmarkForm{URL: "https://example.com", Tags: "examples"})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://example.org"})
+	require.NoError(t, err)
+
+	bookmark, err = repo.GetByURL("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, created.ID, bookmark.ID)
+	require.Len(t, bookmark.Tags, 1)
+}
+
 func TestBookmarkRepositoryCreate(t *testing.T) {
 	db, cleanup := test.InitTestDB(t)
 	defer cleanup()
@@ -90,59 +113,95 @@ func TestBookmarkRepositoryList(t *testing.T) {
 	})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 10)
-	require.Equal(t, "Bookmark 0", result.Items[0].Title)
-	require.Equal(t, "Bookmark 9", result.Items[len(result.Items)-1].Title)
-	require.Equal(t, int64(25), result.Count)
+	require.Equal(t, "Bookmark 24", result.Items[0].Title)
+	require.Equal(t, "Bookmark 15", result.Items[len(result.Items)-1].Title)
 	require.False(t, result.HasPrev)
 	require.True(t, result.HasNext)
-	require.Equal(t, "/?offset=10", result.NextURL)
+	require.True(t, strings.HasPrefix(result.NextURL, "/?cursor="))
+	page1PrevURL := result.PrevURL
 
 	// privacy all - page 2
 	result, err = repo.List(data.BookmarkListRequest{
 		Privacy: data.BookmarkPrivacyQueryAll,
-		Offset:  10,
+		Cursor:  strings.TrimPrefix(result.NextURL, "/?cursor="),
 
 		PaginationPathPrefix: "/?",
 	})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 10)
-	require.Equal(t, "Bookmark 10", result.Items[0].Title)
-	require.Equal(t, "Bookmark 19", result.Items[len(result.Items)-1].Title)
-	require.Equal(t, int64(25), result.Count)
+	require.Equal(t, "Bookmark 14", result.Items[0].Title)
+	require.Equal(t, "Bookmark 5", result.Items[len(result.Items)-1].Title)
 	require.True(t, result.HasPrev)
-	require.Equal(t, "/?offset=0", result.PrevURL)
+	require.NotEqual(t, page1PrevURL, result.PrevURL)
 	require.True(t, result.HasNext)
-	require.Equal(t, "/?offset=20", result.NextURL)
+	page2PrevURL := result.PrevURL
 
 	// privacy all - page 3
 	result, err = repo.List(data.BookmarkListRequest{
 		Privacy:              data.BookmarkPrivacyQueryAll,
-		Offset:               20,
+		Cursor:               strings.TrimPrefix(result.NextURL, "/?cursor="),
 		PaginationPathPrefix: "/?",
 	})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 5)
-	require.Equal(t, "Bookmark 20", result.Items[0].Title)
-	require.Equal(t, "Bookmark 24", result.Items[len(result.Items)-1].Title)
-	require.Equal(t, int64(25), result.Count)
+	require.Equal(t, "Bookmark 4", result.Items[0].Title)
+	require.Equal(t, "Bookmark 0", result.Items[len(result.Items)-1].Title)
 	require.True(t, result.HasPrev)
-	require.Equal(t, "/?offset=10", result.PrevURL)
 	require.False(t, result.HasNext)
 
-	// privacy all - order
+	// privacy all - back to page 2
 	result, err = repo.List(data.BookmarkListRequest{
-		Privacy: data.BookmarkPrivacyQueryAll,
-		Order:   "created_at desc",
+		Privacy:              data.BookmarkPrivacyQueryAll,
+		Cursor:               strings.TrimPrefix(result.PrevURL, "/?cursor="),
+		PaginationPathPrefix: "/?",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 10)
+	require.Equal(t, "Bookmark 14", result.Items[0].Title)
+	require.Equal(t, "Bookmark 5", result.Items[len(result.Items)-1].Title)
+	require.True(t, result.HasPrev)
+	require.True(t, result.HasNext)
+
+	// privacy all - back to page 1
+	result, err = repo.List(data.BookmarkListRequest{
+		Privacy:              data.BookmarkPrivacyQueryAll,
+		Cursor:               strings.TrimPrefix(page2PrevURL, "/?cursor="),
+		PaginationPathPrefix: "/?",
 	})
 	require.NoError(t, err)
+	require.Len(t, result.Items, 10)
 	require.Equal(t, "Bookmark 24", result.Items[0].Title)
+	require.Equal(t, "Bookmark 15", result.Items[len(result.Items)-1].Title)
+	require.False(t, result.HasPrev)
+	require.True(t, result.HasNext)
+
+	// stable when bookmarks are added while paginating
+	result, err = repo.List(data.BookmarkListRequest{Privacy: data.BookmarkPrivacyQueryAll})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://example-25.com", Title: "Bookmark 25"})
+	require.NoError(t, err)
+	result, err = repo.List(data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyQueryAll,
+		Cursor:  strings.TrimPrefix(result.NextURL, "cursor="),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Bookmark 14", result.Items[0].Title)
+
+	// invalid cursor falls back to first page
+	result, err = repo.List(data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyQueryAll,
+		Cursor:  "invalid",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Bookmark 25", result.Items[0].Title)
+	require.False(t, result.HasPrev)
 
 	// privacy private
 	result, err = repo.List(data.BookmarkListRequest{
 		Privacy: data.BookmarkPrivacyPrivate,
 	})
 	require.NoError(t, err)
-	require.Equal(t, int64(12), result.Count)
+	require.Len(t, result.Items, 10)
 	for _, item := range result.Items {
 		require.Equal(t, data.BookmarkPrivacyPrivate, item.Privacy)
 	}
@@ -152,7 +211,7 @@ func TestBookmarkRepositoryList(t *testing.T) {
 		Privacy: data.BookmarkPrivacyPublic,
 	})
 	require.NoError(t, err)
-	require.Equal(t, int64(13), result.Count)
+	require.Len(t, result.Items, 10)
 	for _, item := range result.Items {
 		require.Equal(t, data.BookmarkPrivacyPublic, item.Privacy)
 	}
@@ -160,7 +219,7 @@ func TestBookmarkRepositoryList(t *testing.T) {
 	// privacy defaulting to public
 	result, err = repo.List(data.BookmarkListRequest{})
 	require.NoError(t, err)
-	require.Equal(t, int64(13), result.Count)
+	require.Len(t, result.Items, 10)
 	for _, item := range result.Items {
 		require.Equal(t, data.BookmarkPrivacyPublic, item.Privacy)
 	}
@@ -174,13 +233,333 @@ func TestBookmarkRepositoryList(t *testing.T) {
 		TagID:   tag.ID,
 	})
 	require.NoError(t, err)
-	require.Equal(t, int64(13), result.Count)
+	require.Len(t, result.Items, 10)
 	for _, item := range result.Items {
 		require.Len(t, item.Tags, 1)
 		require.Equal(t, tag.ID, item.Tags[0].ID)
 	}
 }
 
+func TestBookmarkRepositoryListSort(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	titles := []string{"banana", "Cherry", "apple", "date"}
+	ids := []uint{}
+	for _, title := range titles {
+		bookmark, err := repo.Create(data.BookmarkForm{
+			URL:   fmt.Sprintf("https://%s.com", title),
+			Title: title,
+		})
+		require.NoError(t, err)
+		ids = append(ids, bookmark.ID)
+	}
+
+	listTitles := func(req data.BookmarkListRequest) []string {
+		req.Privacy = data.BookmarkPrivacyQueryAll
+		result, err := repo.List(req)
+		require.NoError(t, err)
+		actual := []string{}
+		for _, item := range result.Items {
+			actual = append(actual, item.Title)
+		}
+		return actual
+	}
+
+	require.Equal(t, []string{"date", "apple", "Cherry", "banana"}, listTitles(data.BookmarkListRequest{}))
+	require.Equal(t, []string{"date", "apple", "Cherry", "banana"}, listTitles(data.BookmarkListRequest{Sort: data.BookmarkSortNewest}))
+	require.Equal(t, []string{"banana", "Cherry", "apple", "date"}, listTitles(data.BookmarkListRequest{Sort: data.BookmarkSortOldest}))
+	require.Equal(t, []string{"apple", "banana", "Cherry", "date"}, listTitles(data.BookmarkListRequest{Sort: data.BookmarkSortTitle}))
+
+	// last updated
+	err := repo.Update(ids[1], data.BookmarkForm{URL: "https://cherry.com", Title: "Cherry"})
+	require.NoError(t, err)
+	require.Equal(t, "Cherry", listTitles(data.BookmarkListRequest{Sort: data.BookmarkSortUpdated})[0])
+
+	// most visited
+	require.NoError(t, repo.Visit(ids[0]))
+	require.NoError(t, repo.Visit(ids[0]))
+	require.NoError(t, repo.Visit(ids[2]))
+	require.Equal(t, []string{"banana", "apple", "date", "Cherry"}, listTitles(data.BookmarkListRequest{Sort: data.BookmarkSortVisited}))
+
+	// paginates with page size
+	result, err := repo.List(data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyQueryAll,
+		Sort:    data.BookmarkSortTitle,
+		PerPage: 3,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 3)
+	require.True(t, result.HasNext)
+	require.Equal(t, []string{"date"}, listTitles(data.BookmarkListRequest{
+		Sort:    data.BookmarkSortTitle,
+		PerPage: 3,
+		Cursor:  strings.TrimPrefix(result.NextURL, "cursor="),
+	}))
+
+	// cursors of another sort order start over at the first page
+	require.Equal(t, []string{"date", "apple", "Cherry", "banana"}, listTitles(data.BookmarkListRequest{
+		Sort:   data.BookmarkSortNewest,
+		Cursor: strings.TrimPrefix(result.NextURL, "cursor="),
+	}))
+}
+
+func TestBookmarkRepositoryListCreatedRange(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for _, createdAt := range []time.Time{
+		time.Date(2022, time.December, 31, 23, 59, 0, 0, time.Local),
+		time.Date(2023, time.January, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2023, time.January, 31, 12, 0, 0, 0, time.Local),
+		time.Date(2023, time.February, 1, 8, 0, 0, 0, time.Local),
+	} {
+		result := db.Create(&data.Bookmark{
+			URL:     "https://example.com",
+			Title:   createdAt.Format(time.RFC3339),
+			Privacy: data.BookmarkPrivacyPublic,
+			Model:   gorm.Model{CreatedAt: createdAt},
+		})
+		require.NoError(t, result.Error)
+	}
+
+	from := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.Local)
+	result, err := repo.List(data.BookmarkListRequest{
+		CreatedFrom:  from,
+		CreatedUntil: from.AddDate(0, 1, 0),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	require.Equal(t, time.January, result.Items[0].CreatedAt.Month())
+	require.Equal(t, time.January, result.Items[1].CreatedAt.Month())
+
+	result, err = repo.List(data.BookmarkListRequest{CreatedFrom: from})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 3)
+
+	result, err = repo.List(data.BookmarkListRequest{CreatedUntil: from})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	require.Equal(t, 2022, result.Items[0].CreatedAt.Year())
+}
+
+func TestBookmarkRepositoryMonthCounts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for i, createdAt := range []time.Time{
+		time.Date(2022, time.December, 31, 23, 59, 0, 0, time.Local),
+		time.Date(2023, time.January, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2023, time.January, 31, 12, 0, 0, 0, time.Local),
+		time.Date(2023, time.March, 1, 8, 0, 0, 0, time.Local),
+	} {
+		privacy := data.BookmarkPrivacyPublic
+		if i == 2 {
+			privacy = data.BookmarkPrivacyPrivate
+		}
+		result := db.Create(&data.Bookmark{
+			URL:     "https://example.com",
+			Privacy: privacy,
+			Model:   gorm.Model{CreatedAt: createdAt},
+		})
+		require.NoError(t, result.Error)
+	}
+
+	counts, err := repo.MonthCounts(data.BookmarkPrivacyQueryAll)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkMonthCount{
+		{Year: 2023, Month: time.March, Count: 1},
+		{Year: 2023, Month: time.January, Count: 2},
+		{Year: 2022, Month: time.December, Count: 1},
+	}, counts)
+
+	counts, err = repo.MonthCounts(data.BookmarkPrivacyPublic)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkMonthCount{
+		{Year: 2023, Month: time.March, Count: 1},
+		{Year: 2023, Month: time.January, Count: 1},
+		{Year: 2022, Month: time.December, Count: 1},
+	}, counts)
+}
+
+func TestBookmarkRepositoryListDomain(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for _, url := range []string{
+		"https://www.example.com/one",
+		"https://example.com/two",
+		"https://other.org",
+	} {
+		_, err := repo.Create(data.BookmarkForm{URL: url, Public: true})
+		require.NoError(t, err)
+	}
+
+	result, err := repo.List(data.BookmarkListRequest{Domain: "Example.com"})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	for _, bookmark := range result.Items {
+		require.Equal(t, "example.com", bookmark.Domain)
+	}
+
+	// domain follows URL changes
+	err = repo.Update(result.Items[0].ID, data.BookmarkForm{URL: "https://other.org/path", Public: true})
+	require.NoError(t, err)
+	result, err = repo.List(data.BookmarkListRequest{Domain: "other.org"})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+}
+
+func TestBookmarkRepositoryDomainCounts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for _, form := range []data.BookmarkForm{
+		{URL: "https://example.com/one", Public: true},
+		{URL: "https://example.com/two", Public: false},
+		{URL: "https://example.com/three", Public: true},
+		{URL: "https://other.org", Public: true},
+		{URL: "https://private.org", Public: false},
+	} {
+		_, err := repo.Create(form)
+		require.NoError(t, err)
+	}
+
+	counts, err := repo.DomainCounts(data.BookmarkPrivacyQueryAll)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkDomainCount{
+		{Domain: "example.com", Count: 3},
+		{Domain: "other.org", Count: 1},
+		{Domain: "private.org", Count: 1},
+	}, counts)
+
+	counts, err = repo.DomainCounts(data.BookmarkPrivacyPublic)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkDomainCount{
+		{Domain: "example.com", Count: 2},
+		{Domain: "other.org", Count: 1},
+	}, counts)
+}
+
+func TestBookmarkRepositoryWeekCounts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for i, createdAt := range []time.Time{
+		time.Date(2023, time.February, 26, 23, 0, 0, 0, time.Local), // Sunday
+		time.Date(2023, time.February, 27, 8, 0, 0, 0, time.Local),  // Monday
+		time.Date(2023, time.March, 5, 12, 0, 0, 0, time.Local),     // Sunday
+		time.Date(2023, time.March, 6, 12, 0, 0, 0, time.Local),     // Monday
+	} {
+		privacy := data.BookmarkPrivacyPublic
+		if i == 2 {
+			privacy = data.BookmarkPrivacyPrivate
+		}
+		result := db.Create(&data.Bookmark{
+			URL:     "https://example.com",
+			Privacy: privacy,
+			Model:   gorm.Model{CreatedAt: createdAt},
+		})
+		require.NoError(t, result.Error)
+	}
+
+	since := time.Date(2023, time.February, 27, 0, 0, 0, 0, time.Local)
+	counts, err := repo.WeekCounts(data.BookmarkPrivacyQueryAll, since)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkWeekCount{
+		{Week: time.Date(2023, time.March, 6, 0, 0, 0, 0, time.Local), Count: 1},
+		{Week: since, Count: 2},
+	}, counts)
+
+	counts, err = repo.WeekCounts(data.BookmarkPrivacyPublic, since)
+	require.NoError(t, err)
+	require.Equal(t, []data.BookmarkWeekCount{
+		{Week: time.Date(2023, time.March, 6, 0, 0, 0, 0, time.Local), Count: 1},
+		{Week: since, Count: 1},
+	}, counts)
+}
+
+func TestBookmarkRepositoryPrivacyCounts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	counts, err := repo.PrivacyCounts()
+	require.NoError(t, err)
+	require.Equal(t, map[data.BookmarkPrivacy]int64{
+		data.BookmarkPrivacyPublic:  0,
+		data.BookmarkPrivacyPrivate: 0,
+	}, counts)
+
+	for _, public := range []bool{true, false, false} {
+		_, err := repo.Create(data.BookmarkForm{URL: "https://example.com", Public: public})
+		require.NoError(t, err)
+	}
+
+	counts, err = repo.PrivacyCounts()
+	require.NoError(t, err)
+	require.Equal(t, map[data.BookmarkPrivacy]int64{
+		data.BookmarkPrivacyPublic:  1,
+		data.BookmarkPrivacyPrivate: 2,
+	}, counts)
+}
+
+func TestBookmarkRepositoryUntagged(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for _, form := range []data.BookmarkForm{
+		{URL: "https://example.com", Title: "Tagged", Tags: "go"},
+		{URL: "https://example.com", Title: "Untagged public", Public: true},
+		{URL: "https://example.com", Title: "Untagged private"},
+	} {
+		_, err := repo.Create(form)
+		require.NoError(t, err)
+	}
+
+	count, err := repo.CountUntagged()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	result, err := repo.List(data.BookmarkListRequest{
+		Privacy:  data.BookmarkPrivacyQueryAll,
+		Untagged: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	require.Equal(t, "Untagged private", result.Items[0].Title)
+	require.Equal(t, "Untagged public", result.Items[1].Title)
+}
+
+func TestBookmarkRepositoryVisit(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	bookmark, err := repo.Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+	require.Equal(t, uint(0), bookmark.Visits)
+
+	err = repo.Visit(bookmark.ID)
+	require.NoError(t, err)
+
+	actual, err := repo.Get(bookmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), actual.Visits)
+	require.Equal(t, bookmark.UpdatedAt.UnixNano(), actual.UpdatedAt.UnixNano())
+
+	// not found
+	err = repo.Visit(42)
+	require.EqualError(t, err, "bookmark with id 42 not found")
+}
+
 func TestBookmarkRepositorySearch(t *testing.T) {
 	db, cleanup := test.InitTestDB(t)
 	defer cleanup()
@@ -205,44 +584,125 @@ func TestBookmarkRepositorySearch(t *testing.T) {
 	result, err := repo.Search(data.BookmarkSearchRequest{Query: "bookmark"})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 10)
-	require.Equal(t, int64(25), result.Count)
 	require.True(t, result.HasNext)
-	require.Equal(t, "offset=10", result.NextURL)
+	require.True(t, strings.HasPrefix(result.NextURL, "cursor="))
 	require.False(t, result.HasPrev)
+	seen := map[uint]bool{}
+	for _, item := range result.Items {
+		seen[item.ID] = true
+	}
+	page1 := result.Items
+	page1NextURL := result.NextURL
 
 	// search description
-	result, err = repo.Search(data.BookmarkSearchRequest{Query: "description"})
+	result, err = repo.Search(data.BookmarkSearchRequest{Query: "other description"})
 	require.NoError(t, err)
-	require.Equal(t, int64(26), result.Count)
+	require.Len(t, result.Items, 1)
 
 	// search second page
 	result, err = repo.Search(data.BookmarkSearchRequest{
 		Query:  "bookmark",
-		Offset: 10,
+		Cursor: strings.TrimPrefix(page1NextURL, "cursor="),
 	})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 10)
 	require.True(t, result.HasNext)
-	require.Equal(t, "offset=20", result.NextURL)
 	require.True(t, result.HasPrev)
-	require.Equal(t, "offset=0", result.PrevURL)
+	for _, item := range result.Items {
+		require.False(t, seen[item.ID])
+		seen[item.ID] = true
+	}
+	page2PrevURL := result.PrevURL
 
 	// search third page
 	result, err = repo.Search(data.BookmarkSearchRequest{
 		Query:  "bookmark",
-		Offset: 20,
+		Cursor: strings.TrimPrefix(result.NextURL, "cursor="),
 	})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 5)
 	require.False(t, result.HasNext)
 	require.True(t, result.HasPrev)
-	require.Equal(t, "offset=10", result.PrevURL)
+	for _, item := range result.Items {
+		require.False(t, seen[item.ID])
+		seen[item.ID] = true
+	}
+	require.Len(t, seen, 25)
+
+	// search back to first page
+	result, err = repo.Search(data.BookmarkSearchRequest{
+		Query:  "bookmark",
+		Cursor: strings.TrimPrefix(page2PrevURL, "cursor="),
+	})
+	require.NoError(t, err)
+	require.Equal(t, page1, result.Items)
+	require.False(t, result.HasPrev)
+	require.True(t, result.HasNext)
 
 	// search no result
 	result, err = repo.Search(data.BookmarkSearchRequest{Query: "nothing"})
 	require.NoError(t, err)
 	require.Len(t, result.Items, 0)
-	require.Equal(t, int64(0), result.Count)
+	require.False(t, result.HasNext)
+	require.Empty(t, result.Suggestion)
+
+	// search with typo
+	result, err = repo.Search(data.BookmarkSearchRequest{Query: "bokmark"})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 10)
+	require.Equal(t, "bookmark", result.Suggestion)
+
+	result, err = repo.Search(data.BookmarkSearchRequest{Query: "othr"})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	require.Equal(t, "other", result.Suggestion)
+
+	// tag names are suggested even when no bookmark text matches them
+	_, err = repo.Create(data.BookmarkForm{URL: "https://k8s.io", Title: "Orchestration", Tags: "Kubernetes"})
+	require.NoError(t, err)
+	result, err = repo.Search(data.BookmarkSearchRequest{Query: "kuberntes"})
+	require.NoError(t, err)
+	require.Equal(t, "kubernetes", result.Suggestion)
+}
+
+func TestBookmarkRepositorySuggest(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for i := 0; i < 5; i++ {
+		_, err := repo.Create(data.BookmarkForm{
+			URL:   fmt.Sprintf("https://golang-%d.org", i),
+			Title: fmt.Sprintf("Go Bookmark %d", i),
+		})
+		require.NoError(t, err)
+	}
+	_, err := repo.Create(data.BookmarkForm{
+		URL:         "https://other.org",
+		Title:       "Other",
+		Description: "golang in description",
+	})
+	require.NoError(t, err)
+
+	// matches prefixes in URL and title
+	results, err := repo.Suggest("golan", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	results, err = repo.Suggest("go book", 3)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// ignores search syntax
+	results, err = repo.Suggest(`"oth*`, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "Other", results[0].Title)
+
+	// empty query
+	results, err = repo.Suggest("", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 0)
 }
 
 func TestBookmarkRepositoryDelete(t *testing.T) {
@@ -44,3 +44,34 @@ func TestBookmarkCreateIsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBookmarkSort(t *testing.T) {
+	for _, sort := range data.BookmarkSorts {
+		actual, ok := data.ParseBookmarkSort(string(sort))
+		require.True(t, ok)
+		require.Equal(t, sort, actual)
+	}
+
+	actual, ok := data.ParseBookmarkSort("relevance")
+	require.True(t, ok)
+	require.Equal(t, data.BookmarkSortRelevance, actual)
+
+	_, ok = data.ParseBookmarkSort("created_at desc; drop table bookmarks")
+	require.False(t, ok)
+	_, ok = data.ParseBookmarkSort("")
+	require.False(t, ok)
+}
+
+func TestClampPerPage(t *testing.T) {
+	require.Equal(t, data.DefaultPerPage, data.ClampPerPage(0))
+	require.Equal(t, data.DefaultPerPage, data.ClampPerPage(-5))
+	require.Equal(t, 25, data.ClampPerPage(25))
+	require.Equal(t, data.MaxPerPage, data.ClampPerPage(1000))
+}
+
+func TestExtractDomain(t *testing.T) {
+	require.Equal(t, "example.com", data.ExtractDomain("https://example.com/path"))
+	require.Equal(t, "example.com", data.ExtractDomain("https://www.Example.com:8080/path"))
+	require.Equal(t, "en.wikipedia.org", data.ExtractDomain("https://en.wikipedia.org/wiki/Main_Page"))
+	require.Equal(t, "", data.ExtractDomain("/path"))
+}
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FeedToken grants read access to the private feeds, its secret is part of
+// the feed URL so that feed readers don't need to log in. Only a hash of the
+// secret is stored.
+type FeedToken struct {
+	gorm.Model
+	Name       string
+	SecretHash string `gorm:"unique"`
+	LastUsedAt *time.Time
+}
+
+type FeedTokenCreate struct {
+	Name string
+}
+
+func (req *FeedTokenCreate) IsValid() *ValidationError {
+	if req.Name == "" {
+		return NewValidationError("Feed token is invalid", map[string]string{
+			"Name": "Name is required",
+		})
+	}
+	return nil
+}
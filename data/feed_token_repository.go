@@ -0,0 +1,85 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FeedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedTokenRepository(db *gorm.DB) *FeedTokenRepository {
+	return &FeedTokenRepository{db}
+}
+
+// Create stores a new feed token and returns it together with its secret,
+// which can't be retrieved again afterwards.
+func (r *FeedTokenRepository) Create(req FeedTokenCreate) (*FeedToken, string, error) {
+	validationErr := req.IsValid()
+	if validationErr != nil {
+		return nil, "", validationErr
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &FeedToken{
+		Name:       req.Name,
+		SecretHash: hashTokenSecret(secret),
+	}
+	result := r.db.Create(token)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+
+	return token, secret, nil
+}
+
+func (r *FeedTokenRepository) GetBySecret(secret string) (*FeedToken, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	var token FeedToken
+	result := r.db.Where("secret_hash = ?", hashTokenSecret(secret)).First(&token)
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *FeedTokenRepository) List() ([]FeedToken, error) {
+	var tokens []FeedToken
+	err := r.db.Order("created_at desc").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Touch records that the feed token has just been used.
+func (r *FeedTokenRepository) Touch(id uint) error {
+	return r.db.Model(&FeedToken{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", time.Now()).
+		Error
+}
+
+func (r *FeedTokenRepository) Revoke(id uint) error {
+	result := r.db.Delete(&FeedToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("feed token with id %d not found", id)
+	}
+	return nil
+}
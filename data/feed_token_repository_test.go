@@ -0,0 +1,75 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestFeedTokenRepositoryCreate(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewFeedTokenRepository(db)
+
+	token, secret, err := repo.Create(data.FeedTokenCreate{Name: "Feed reader"})
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+	require.Equal(t, "Feed reader", token.Name)
+	require.NotEqual(t, secret, token.SecretHash)
+
+	found, err := repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, found.ID)
+
+	found, err = repo.GetBySecret("unknown")
+	require.NoError(t, err)
+	require.Nil(t, found)
+	found, err = repo.GetBySecret("")
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	// API tokens and feed tokens are not interchangeable
+	_, apiSecret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "api", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	found, err = repo.GetBySecret(apiSecret)
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	_, _, err = repo.Create(data.FeedTokenCreate{})
+	require.EqualError(t, err, "Feed token is invalid")
+	require.Equal(t, "Name is required", err.(*data.ValidationError).Fields["Name"])
+}
+
+func TestFeedTokenRepositoryTouchAndRevoke(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewFeedTokenRepository(db)
+
+	token, secret, err := repo.Create(data.FeedTokenCreate{Name: "Feed reader"})
+	require.NoError(t, err)
+	require.Nil(t, token.LastUsedAt)
+
+	err = repo.Touch(token.ID)
+	require.NoError(t, err)
+	token, err = repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.NotNil(t, token.LastUsedAt)
+	require.WithinDuration(t, time.Now(), *token.LastUsedAt, time.Minute)
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+
+	err = repo.Revoke(token.ID)
+	require.NoError(t, err)
+	token, err = repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.Nil(t, token)
+
+	err = repo.Revoke(1)
+	require.EqualError(t, err, "feed token with id 1 not found")
+}
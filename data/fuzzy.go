@@ -0,0 +1,110 @@
+package data
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxFuzzyCandidates bounds the number of rows compared with a search term.
+const maxFuzzyCandidates = 1000
+
+type fuzzyCandidate struct {
+	Term     string
+	Distance int
+	Weight   int
+}
+
+// maxEditDistance returns how many edits are tolerated for a term, short
+// terms are only matched exactly to avoid nonsensical suggestions.
+func maxEditDistance(term string) int {
+	length := len([]rune(term))
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func levenshtein(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// fuzzyMatches returns the candidates within the tolerated edit distance of
+// term, closest (and then most used) first.
+func fuzzyMatches(term string, candidates []fuzzyCandidate) []fuzzyCandidate {
+	term = strings.ToLower(term)
+	maxDistance := maxEditDistance(term)
+	if maxDistance == 0 {
+		return nil
+	}
+
+	matches := []fuzzyCandidate{}
+	for _, candidate := range candidates {
+		distance := levenshtein(term, strings.ToLower(candidate.Term))
+		if distance <= maxDistance {
+			candidate.Distance = distance
+			matches = append(matches, candidate)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Weight > matches[j].Weight
+	})
+	return matches
+}
+
+// queryTerms splits a search query into plain words, any full-text search
+// syntax like quotes, prefix stars or column filters is dropped.
+func queryTerms(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// prefixQuery builds a full-text search query matching all words of query
+// as prefixes.
+func prefixQuery(query string) string {
+	terms := queryTerms(query)
+	for i, term := range terms {
+		terms[i] = `"` + term + `"*`
+	}
+	return strings.Join(terms, " ")
+}
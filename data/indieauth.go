@@ -0,0 +1,172 @@
+package data
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	IndieAuthScopeCreate  = "create"
+	IndieAuthScopeRead    = "read"
+	IndieAuthScopeProfile = "profile"
+)
+
+// IndieAuthScopes are the scopes clients can request, create and read map
+// onto the write and read token scopes.
+var IndieAuthScopes = []string{
+	IndieAuthScopeCreate,
+	IndieAuthScopeRead,
+	IndieAuthScopeProfile,
+}
+
+const AuthorizationCodeLifetime = 10 * time.Minute
+
+// AuthorizationCode is issued to an IndieAuth client once the request has
+// been approved, only a hash of the code is stored.
+type AuthorizationCode struct {
+	gorm.Model
+	CodeHash      string `gorm:"unique"`
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Scopes              []string
+}
+
+type AuthorizationCodeRedeem struct {
+	Code         string
+	ClientID     string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+func (c *AuthorizationCode) ScopeList() []string {
+	return strings.Fields(c.Scope)
+}
+
+func (c *AuthorizationCode) HasScope(scope string) bool {
+	for _, s := range c.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenScopes returns the token scopes granted by the code, no token must be
+// issued when there are none.
+func (c *AuthorizationCode) TokenScopes() []TokenScope {
+	scopes := []TokenScope{}
+	if c.HasScope(IndieAuthScopeRead) {
+		scopes = append(scopes, TokenScopeRead)
+	}
+	if c.HasScope(IndieAuthScopeCreate) {
+		scopes = append(scopes, TokenScopeCreate)
+	}
+	return scopes
+}
+
+// ParseIndieAuthScopes returns the supported scopes of a space separated
+// scope parameter, unsupported scopes are dropped.
+func ParseIndieAuthScopes(scope string) []string {
+	requested := strings.Fields(scope)
+	scopes := []string{}
+	for _, supported := range IndieAuthScopes {
+		for _, s := range requested {
+			if s == supported {
+				scopes = append(scopes, s)
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+// IsValidClient reports whether client ID and redirect URI can be trusted
+// enough to redirect errors back to the client. Client metadata isn't
+// fetched, so the redirect URI must be on the same host as the client ID.
+func (req *AuthorizationRequest) IsValidClient() *ValidationError {
+	isErr := false
+	fields := make(map[string]string)
+
+	clientID, err := url.Parse(req.ClientID)
+	if err != nil || (clientID.Scheme != "http" && clientID.Scheme != "https") || clientID.Host == "" {
+		isErr = true
+		fields["ClientID"] = "Client ID must be an http or https URL"
+	}
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil || redirectURI.Host == "" {
+		isErr = true
+		fields["RedirectURI"] = "Redirect URI must be a URL"
+	} else if clientID != nil && (redirectURI.Scheme != clientID.Scheme || redirectURI.Host != clientID.Host) {
+		isErr = true
+		fields["RedirectURI"] = "Redirect URI must be on the same host as the client ID"
+	}
+
+	if isErr {
+		return NewValidationError("Client is invalid", fields)
+	}
+
+	return nil
+}
+
+func (req *AuthorizationRequest) IsValid() *ValidationError {
+	validationErr := req.IsValidClient()
+	if validationErr != nil {
+		return validationErr
+	}
+
+	isErr := false
+	fields := make(map[string]string)
+
+	if req.ResponseType != "code" {
+		isErr = true
+		fields["ResponseType"] = "Response type must be code"
+	}
+
+	if req.State == "" {
+		isErr = true
+		fields["State"] = "State is required"
+	}
+
+	if req.CodeChallenge == "" {
+		isErr = true
+		fields["CodeChallenge"] = "Code challenge is required"
+	}
+	if req.CodeChallengeMethod != "S256" {
+		isErr = true
+		fields["CodeChallengeMethod"] = "Code challenge method must be S256"
+	}
+
+	if isErr {
+		return NewValidationError("Authorization request is invalid", fields)
+	}
+
+	return nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against its S256 challenge.
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	hash := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(hash[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IndieAuthRepository struct {
+	db *gorm.DB
+}
+
+func NewIndieAuthRepository(db *gorm.DB) *IndieAuthRepository {
+	return &IndieAuthRepository{db}
+}
+
+// CreateCode stores an authorization code for an approved request and
+// returns the code, which can't be retrieved again afterwards.
+func (r *IndieAuthRepository) CreateCode(req AuthorizationRequest) (string, error) {
+	validationErr := req.IsValid()
+	if validationErr != nil {
+		return "", validationErr
+	}
+
+	code, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// expired codes can't be redeemed anymore
+		err := tx.Unscoped().Where("expires_at < ?", time.Now()).Delete(&AuthorizationCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&AuthorizationCode{
+			CodeHash:      hashTokenSecret(code),
+			ClientID:      req.ClientID,
+			RedirectURI:   req.RedirectURI,
+			Scope:         strings.Join(req.Scopes, " "),
+			CodeChallenge: req.CodeChallenge,
+			ExpiresAt:     time.Now().Add(AuthorizationCodeLifetime),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// RedeemCode returns the authorization code matching the request, nil is
+// returned when the code doesn't exist, is expired or doesn't match the
+// client, redirect URI or code verifier. Codes can only be redeemed once.
+func (r *IndieAuthRepository) RedeemCode(req AuthorizationCodeRedeem) (*AuthorizationCode, error) {
+	if req.Code == "" {
+		return nil, nil
+	}
+
+	var code AuthorizationCode
+	result := r.db.Where("code_hash = ?", hashTokenSecret(req.Code)).First(&code)
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// the delete decides which of concurrent requests redeems the code
+	result = r.db.Unscoped().Delete(&code)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, nil
+	}
+
+	if !code.ExpiresAt.After(time.Now()) ||
+		code.ClientID != req.ClientID ||
+		code.RedirectURI != req.RedirectURI ||
+		!verifyCodeChallenge(code.CodeChallenge, req.CodeVerifier) {
+		return nil, nil
+	}
+
+	return &code, nil
+}
@@ -0,0 +1,155 @@
+package data_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+const codeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func codeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func authorizationRequest() data.AuthorizationRequest {
+	return data.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "https://app.example/",
+		RedirectURI:         "https://app.example/callback",
+		State:               "1234",
+		CodeChallenge:       codeChallenge(codeVerifier),
+		CodeChallengeMethod: "S256",
+		Scopes:              []string{data.IndieAuthScopeCreate, data.IndieAuthScopeProfile},
+	}
+}
+
+func TestAuthorizationRequestIsValid(t *testing.T) {
+	req := authorizationRequest()
+	require.Nil(t, req.IsValid())
+
+	req.RedirectURI = "https://other.example/callback"
+	err := req.IsValid()
+	require.EqualError(t, err, "Client is invalid")
+	require.Equal(t, "Redirect URI must be on the same host as the client ID", err.Fields["RedirectURI"])
+
+	req = authorizationRequest()
+	req.ClientID = "app"
+	err = req.IsValidClient()
+	require.Equal(t, "Client ID must be an http or https URL", err.Fields["ClientID"])
+
+	req = data.AuthorizationRequest{
+		ClientID:            "https://app.example/",
+		RedirectURI:         "https://app.example/callback",
+		CodeChallengeMethod: "plain",
+	}
+	require.Nil(t, req.IsValidClient())
+	err = req.IsValid()
+	require.EqualError(t, err, "Authorization request is invalid")
+	require.Equal(t, map[string]string{
+		"ResponseType":        "Response type must be code",
+		"State":               "State is required",
+		"CodeChallenge":       "Code challenge is required",
+		"CodeChallengeMethod": "Code challenge method must be S256",
+	}, err.Fields)
+}
+
+func TestParseIndieAuthScopes(t *testing.T) {
+	require.Equal(t, []string{"create", "profile"}, data.ParseIndieAuthScopes("profile update create delete"))
+	require.Empty(t, data.ParseIndieAuthScopes(""))
+
+	code := data.AuthorizationCode{Scope: "create read profile"}
+	require.Equal(t, []data.TokenScope{data.TokenScopeRead, data.TokenScopeCreate}, code.TokenScopes())
+	code = data.AuthorizationCode{Scope: "profile"}
+	require.Empty(t, code.TokenScopes())
+}
+
+func TestIndieAuthRepositoryRedeemCode(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewIndieAuthRepository(db)
+
+	redeem := data.AuthorizationCodeRedeem{
+		ClientID:     "https://app.example/",
+		RedirectURI:  "https://app.example/callback",
+		CodeVerifier: codeVerifier,
+	}
+
+	// valid
+	secret, err := repo.CreateCode(authorizationRequest())
+	require.NoError(t, err)
+	redeem.Code = secret
+	code, err := repo.RedeemCode(redeem)
+	require.NoError(t, err)
+	require.NotNil(t, code)
+	require.Equal(t, "create profile", code.Scope)
+	require.Equal(t, "https://app.example/", code.ClientID)
+
+	// codes can only be redeemed once
+	code, err = repo.RedeemCode(redeem)
+	require.NoError(t, err)
+	require.Nil(t, code)
+
+	// mismatches invalidate the code
+	for _, modify := range []func(*data.AuthorizationCodeRedeem){
+		func(r *data.AuthorizationCodeRedeem) { r.CodeVerifier = "wrong-verifier-wrong-verifier-wrong-verifier" },
+		func(r *data.AuthorizationCodeRedeem) { r.ClientID = "https://other.example/" },
+		func(r *data.AuthorizationCodeRedeem) { r.RedirectURI = "https://app.example/other" },
+	} {
+		secret, err = repo.CreateCode(authorizationRequest())
+		require.NoError(t, err)
+
+		invalid := redeem
+		invalid.Code = secret
+		modify(&invalid)
+		code, err = repo.RedeemCode(invalid)
+		require.NoError(t, err)
+		require.Nil(t, code)
+
+		redeem.Code = secret
+		code, err = repo.RedeemCode(redeem)
+		require.NoError(t, err)
+		require.Nil(t, code)
+	}
+
+	// expired
+	secret, err = repo.CreateCode(authorizationRequest())
+	require.NoError(t, err)
+	err = db.Model(&data.AuthorizationCode{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second)).Error
+	require.NoError(t, err)
+	redeem.Code = secret
+	code, err = repo.RedeemCode(redeem)
+	require.NoError(t, err)
+	require.Nil(t, code)
+
+	// a concurrent request redeeming the code between the lookup and the
+	// delete wins
+	secret, err = repo.CreateCode(authorizationRequest())
+	require.NoError(t, err)
+	redeem.Code = secret
+	concurrent := db.Session(&gorm.Session{NewDB: true})
+	err = db.Callback().Query().After("gorm:query").Register("test:redeem", func(tx *gorm.DB) {
+		if tx.Statement.Table == "authorization_codes" {
+			require.NoError(t, concurrent.Unscoped().Where("1 = 1").Delete(&data.AuthorizationCode{}).Error)
+		}
+	})
+	require.NoError(t, err)
+	code, err = repo.RedeemCode(redeem)
+	require.NoError(t, db.Callback().Query().Remove("test:redeem"))
+	require.NoError(t, err)
+	require.Nil(t, code)
+
+	// invalid requests
+	req := authorizationRequest()
+	req.CodeChallenge = ""
+	_, err = repo.CreateCode(req)
+	require.EqualError(t, err, "Authorization request is invalid")
+}
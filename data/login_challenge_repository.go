@@ -0,0 +1,74 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginChallengeRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginChallengeRepository(db *gorm.DB) *LoginChallengeRepository {
+	return &LoginChallengeRepository{db}
+}
+
+// Create stores a new challenge and returns its token, expired challenges
+// are removed at the same time.
+func (r *LoginChallengeRepository) Create() (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("expires_at <= ?", time.Now()).Delete(&LoginChallenge{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&LoginChallenge{
+			TokenHash: hashTokenSecret(token),
+			ExpiresAt: time.Now().Add(LoginChallengeLifetime),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetByToken returns nil when the challenge doesn't exist or is expired.
+func (r *LoginChallengeRepository) GetByToken(token string) (*LoginChallenge, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	var challenge LoginChallenge
+	result := r.db.Where("token_hash = ?", hashTokenSecret(token)).First(&challenge)
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if challenge.IsExpired() {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
+// RecordFailure counts a wrong code, the challenge is removed once the
+// maximum number of attempts is reached.
+func (r *LoginChallengeRepository) RecordFailure(challenge *LoginChallenge) error {
+	challenge.Attempts++
+	if challenge.Attempts >= MaxLoginChallengeAttempts {
+		return r.Delete(challenge)
+	}
+	return r.db.Model(challenge).UpdateColumn("attempts", challenge.Attempts).Error
+}
+
+func (r *LoginChallengeRepository) Delete(challenge *LoginChallenge) error {
+	return r.db.Unscoped().Delete(challenge).Error
+}
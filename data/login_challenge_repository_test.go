@@ -0,0 +1,74 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestLoginChallengeRepository(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewLoginChallengeRepository(db)
+
+	challenge, err := repo.GetByToken("")
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	token, err := repo.Create()
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	require.NotEqual(t, token, challenge.TokenHash)
+	require.WithinDuration(t, time.Now().Add(data.LoginChallengeLifetime), challenge.ExpiresAt, time.Minute)
+
+	challenge, err = repo.GetByToken("unknown")
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	// deleted after too many failures
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	for i := 1; i < data.MaxLoginChallengeAttempts; i++ {
+		require.NoError(t, repo.RecordFailure(challenge))
+		challenge, err = repo.GetByToken(token)
+		require.NoError(t, err)
+		require.Equal(t, i, challenge.Attempts)
+	}
+	require.NoError(t, repo.RecordFailure(challenge))
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	// expired
+	token, err = repo.Create()
+	require.NoError(t, err)
+	db.Model(&data.LoginChallenge{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second))
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	// expired challenges are removed when creating new ones
+	_, err = repo.Create()
+	require.NoError(t, err)
+	var count int64
+	db.Model(&data.LoginChallenge{}).Unscoped().Count(&count)
+	require.Equal(t, int64(1), count)
+
+	// delete
+	token, err = repo.Create()
+	require.NoError(t, err)
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(challenge))
+	challenge, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+}
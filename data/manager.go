@@ -1,7 +1,9 @@
 package data
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"github.com/go-gormigrate/gormigrate/v2"
@@ -232,5 +234,349 @@ func NewMigrator(db *gorm.DB) *gormigrate.Gormigrate {
 				return err
 			},
 		},
+		{
+			ID: "202303041200",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Exec(`CREATE VIRTUAL TABLE bookmarks_fts_vocab USING fts5vocab(bookmarks_fts, col);`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec("DROP TABLE bookmarks_fts_vocab;").Error
+			},
+		},
+		{
+			ID: "202303111000",
+			Migrate: func(tx *gorm.DB) error {
+				type Settings struct {
+					PerPage int
+					Sort    string
+				}
+				type Bookmark struct {
+					Visits uint `gorm:"not null;default:0"`
+				}
+				err := tx.Migrator().AddColumn(&Settings{}, "PerPage")
+				if err != nil {
+					return err
+				}
+				err = tx.Migrator().AddColumn(&Settings{}, "Sort")
+				if err != nil {
+					return err
+				}
+				// earlier migrations reference the current Bookmark model through
+				// their many2many relations and might have added it already
+				if tx.Migrator().HasColumn(&Bookmark{}, "Visits") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&Bookmark{}, "Visits")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				err := tx.Exec("ALTER TABLE settings DROP COLUMN per_page;").Error
+				if err != nil {
+					return err
+				}
+				err = tx.Exec("ALTER TABLE settings DROP COLUMN sort;").Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE bookmarks DROP COLUMN visits;").Error
+			},
+		},
+		{
+			ID: "202303181000",
+			Migrate: func(tx *gorm.DB) error {
+				type Bookmark struct {
+					ID     uint
+					URL    string
+					Domain string `gorm:"index"`
+				}
+				// earlier migrations reference the current Bookmark model through
+				// their many2many relations and might have added it already
+				if !tx.Migrator().HasColumn(&Bookmark{}, "Domain") {
+					err := tx.Migrator().AddColumn(&Bookmark{}, "Domain")
+					if err != nil {
+						return err
+					}
+				}
+				if !tx.Migrator().HasIndex(&Bookmark{}, "Domain") {
+					err := tx.Migrator().CreateIndex(&Bookmark{}, "Domain")
+					if err != nil {
+						return err
+					}
+				}
+
+				var bookmarks []Bookmark
+				err := tx.Unscoped().Find(&bookmarks).Error
+				if err != nil {
+					return err
+				}
+				for _, bookmark := range bookmarks {
+					err = tx.Model(&bookmark).UpdateColumn("domain", ExtractDomain(bookmark.URL)).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				err := tx.Exec("DROP INDEX idx_bookmarks_domain;").Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE bookmarks DROP COLUMN domain;").Error
+			},
+		},
+		{
+			ID: "202303251000",
+			Migrate: func(tx *gorm.DB) error {
+				type Token struct {
+					gorm.Model
+					Name       string
+					SecretHash string `gorm:"unique"`
+				}
+				return tx.AutoMigrate(&Token{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("tokens")
+			},
+		},
+		{
+			ID: "202304011000",
+			Migrate: func(tx *gorm.DB) error {
+				type Token struct {
+					Scopes     string `gorm:"not null;default:'read write'"`
+					LastUsedAt *time.Time
+					ExpiresAt  *time.Time
+				}
+				for _, field := range []string{"Scopes", "LastUsedAt", "ExpiresAt"} {
+					err := tx.Migrator().AddColumn(&Token{}, field)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, column := range []string{"scopes", "last_used_at", "expires_at"} {
+					err := tx.Exec(fmt.Sprintf("ALTER TABLE tokens DROP COLUMN %s;", column)).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "202304081000",
+			Migrate: func(tx *gorm.DB) error {
+				type Webhook struct {
+					gorm.Model
+					URL    string `gorm:"not null"`
+					Secret string
+				}
+				type WebhookDelivery struct {
+					gorm.Model
+					WebhookID     uint `gorm:"index"`
+					Event         string
+					Payload       string
+					State         string `gorm:"index;default:'pending'"`
+					Attempts      int
+					NextAttemptAt time.Time `gorm:"index"`
+					LastStatus    int
+					LastError     string
+					DeliveredAt   *time.Time
+				}
+				return tx.AutoMigrate(&Webhook{}, &WebhookDelivery{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("webhook_deliveries", "webhooks")
+			},
+		},
+		{
+			ID: "202304151000",
+			Migrate: func(tx *gorm.DB) error {
+				type FeedToken struct {
+					gorm.Model
+					Name       string
+					SecretHash string `gorm:"unique"`
+					LastUsedAt *time.Time
+				}
+				return tx.AutoMigrate(&FeedToken{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("feed_tokens")
+			},
+		},
+		{
+			ID: "202304221000",
+			Migrate: func(tx *gorm.DB) error {
+				type ActorKey struct {
+					gorm.Model
+					PrivateKey string
+					PublicKey  string
+				}
+				type Follower struct {
+					gorm.Model
+					ActorID      string `gorm:"unique"`
+					Inbox        string
+					SharedInbox  string
+					FollowID     string
+					LocalActorID string
+				}
+				type ActivityDelivery struct {
+					gorm.Model
+					Type          string
+					Inbox         string
+					LocalActorID  string
+					FollowerID    uint
+					BookmarkID    uint
+					State         string `gorm:"index;default:'pending'"`
+					Attempts      int
+					NextAttemptAt time.Time `gorm:"index"`
+					LastStatus    int
+					LastError     string
+					DeliveredAt   *time.Time
+				}
+				return tx.AutoMigrate(&ActorKey{}, &Follower{}, &ActivityDelivery{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("activity_deliveries", "followers", "actor_keys")
+			},
+		},
+		{
+			ID: "202304291000",
+			Migrate: func(tx *gorm.DB) error {
+				type Token struct {
+					ClientID string
+				}
+				type AuthorizationCode struct {
+					gorm.Model
+					CodeHash      string `gorm:"unique"`
+					ClientID      string
+					RedirectURI   string
+					Scope         string
+					CodeChallenge string
+					ExpiresAt     time.Time
+				}
+				err := tx.Migrator().AddColumn(&Token{}, "ClientID")
+				if err != nil {
+					return err
+				}
+				return tx.AutoMigrate(&AuthorizationCode{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				err := tx.Migrator().DropTable("authorization_codes")
+				if err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE tokens DROP COLUMN client_id;").Error
+			},
+		},
+		{
+			ID: "202305061000",
+			Migrate: func(tx *gorm.DB) error {
+				type Session struct {
+					LastSeenAt time.Time
+					ExpiresAt  time.Time
+				}
+				err := tx.Migrator().AddColumn(&Session{}, "LastSeenAt")
+				if err != nil {
+					return err
+				}
+				err = tx.Migrator().AddColumn(&Session{}, "ExpiresAt")
+				if err != nil {
+					return err
+				}
+				// existing sessions never expired, they need to log in again
+				return tx.Exec("DELETE FROM sessions;").Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				err := tx.Exec("ALTER TABLE sessions DROP COLUMN expires_at;").Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE sessions DROP COLUMN last_seen_at;").Error
+			},
+		},
+		{
+			ID: "202305131000",
+			Migrate: func(tx *gorm.DB) error {
+				type Settings struct {
+					TOTPSecret      string
+					TOTPLastCounter int64
+					RecoveryCodes   string
+				}
+				type LoginChallenge struct {
+					gorm.Model
+					TokenHash string `gorm:"unique"`
+					Attempts  int
+					ExpiresAt time.Time
+				}
+				for _, field := range []string{"TOTPSecret", "TOTPLastCounter", "RecoveryCodes"} {
+					err := tx.Migrator().AddColumn(&Settings{}, field)
+					if err != nil {
+						return err
+					}
+				}
+				return tx.AutoMigrate(&LoginChallenge{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				err := tx.Migrator().DropTable("login_challenges")
+				if err != nil {
+					return err
+				}
+				for _, column := range []string{"recovery_codes", "totp_last_counter", "totp_secret"} {
+					err = tx.Exec(fmt.Sprintf("ALTER TABLE settings DROP COLUMN %s;", column)).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "202305201000",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Exec(`CREATE VIRTUAL TABLE tags_fts_vocab USING fts5vocab(tags_fts, row);`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec("DROP TABLE tags_fts_vocab;").Error
+			},
+		},
+		{
+			// tokens issued by IndieAuth clients for the "create" scope used to
+			// get the full write scope
+			ID: "202305271000",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Exec(`UPDATE tokens SET scopes = trim(replace(' ' || scopes || ' ', ' write ', ' create ')) WHERE client_id != '';`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec(`UPDATE tokens SET scopes = trim(replace(' ' || scopes || ' ', ' create ', ' write ')) WHERE client_id != '';`).Error
+			},
+		},
+		{
+			ID: "202306031000",
+			Migrate: func(tx *gorm.DB) error {
+				type Settings struct {
+					gorm.Model
+					TwoFactorFailures int
+					TwoFactorFailedAt *time.Time
+				}
+				for _, field := range []string{"TwoFactorFailures", "TwoFactorFailedAt"} {
+					err := tx.Migrator().AddColumn(&Settings{}, field)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, column := range []string{"two_factor_failed_at", "two_factor_failures"} {
+					err := tx.Exec(fmt.Sprintf("ALTER TABLE settings DROP COLUMN %s;", column)).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
 	})
 }
@@ -25,3 +25,22 @@ func TestMigrations(t *testing.T) {
 	err := migrator.Migrate()
 	require.NoError(t, err)
 }
+
+func TestMigrateIndieAuthTokenScopes(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	migrator := data.NewMigrator(db)
+	require.NoError(t, migrator.RollbackTo("202305201000"))
+
+	client := data.Token{Name: "client", SecretHash: "client", Scopes: "read write", ClientID: "https://app.example.com/"}
+	require.NoError(t, db.Create(&client).Error)
+	personal := data.Token{Name: "personal", SecretHash: "personal", Scopes: "read write"}
+	require.NoError(t, db.Create(&personal).Error)
+
+	require.NoError(t, migrator.Migrate())
+	require.NoError(t, db.First(&client, client.ID).Error)
+	require.Equal(t, "read create", client.Scopes)
+	require.NoError(t, db.First(&personal, personal.ID).Error)
+	require.Equal(t, "read write", personal.Scopes)
+}
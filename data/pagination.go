@@ -0,0 +1,226 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cursor points at the first or last bookmark of a page, backward cursors
+// are used to fetch the page before it. Keyset is the name of the ordering
+// the cursor was created for, its value is meaningless for any other.
+type cursor struct {
+	Keyset   string `json:"k"`
+	Value    string `json:"v"`
+	ID       uint   `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	err = json.Unmarshal(decoded, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// keyset describes the column bookmarks are ordered by, ties are broken by
+// the bookmark ID.
+type keyset struct {
+	name   string
+	column string
+	desc   bool
+	value  func(b *Bookmark) (string, error)
+	parse  func(value string) (interface{}, error)
+}
+
+func keysetTime(name, column string, desc bool, value func(b *Bookmark) time.Time) keyset {
+	return keyset{
+		name:   name,
+		column: column,
+		desc:   desc,
+		value: func(b *Bookmark) (string, error) {
+			return value(b).Format(time.RFC3339Nano), nil
+		},
+		parse: func(value string) (interface{}, error) {
+			return time.Parse(time.RFC3339Nano, value)
+		},
+	}
+}
+
+var keysetCreatedAt = keysetTime("newest", "bookmarks.created_at", true, func(b *Bookmark) time.Time {
+	return b.CreatedAt
+})
+
+var keysetOldest = keysetTime("oldest", "bookmarks.created_at", false, func(b *Bookmark) time.Time {
+	return b.CreatedAt
+})
+
+var keysetUpdatedAt = keysetTime("updated", "bookmarks.updated_at", true, func(b *Bookmark) time.Time {
+	return b.UpdatedAt
+})
+
+var keysetTitle = keyset{
+	name:   "title",
+	column: "bookmarks.title COLLATE NOCASE",
+	value: func(b *Bookmark) (string, error) {
+		return b.Title, nil
+	},
+	parse: func(value string) (interface{}, error) {
+		return value, nil
+	},
+}
+
+var keysetVisits = keyset{
+	name:   "visited",
+	column: "bookmarks.visits",
+	desc:   true,
+	value: func(b *Bookmark) (string, error) {
+		return strconv.FormatUint(uint64(b.Visits), 10), nil
+	},
+	parse: func(value string) (interface{}, error) {
+		return strconv.ParseUint(value, 10, 64)
+	},
+}
+
+// keysetForSort returns the keyset for a whitelisted sort option, match is
+// the full-text search query when sorting search results by relevance.
+func keysetForSort(db *gorm.DB, sort BookmarkSort, match string) keyset {
+	switch sort {
+	case BookmarkSortOldest:
+		return keysetOldest
+	case BookmarkSortTitle:
+		return keysetTitle
+	case BookmarkSortUpdated:
+		return keysetUpdatedAt
+	case BookmarkSortVisited:
+		return keysetVisits
+	case BookmarkSortRelevance:
+		if match != "" {
+			return keysetSearchRank(db, match)
+		}
+	}
+	return keysetCreatedAt
+}
+
+// keysetSearchRank orders by relevance for the given full-text search query.
+func keysetSearchRank(db *gorm.DB, match string) keyset {
+	return keyset{
+		name:   "relevance",
+		column: "bookmarks_fts.rank",
+		value: func(b *Bookmark) (string, error) {
+			var rank float64
+			err := db.Table("bookmarks_fts").
+				Select("rank").
+				Where("bookmarks_fts MATCH ? AND rowid = ?", match, b.ID).
+				Scan(&rank).
+				Error
+			return strconv.FormatFloat(rank, 'g', -1, 64), err
+		},
+		parse: func(value string) (interface{}, error) {
+			return strconv.ParseFloat(value, 64)
+		},
+	}
+}
+
+type page struct {
+	Items      []Bookmark
+	HasPrev    bool
+	PrevCursor string
+	HasNext    bool
+	NextCursor string
+}
+
+// paginate fetches the page of bookmarks after (or before, for backward
+// cursors) the given cursor. An invalid or empty cursor, or one created for a
+// different sort order, fetches the first page.
+func paginate(query *gorm.DB, ks keyset, rawCursor string, limit int) (*page, error) {
+	var c *cursor
+	var value interface{}
+	if rawCursor != "" {
+		decoded, err := decodeCursor(rawCursor)
+		if err == nil && decoded.Keyset == ks.name {
+			value, err = ks.parse(decoded.Value)
+			if err == nil {
+				c = decoded
+			}
+		}
+	}
+
+	backward := c != nil && c.Backward
+	desc := ks.desc != backward
+	op, direction := ">", "asc"
+	if desc {
+		op, direction = "<", "desc"
+	}
+
+	if c != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND bookmarks.id %s ?))", ks.column, op, ks.column, op),
+			value, value, c.ID,
+		)
+	}
+
+	var bookmarks []Bookmark
+	err := query.
+		Order(fmt.Sprintf("%s %s", ks.column, direction)).
+		Order(fmt.Sprintf("bookmarks.id %s", direction)).
+		Limit(limit + 1).
+		Find(&bookmarks).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(bookmarks) > limit
+	if more {
+		bookmarks = bookmarks[:limit]
+	}
+
+	p := &page{Items: bookmarks}
+	if len(bookmarks) == 0 {
+		return p, nil
+	}
+
+	if backward {
+		for i, j := 0, len(bookmarks)-1; i < j; i, j = i+1, j-1 {
+			bookmarks[i], bookmarks[j] = bookmarks[j], bookmarks[i]
+		}
+		p.HasPrev = more
+		p.HasNext = true
+	} else {
+		p.HasPrev = c != nil
+		p.HasNext = more
+	}
+
+	first := &bookmarks[0]
+	firstValue, err := ks.value(first)
+	if err != nil {
+		return nil, err
+	}
+	p.PrevCursor = cursor{Keyset: ks.name, Value: firstValue, ID: first.ID, Backward: true}.encode()
+
+	last := &bookmarks[len(bookmarks)-1]
+	lastValue, err := ks.value(last)
+	if err != nil {
+		return nil, err
+	}
+	p.NextCursor = cursor{Keyset: ks.name, Value: lastValue, ID: last.ID}.encode()
+
+	return p, nil
+}
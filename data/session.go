@@ -1,17 +1,40 @@
 package data
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+const (
+	// SessionLifetime is how long a session stays valid without being used.
+	SessionLifetime = 14 * 24 * time.Hour
+
+	// sessionTouchInterval throttles updating the last seen time, so that not
+	// every request writes to the database.
+	sessionTouchInterval = time.Minute
+)
+
 type Session struct {
 	gorm.Model
-	Token     string `gorm:"unique"`
-	UserAgent string
-	IP        string
+	Token      string `gorm:"unique"`
+	UserAgent  string
+	IP         string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
 }
 
 type SessionCreate struct {
 	UserAgent string
 	IP        string
 }
+
+func (s *Session) IsExpired() bool {
+	return !s.ExpiresAt.After(time.Now())
+}
+
+// NeedsTouch reports whether the last seen time is outdated enough to be
+// recorded again.
+func (s *Session) NeedsTouch() bool {
+	return time.Since(s.LastSeenAt) >= sessionTouchInterval
+}
@@ -1,6 +1,9 @@
 package data
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -25,22 +28,88 @@ func (r *SessionRepository) GetByToken(token string) (*Session, error) {
 	return &session, nil
 }
 
+// Create stores a new session, expired sessions are removed at the same
+// time.
 func (r *SessionRepository) Create(req *SessionCreate) (*Session, error) {
 	guid, err := uuid.NewUUID()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	session := &Session{
-		Token:     guid.String(),
-		UserAgent: req.UserAgent,
-		IP:        req.IP,
+		Token:      guid.String(),
+		UserAgent:  req.UserAgent,
+		IP:         req.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionLifetime),
 	}
 
-	result := r.db.Create(session)
-	if result.Error != nil {
-		return nil, result.Error
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("expires_at <= ?", now).Delete(&Session{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(session).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return session, nil
 }
+
+// List returns the sessions which haven't expired yet, most recently seen
+// first.
+func (r *SessionRepository) List() ([]Session, error) {
+	var sessions []Session
+	err := r.db.Where("expires_at > ?", time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Touch records that the session has just been used and extends its expiry.
+func (r *SessionRepository) Touch(session *Session) error {
+	now := time.Now()
+	err := r.db.Model(session).UpdateColumns(map[string]interface{}{
+		"last_seen_at": now,
+		"expires_at":   now.Add(SessionLifetime),
+	}).Error
+	if err != nil {
+		return err
+	}
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(SessionLifetime)
+	return nil
+}
+
+func (r *SessionRepository) Delete(id uint) error {
+	result := r.db.Unscoped().Delete(&Session{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("session with id %d not found", id)
+	}
+	return nil
+}
+
+func (r *SessionRepository) DeleteByToken(token string) error {
+	return r.db.Unscoped().Where("token = ?", token).Delete(&Session{}).Error
+}
+
+// DeleteAll logs out everywhere.
+func (r *SessionRepository) DeleteAll() error {
+	return r.db.Unscoped().Where("1 = 1").Delete(&Session{}).Error
+}
+
+// DeleteOthers logs out everywhere except for the session with the given
+// ID.
+func (r *SessionRepository) DeleteOthers(id uint) error {
+	return r.db.Unscoped().Where("id <> ?", id).Delete(&Session{}).Error
+}
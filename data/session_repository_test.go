@@ -2,6 +2,7 @@ package data_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -51,4 +52,110 @@ func TestSessionRepositoryCreate(t *testing.T) {
 	require.NotEmpty(t, session.Token)
 	require.Equal(t, "test-agent", session.UserAgent)
 	require.Equal(t, "test-ip", session.IP)
+	require.WithinDuration(t, time.Now(), session.LastSeenAt, time.Second)
+	require.WithinDuration(t, time.Now().Add(data.SessionLifetime), session.ExpiresAt, time.Second)
+	require.False(t, session.IsExpired())
+	require.False(t, session.NeedsTouch())
+}
+
+func TestSessionRepositoryCreatePurgesExpired(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+
+	result := db.Create(&data.Session{Token: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, result.Error)
+	expired, err := repo.GetByToken("expired")
+	require.NoError(t, err)
+	require.True(t, expired.IsExpired())
+
+	_, err = repo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	expired, err = repo.GetByToken("expired")
+	require.NoError(t, err)
+	require.Nil(t, expired)
+}
+
+func TestSessionRepositoryTouch(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+
+	lastSeen := time.Now().Add(-time.Hour)
+	result := db.Create(&data.Session{
+		Token:      "token",
+		LastSeenAt: lastSeen,
+		ExpiresAt:  lastSeen.Add(data.SessionLifetime),
+	})
+	require.NoError(t, result.Error)
+
+	session, err := repo.GetByToken("token")
+	require.NoError(t, err)
+	require.True(t, session.NeedsTouch())
+
+	err = repo.Touch(session)
+	require.NoError(t, err)
+	require.False(t, session.NeedsTouch())
+
+	session, err = repo.GetByToken("token")
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), session.LastSeenAt, time.Second)
+	require.WithinDuration(t, time.Now().Add(data.SessionLifetime), session.ExpiresAt, time.Second)
+}
+
+func TestSessionRepositoryListAndDelete(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+
+	first, err := repo.Create(&data.SessionCreate{UserAgent: "first"})
+	require.NoError(t, err)
+	second, err := repo.Create(&data.SessionCreate{UserAgent: "second"})
+	require.NoError(t, err)
+	result := db.Create(&data.Session{Token: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, result.Error)
+
+	sessions, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, second.ID, sessions[0].ID)
+	require.Equal(t, first.ID, sessions[1].ID)
+
+	err = repo.Delete(first.ID)
+	require.NoError(t, err)
+	err = repo.Delete(first.ID)
+	require.Error(t, err)
+
+	err = repo.DeleteByToken(second.Token)
+	require.NoError(t, err)
+	sessions, err = repo.List()
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	_, err = repo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	err = repo.DeleteAll()
+	require.NoError(t, err)
+	sessions, err = repo.List()
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestSessionRepositoryDeleteOthers(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+
+	current, err := repo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	_, err = repo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	err = repo.DeleteOthers(current.ID)
+	require.NoError(t, err)
+	sessions, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, current.ID, sessions[0].ID)
 }
@@ -1,12 +1,94 @@
 package data
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength applies when changing the password.
+const MinPasswordLength = 8
 
 type Settings struct {
 	gorm.Model
 	Password string
+	PerPage  int
+	Sort     BookmarkSort
+
+	// TOTPSecret is only set while two-factor authentication is enabled,
+	// TOTPLastCounter is the last time step a code was accepted for so that
+	// codes can't be used twice.
+	TOTPSecret      string
+	TOTPLastCounter int64
+	// RecoveryCodes are the space separated hashes of the unused recovery
+	// codes.
+	RecoveryCodes string
+	// TwoFactorFailures counts wrong codes, failures are forgotten once the
+	// last one at TwoFactorFailedAt is older than TwoFactorLockout.
+	TwoFactorFailures int
+	TwoFactorFailedAt *time.Time
 }
 
 type SettingsUpsert struct {
 	Password string
+	PerPage  int
+	Sort     BookmarkSort
+}
+
+func (s *Settings) TwoFactorEnabled() bool {
+	return s.TOTPSecret != ""
+}
+
+// TwoFactorLocked reports whether there were too many wrong codes recently,
+// no codes are accepted until the lockout is over.
+func (s *Settings) TwoFactorLocked() bool {
+	return s.TwoFactorFailures >= MaxTwoFactorFailures &&
+		s.TwoFactorFailedAt != nil &&
+		time.Since(*s.TwoFactorFailedAt) < TwoFactorLockout
+}
+
+func (s *Settings) RecoveryCodeCount() int {
+	return len(strings.Fields(s.RecoveryCodes))
+}
+
+// PasswordChange is the form to change the password, the current password
+// needs to be verified separately.
+type PasswordChange struct {
+	Current      string
+	New          string
+	Confirmation string
+}
+
+// IsValidPassword reports whether password is long enough to be set.
+func IsValidPassword(password string) bool {
+	return utf8.RuneCountInString(password) >= MinPasswordLength
+}
+
+func (req *PasswordChange) IsValid() *ValidationError {
+	isErr := false
+	fields := make(map[string]string)
+
+	if req.Current == "" {
+		isErr = true
+		fields["Current"] = "Current password is required"
+	}
+
+	if !IsValidPassword(req.New) {
+		isErr = true
+		fields["New"] = fmt.Sprintf("New password must be at least %d characters long", MinPasswordLength)
+	}
+
+	if req.Confirmation != req.New {
+		isErr = true
+		fields["Confirmation"] = "Passwords don't match"
+	}
+
+	if isErr {
+		return NewValidationError("Password change is invalid", fields)
+	}
+
+	return nil
 }
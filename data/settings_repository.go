@@ -2,6 +2,8 @@ package data
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -58,6 +60,8 @@ func (r *SettingsRepository) insert(req *SettingsUpsert) error {
 	}
 	value := Settings{
 		Password: hashedPassword,
+		PerPage:  req.PerPage,
+		Sort:     req.Sort,
 	}
 
 	result := r.db.Create(&value)
@@ -72,7 +76,137 @@ func (r *SettingsRepository) update(existing *Settings, req *SettingsUpsert) err
 		}
 		existing.Password = hashedPassword
 	}
+	if req.PerPage != 0 {
+		existing.PerPage = req.PerPage
+	}
+	if req.Sort != "" {
+		existing.Sort = req.Sort
+	}
 
 	result := r.db.Save(existing)
 	return result.Error
 }
+
+// EnableTwoFactor stores secret once code shows that it has been added to an
+// authenticator app. The returned recovery codes can't be retrieved again.
+func (r *SettingsRepository) EnableTwoFactor(secret, code string) ([]string, error) {
+	settings, err := r.Get()
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, errors.New("settings are not initialized")
+	}
+	if settings.TwoFactorEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	counter, ok := util.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, NewValidationError("Two-factor authentication is invalid", map[string]string{
+			"Code": "Code is wrong",
+		})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	settings.TOTPSecret = secret
+	settings.TOTPLastCounter = counter
+	settings.RecoveryCodes = strings.Join(hashes, " ")
+	err = r.db.Save(settings).Error
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *SettingsRepository) DisableTwoFactor() error {
+	settings, err := r.Get()
+	if err != nil || settings == nil {
+		return err
+	}
+
+	settings.TOTPSecret = ""
+	settings.TOTPLastCounter = 0
+	settings.RecoveryCodes = ""
+	settings.TwoFactorFailures = 0
+	settings.TwoFactorFailedAt = nil
+	return r.db.Save(settings).Error
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, including the unused
+// ones.
+func (r *SettingsRepository) RegenerateRecoveryCodes() ([]string, error) {
+	settings, err := r.Get()
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || !settings.TwoFactorEnabled() {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	settings.RecoveryCodes = strings.Join(hashes, " ")
+	err = r.db.Save(settings).Error
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor reports whether code is a current TOTP code or an unused
+// recovery code. Either can only be used once. Wrong codes are counted and
+// no code is accepted while that locks the second factor.
+func (r *SettingsRepository) VerifyTwoFactor(code string) (bool, error) {
+	settings, err := r.Get()
+	if err != nil {
+		return false, err
+	}
+	if settings == nil || !settings.TwoFactorEnabled() || settings.TwoFactorLocked() {
+		return false, nil
+	}
+
+	counter, ok := util.ValidateTOTP(settings.TOTPSecret, code, time.Now())
+	if ok {
+		if counter <= settings.TOTPLastCounter {
+			return false, r.recordTwoFactorFailure(settings)
+		}
+		settings.TOTPLastCounter = counter
+		settings.TwoFactorFailures = 0
+		settings.TwoFactorFailedAt = nil
+		return true, r.db.Save(settings).Error
+	}
+
+	hash := hashRecoveryCode(code)
+	hashes := strings.Fields(settings.RecoveryCodes)
+	for i, existing := range hashes {
+		if existing == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			settings.RecoveryCodes = strings.Join(hashes, " ")
+			settings.TwoFactorFailures = 0
+			settings.TwoFactorFailedAt = nil
+			return true, r.db.Save(settings).Error
+		}
+	}
+	return false, r.recordTwoFactorFailure(settings)
+}
+
+// recordTwoFactorFailure counts a wrong code in a single statement so that
+// concurrent attempts can't overwrite each other's count.
+func (r *SettingsRepository) recordTwoFactorFailure(settings *Settings) error {
+	now := time.Now()
+	return r.db.Model(settings).UpdateColumns(map[string]interface{}{
+		"two_factor_failures": gorm.Expr(
+			"CASE WHEN two_factor_failed_at IS NULL OR two_factor_failed_at <= ? THEN 1 ELSE two_factor_failures + 1 END",
+			now.Add(-TwoFactorLockout),
+		),
+		"two_factor_failed_at": now,
+	}).Error
+}
@@ -1,13 +1,16 @@
 package data_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/test"
+	"github.com/chdorner/submarine/util"
 )
 
 func TestIsInitialized(t *testing.T) {
@@ -69,4 +72,131 @@ func TestSettingsRepositoryUpsert(t *testing.T) {
 	db.First(&actual)
 	err = bcrypt.CompareHashAndPassword([]byte(actual.Password), []byte("topsecret"))
 	require.NoError(t, err)
+
+	// update list preferences keeps password
+	err = repo.Upsert(data.SettingsUpsert{PerPage: 25, Sort: data.BookmarkSortTitle})
+	require.NoError(t, err)
+	db.First(&actual)
+	require.Equal(t, 25, actual.PerPage)
+	require.Equal(t, data.BookmarkSortTitle, actual.Sort)
+	err = bcrypt.CompareHashAndPassword([]byte(actual.Password), []byte("topsecret"))
+	require.NoError(t, err)
+}
+
+func TestSettingsRepositoryTwoFactor(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "supersecret"})
+	require.NoError(t, err)
+
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	counter := util.TOTPCounter(time.Now())
+	code, err := util.TOTPCode(secret, counter)
+	require.NoError(t, err)
+
+	// not enabled yet
+	ok, err := repo.VerifyTwoFactor(code)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// enabling requires a valid code
+	_, err = repo.EnableTwoFactor(secret, "000000")
+	require.IsType(t, &data.ValidationError{}, err)
+	require.Equal(t, "Code is wrong", err.(*data.ValidationError).Fields["Code"])
+
+	recoveryCodes, err := repo.EnableTwoFactor(secret, code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, data.RecoveryCodeCount)
+	require.Regexp(t, "^[a-z2-7]{5}-[a-z2-7]{5}$", recoveryCodes[0])
+	settings, err := repo.Get()
+	require.NoError(t, err)
+	require.True(t, settings.TwoFactorEnabled())
+	require.Equal(t, data.RecoveryCodeCount, settings.RecoveryCodeCount())
+	require.NotContains(t, settings.RecoveryCodes, recoveryCodes[0])
+
+	// codes can't be used twice
+	ok, err = repo.VerifyTwoFactor(code)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	next, err := util.TOTPCode(secret, counter+1)
+	require.NoError(t, err)
+	ok, err = repo.VerifyTwoFactor(next)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = repo.VerifyTwoFactor(next)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// recovery codes
+	ok, err = repo.VerifyTwoFactor(strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", "")))
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = repo.VerifyTwoFactor(recoveryCodes[0])
+	require.NoError(t, err)
+	require.False(t, ok)
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.Equal(t, data.RecoveryCodeCount-1, settings.RecoveryCodeCount())
+
+	ok, err = repo.VerifyTwoFactor("wrong")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// regenerating replaces unused codes
+	regenerated, err := repo.RegenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, regenerated, data.RecoveryCodeCount)
+	ok, err = repo.VerifyTwoFactor(recoveryCodes[1])
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = repo.VerifyTwoFactor(regenerated[0])
+	require.NoError(t, err)
+	require.True(t, ok)
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.Zero(t, settings.TwoFactorFailures)
+
+	// too many wrong codes lock even valid codes out
+	for i := 0; i < data.MaxTwoFactorFailures; i++ {
+		ok, err = repo.VerifyTwoFactor("wrong")
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.True(t, settings.TwoFactorLocked())
+	ok, err = repo.VerifyTwoFactor(regenerated[1])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// old failures are forgotten
+	err = db.Model(settings).Update("two_factor_failed_at", time.Now().Add(-data.TwoFactorLockout)).Error
+	require.NoError(t, err)
+	ok, err = repo.VerifyTwoFactor("wrong")
+	require.NoError(t, err)
+	require.False(t, ok)
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.Equal(t, 1, settings.TwoFactorFailures)
+	require.False(t, settings.TwoFactorLocked())
+	ok, err = repo.VerifyTwoFactor(regenerated[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// disable
+	err = repo.DisableTwoFactor()
+	require.NoError(t, err)
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.False(t, settings.TwoFactorEnabled())
+	require.Zero(t, settings.RecoveryCodeCount())
+	ok, err = repo.VerifyTwoFactor(regenerated[1])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = repo.RegenerateRecoveryCodes()
+	require.Error(t, err)
 }
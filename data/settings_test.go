@@ -0,0 +1,23 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+)
+
+func TestPasswordChangeIsValid(t *testing.T) {
+	req := data.PasswordChange{Current: "secret", New: "correct horse", Confirmation: "correct horse"}
+	require.Nil(t, req.IsValid())
+
+	req = data.PasswordChange{New: "short", Confirmation: "other"}
+	err := req.IsValid()
+	require.NotNil(t, err)
+	require.Equal(t, map[string]string{
+		"Current":      "Current password is required",
+		"New":          "New password must be at least 8 characters long",
+		"Confirmation": "Passwords don't match",
+	}, err.Fields)
+}
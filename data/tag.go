@@ -14,6 +14,12 @@ type Tag struct {
 	Bookmarks []Bookmark `gorm:"many2many:bookmark_tags;"`
 }
 
+type TagCount struct {
+	Name        string
+	DisplayName string
+	Count       int64
+}
+
 func (t *Tag) BeforeSave(tx *gorm.DB) error {
 	t.Name = strings.ToLower(t.DisplayName)
 	return nil
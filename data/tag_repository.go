@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"strings"
 
 	"gorm.io/gorm"
@@ -52,11 +53,173 @@ func (r *TagRepository) Upsert(tagNames []string) ([]Tag, error) {
 	return tags, nil
 }
 
+// Rename changes the display name of a tag, when a different tag with the
+// new name already exists both tags are merged into the existing one.
+func (r *TagRepository) Rename(oldName, newName string) (*Tag, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, NewValidationError("Tag is invalid", map[string]string{
+			"Name": "Name is required",
+		})
+	}
+
+	tag, err := r.GetByName(oldName)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, fmt.Errorf("tag with name %s not found", oldName)
+	}
+
+	existing, err := r.GetByName(newName)
+	if err != nil {
+		return nil, err
+	}
+
+	rename := WebhookTagRename{
+		OldName:        tag.Name,
+		OldDisplayName: tag.DisplayName,
+	}
+	renamed := existing
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if existing == nil || existing.ID == tag.ID {
+			tag.DisplayName = newName
+			result := tx.Save(tag)
+			if result.Error != nil {
+				return result.Error
+			}
+			renamed = tag
+		} else {
+			err := tx.Exec("INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id) "+
+				"SELECT bookmark_id, ? FROM bookmark_tags WHERE tag_id = ?", existing.ID, tag.ID).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Exec("DELETE FROM bookmark_tags WHERE tag_id = ?", tag.ID).Error
+			if err != nil {
+				return err
+			}
+			// the name is unique, so the merged tag can't stay soft-deleted
+			err = tx.Unscoped().Delete(tag).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		rename.Name = renamed.Name
+		rename.DisplayName = renamed.DisplayName
+		return enqueueWebhookEvent(tx, WebhookEventTagRenamed, rename)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
+
+// Counts returns all tags in use with their number of bookmarks, ordered
+// by name.
+func (r *TagRepository) Counts() ([]TagCount, error) {
+	var counts []TagCount
+	err := r.countsQuery().
+		Order("tags.name asc").
+		Scan(&counts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// TopTags returns the tags with the most bookmarks, up to limit.
+func (r *TagRepository) TopTags(limit int) ([]TagCount, error) {
+	var counts []TagCount
+	err := r.countsQuery().
+		Order("count desc, tags.name asc").
+		Limit(limit).
+		Scan(&counts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// UsedOnce returns the tags which are only used by a single bookmark.
+func (r *TagRepository) UsedOnce() ([]TagCount, error) {
+	var counts []TagCount
+	err := r.countsQuery().
+		Having("count(bookmarks.id) = 1").
+		Order("tags.name asc").
+		Scan(&counts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// countsQuery counts the bookmarks per tag, ignoring deleted bookmarks.
+func (r *TagRepository) countsQuery() *gorm.DB {
+	return r.db.Model(&Tag{}).
+		Select("tags.name, tags.display_name, count(bookmarks.id) AS count").
+		Joins("JOIN bookmark_tags bt ON bt.tag_id = tags.id").
+		Joins("JOIN bookmarks ON bookmarks.id = bt.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Group("tags.id")
+}
+
 func (r *TagRepository) Search(query string) ([]Tag, error) {
+	match := prefixQuery(query)
+	if match == "" {
+		return []Tag{}, nil
+	}
+
 	var tags []Tag
-	err := r.db.Table("tags_fts").Unscoped().Where("tags_fts MATCH ?", query).Order("rank").Find(&tags).Error
+	err := r.db.Joins("JOIN tags_fts ON tags_fts.rowid = tags.id").
+		Where("tags_fts MATCH ?", match).
+		Order("tags_fts.rank").
+		Find(&tags).
+		Error
 	if err != nil {
 		return nil, err
 	}
+	if len(tags) > 0 {
+		return tags, nil
+	}
+
+	return r.fuzzySearch(query)
+}
+
+// fuzzySearch matches tag names within the tolerated edit distance of query,
+// only names of a length within that distance are loaded as candidates.
+func (r *TagRepository) fuzzySearch(query string) ([]Tag, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	maxDistance := maxEditDistance(query)
+	if maxDistance == 0 {
+		return []Tag{}, nil
+	}
+	length := len([]rune(query))
+
+	var all []Tag
+	err := r.db.
+		Where("length(name) BETWEEN ? AND ?", length-maxDistance, length+maxDistance).
+		Order("name").
+		Limit(maxFuzzyCandidates).
+		Find(&all).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Tag, len(all))
+	candidates := make([]fuzzyCandidate, 0, len(all))
+	for _, tag := range all {
+		byName[tag.Name] = tag
+		candidates = append(candidates, fuzzyCandidate{Term: tag.Name})
+	}
+
+	tags := []Tag{}
+	for _, match := range fuzzyMatches(query, candidates) {
+		tags = append(tags, byName[match.Term])
+	}
 	return tags, nil
 }
@@ -82,16 +82,13 @@ func TestTagRepositorySearch(t *testing.T) {
 	defer cleanup()
 	repo := data.NewTagRepository(db)
 
-	tagNames := []string{"golang", "gomigrate", "toRead"}
+	tagNames := []string{"golang", "gomigrate", "toRead", "Kubernetes"}
 	_, err := repo.Upsert(tagNames)
 	require.NoError(t, err)
 
+	// prefix
 	results, err := repo.Search("go")
 	require.NoError(t, err)
-	require.Len(t, results, 0)
-
-	results, err = repo.Search("go*")
-	require.NoError(t, err)
 	require.Len(t, results, 2)
 	var resultNames []string
 	for _, tag := range results {
@@ -100,8 +97,114 @@ func TestTagRepositorySearch(t *testing.T) {
 	require.Contains(t, resultNames, "golang")
 	require.Contains(t, resultNames, "gomigrate")
 
+	results, err = repo.Search("go*")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
 	results, err = repo.Search("toread")
 	require.NoError(t, err)
 	require.Len(t, results, 1)
 	require.Equal(t, "toRead", results[0].DisplayName)
+	require.Equal(t, "toread", results[0].Name)
+	require.NotZero(t, results[0].ID)
+
+	// fuzzy
+	results, err = repo.Search("kuberntes")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "Kubernetes", results[0].DisplayName)
+
+	results, err = repo.Search("gopher")
+	require.NoError(t, err)
+	require.Len(t, results, 0)
+
+	// empty query
+	results, err = repo.Search(" ")
+	require.NoError(t, err)
+	require.Len(t, results, 0)
+}
+
+func TestTagRepositoryCounts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTagRepository(db)
+	bookmarkRepo := data.NewBookmarkRepository(db)
+
+	for _, tags := range []string{"go, rust", "go, sqlite", "go", "rust", "deleted"} {
+		_, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com", Tags: tags})
+		require.NoError(t, err)
+	}
+	deleted, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com", Tags: "deleted, go"})
+	require.NoError(t, err)
+	err = bookmarkRepo.Delete(deleted.ID)
+	require.NoError(t, err)
+
+	top, err := repo.TopTags(2)
+	require.NoError(t, err)
+	require.Equal(t, []data.TagCount{
+		{Name: "go", DisplayName: "go", Count: 3},
+		{Name: "rust", DisplayName: "rust", Count: 2},
+	}, top)
+
+	once, err := repo.UsedOnce()
+	require.NoError(t, err)
+	require.Equal(t, []data.TagCount{
+		{Name: "deleted", DisplayName: "deleted", Count: 1},
+		{Name: "sqlite", DisplayName: "sqlite", Count: 1},
+	}, once)
+}
+
+func TestTagRepositoryRename(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTagRepository(db)
+	bookmarkRepo := data.NewBookmarkRepository(db)
+
+	first, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com/one", Tags: "golang, databases"})
+	require.NoError(t, err)
+	second, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com/two", Tags: "go"})
+	require.NoError(t, err)
+
+	// rename
+	tag, err := repo.Rename("databases", "Databases")
+	require.NoError(t, err)
+	require.Equal(t, "databases", tag.Name)
+	require.Equal(t, "Databases", tag.DisplayName)
+
+	tag, err = repo.Rename("databases", "sql")
+	require.NoError(t, err)
+	require.Equal(t, "sql", tag.Name)
+	found, err := repo.Search("sql")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	// merge into existing tag
+	tag, err = repo.Rename("golang", "Go")
+	require.NoError(t, err)
+	require.Equal(t, "go", tag.Name)
+
+	golang, err := repo.GetByName("golang")
+	require.NoError(t, err)
+	require.Nil(t, golang)
+
+	for _, id := range []uint{first.ID, second.ID} {
+		bookmark, err := bookmarkRepo.Get(id)
+		require.NoError(t, err)
+		names := []string{}
+		for _, tag := range bookmark.Tags {
+			names = append(names, tag.Name)
+		}
+		require.Contains(t, names, "go")
+		require.NotContains(t, names, "golang")
+	}
+
+	// the merged tag name can be used again
+	_, err = bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.com/three", Tags: "golang"})
+	require.NoError(t, err)
+
+	// errors
+	_, err = repo.Rename("missing", "other")
+	require.EqualError(t, err, "tag with name missing not found")
+	_, err = repo.Rename("go", " ")
+	require.EqualError(t, err, "Tag is invalid")
 }
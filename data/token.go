@@ -0,0 +1,104 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenScope string
+
+const (
+	TokenScopeRead  TokenScope = "read"
+	TokenScopeWrite TokenScope = "write"
+	// TokenScopeCreate only allows creating bookmarks with Micropub, it's
+	// what IndieAuth clients get for the "create" scope.
+	TokenScopeCreate TokenScope = "create"
+)
+
+var TokenScopes = []TokenScope{
+	TokenScopeRead,
+	TokenScopeWrite,
+	TokenScopeCreate,
+}
+
+// Token authenticates API requests, only a hash of its secret is stored.
+type Token struct {
+	gorm.Model
+	Name       string
+	SecretHash string `gorm:"unique"`
+	Scopes     string `gorm:"not null;default:'read write'"`
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	ClientID   string
+}
+
+type TokenCreate struct {
+	Name      string
+	Scopes    []TokenScope
+	ExpiresAt *time.Time
+	ClientID  string
+}
+
+func (t *Token) ScopeList() []TokenScope {
+	scopes := []TokenScope{}
+	for _, scope := range strings.Fields(t.Scopes) {
+		scopes = append(scopes, TokenScope(scope))
+	}
+	return scopes
+}
+
+func (t *Token) HasScope(scope TokenScope) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) IsExpired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+func (req *TokenCreate) IsValid() *ValidationError {
+	isErr := false
+	fields := make(map[string]string)
+
+	if req.Name == "" {
+		isErr = true
+		fields["Name"] = "Name is required"
+	}
+
+	if len(req.Scopes) == 0 {
+		isErr = true
+		fields["Scopes"] = "At least one scope is required"
+	}
+	for _, scope := range req.Scopes {
+		if !isValidTokenScope(scope) {
+			isErr = true
+			fields["Scopes"] = "Scope is invalid"
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		isErr = true
+		fields["ExpiresAt"] = "Expiry must be in the future"
+	}
+
+	if isErr {
+		return NewValidationError("Token is invalid", fields)
+	}
+
+	return nil
+}
+
+func isValidTokenScope(scope TokenScope) bool {
+	for _, valid := range TokenScopes {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
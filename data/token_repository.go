@@ -0,0 +1,119 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db}
+}
+
+// Create stores a new token and returns it together with its secret, which
+// can't be retrieved again afterwards.
+func (r *TokenRepository) Create(req TokenCreate) (*Token, string, error) {
+	validationErr := req.IsValid()
+	if validationErr != nil {
+		return nil, "", validationErr
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes := []string{}
+	for _, scope := range TokenScopes {
+		for _, requested := range req.Scopes {
+			if scope == requested {
+				scopes = append(scopes, string(scope))
+				break
+			}
+		}
+	}
+
+	token := &Token{
+		Name:       req.Name,
+		SecretHash: hashTokenSecret(secret),
+		Scopes:     strings.Join(scopes, " "),
+		ExpiresAt:  req.ExpiresAt,
+		ClientID:   req.ClientID,
+	}
+	result := r.db.Create(token)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+
+	return token, secret, nil
+}
+
+func (r *TokenRepository) GetBySecret(secret string) (*Token, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	var token Token
+	result := r.db.Where("secret_hash = ?", hashTokenSecret(secret)).First(&token)
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *TokenRepository) List() ([]Token, error) {
+	var tokens []Token
+	err := r.db.Order("created_at desc").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Touch records that the token has just been used.
+func (r *TokenRepository) Touch(id uint) error {
+	return r.db.Model(&Token{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", time.Now()).
+		Error
+}
+
+func (r *TokenRepository) Revoke(id uint) error {
+	result := r.db.Delete(&Token{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("token with id %d not found", id)
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashTokenSecret doesn't need a slow password hash, secrets are long random
+// strings and need to be looked up by their hash.
+func hashTokenSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,143 @@
+package data_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestTokenRepositoryCreate(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	token, secret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+	require.Equal(t, "scripts", token.Name)
+	require.NotEqual(t, secret, token.SecretHash)
+	require.Equal(t, data.TokenScopes, token.ScopeList())
+	require.Nil(t, token.ExpiresAt)
+	require.Nil(t, token.LastUsedAt)
+
+	_, otherSecret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	require.NotEqual(t, secret, otherSecret)
+
+	// scopes are stored in a stable order
+	token, _, err = repo.Create(data.TokenCreate{
+		Name:   "read-only",
+		Scopes: []data.TokenScope{data.TokenScopeRead, data.TokenScopeRead},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "read", token.Scopes)
+	require.True(t, token.HasScope(data.TokenScopeRead))
+	require.False(t, token.HasScope(data.TokenScopeWrite))
+
+	// invalid
+	_, _, err = repo.Create(data.TokenCreate{})
+	require.EqualError(t, err, "Token is invalid")
+	require.Equal(t, map[string]string{
+		"Name":   "Name is required",
+		"Scopes": "At least one scope is required",
+	}, err.(*data.ValidationError).Fields)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = repo.Create(data.TokenCreate{
+		Name:      "expired",
+		Scopes:    []data.TokenScope{"admin"},
+		ExpiresAt: &past,
+	})
+	require.EqualError(t, err, "Token is invalid")
+	require.Equal(t, map[string]string{
+		"Scopes":    "Scope is invalid",
+		"ExpiresAt": "Expiry must be in the future",
+	}, err.(*data.ValidationError).Fields)
+}
+
+func TestTokenRepositoryGetBySecret(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	created, secret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	token, err := repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	require.Equal(t, created.ID, token.ID)
+
+	token, err = repo.GetBySecret("invalid")
+	require.NoError(t, err)
+	require.Nil(t, token)
+
+	token, err = repo.GetBySecret("")
+	require.NoError(t, err)
+	require.Nil(t, token)
+}
+
+func TestTokenRepositoryList(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	first, _, err := repo.Create(data.TokenCreate{Name: "first", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	second, _, err := repo.Create(data.TokenCreate{Name: "second", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	tokens, err = repo.List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, second.ID, tokens[0].ID)
+	require.Equal(t, first.ID, tokens[1].ID)
+}
+
+func TestTokenRepositoryTouch(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	_, secret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	token, err := repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.Nil(t, token.LastUsedAt)
+
+	err = repo.Touch(token.ID)
+	require.NoError(t, err)
+
+	token, err = repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.NotNil(t, token.LastUsedAt)
+	require.WithinDuration(t, time.Now(), *token.LastUsedAt, time.Minute)
+}
+
+func TestTokenRepositoryRevoke(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	token, secret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	err = repo.Revoke(token.ID)
+	require.NoError(t, err)
+
+	actual, err := repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+
+	err = repo.Revoke(token.ID)
+	require.EqualError(t, err, fmt.Sprintf("token with id %d not found", token.ID))
+}
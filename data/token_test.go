@@ -0,0 +1,23 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+)
+
+func TestTokenIsExpired(t *testing.T) {
+	token := data.Token{}
+	require.False(t, token.IsExpired())
+
+	future := time.Now().Add(time.Hour)
+	token.ExpiresAt = &future
+	require.False(t, token.IsExpired())
+
+	past := time.Now().Add(-time.Hour)
+	token.ExpiresAt = &past
+	require.True(t, token.IsExpired())
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes generated at a time.
+	RecoveryCodeCount = 10
+
+	// LoginChallengeLifetime is how long the second login step can take after
+	// the password has been checked.
+	LoginChallengeLifetime = 5 * time.Minute
+
+	// MaxLoginChallengeAttempts is the number of wrong codes after which the
+	// password has to be entered again.
+	MaxLoginChallengeAttempts = 3
+
+	// MaxTwoFactorFailures is the number of wrong codes across login attempts
+	// after which no codes are accepted for TwoFactorLockout.
+	MaxTwoFactorFailures = 5
+	TwoFactorLockout     = 15 * time.Minute
+)
+
+// LoginChallenge is created once the password has been checked and a second
+// factor is still required, only a hash of its token is stored.
+type LoginChallenge struct {
+	gorm.Model
+	TokenHash string `gorm:"unique"`
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+func (c *LoginChallenge) IsExpired() bool {
+	return !c.ExpiresAt.After(time.Now())
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns new recovery codes in the form xxxxx-xxxxx
+// and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		secret := make([]byte, 10)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(secret))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which are easily mistyped.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashTokenSecret(code)
+}
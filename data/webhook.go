@@ -0,0 +1,109 @@
+package data
+
+import (
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookEventBookmarkCreated WebhookEvent = "bookmark.created"
+	WebhookEventBookmarkUpdated WebhookEvent = "bookmark.updated"
+	WebhookEventBookmarkDeleted WebhookEvent = "bookmark.deleted"
+	WebhookEventTagRenamed      WebhookEvent = "tag.renamed"
+)
+
+type WebhookDeliveryState string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryState = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryState = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryState = "failed"
+)
+
+// Webhook receives a signed POST request for every bookmark and tag event,
+// the secret is kept in plain text as it is needed for signing.
+type Webhook struct {
+	gorm.Model
+	URL    string `gorm:"not null"`
+	Secret string
+}
+
+type WebhookForm struct {
+	URL string
+}
+
+// WebhookDelivery is queued in the same transaction as the change it
+// reports, so that no event is lost when sending fails or the server stops.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint `gorm:"index"`
+	Webhook       Webhook
+	Event         WebhookEvent
+	Payload       string
+	State         WebhookDeliveryState `gorm:"index;default:'pending'"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastStatus    int
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+type WebhookPayload struct {
+	Event     WebhookEvent `json:"event"`
+	Timestamp time.Time    `json:"timestamp"`
+	Data      interface{}  `json:"data"`
+}
+
+type WebhookBookmark struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Public      bool      `json:"public"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type WebhookTagRename struct {
+	OldName        string `json:"oldName"`
+	OldDisplayName string `json:"oldDisplayName"`
+	Name           string `json:"name"`
+	DisplayName    string `json:"displayName"`
+}
+
+func NewWebhookBookmark(bookmark *Bookmark) WebhookBookmark {
+	tags := []string{}
+	for _, tag := range bookmark.Tags {
+		tags = append(tags, tag.DisplayName)
+	}
+	return WebhookBookmark{
+		ID:          bookmark.ID,
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Public:      bookmark.IsPublic(),
+		Tags:        tags,
+		CreatedAt:   bookmark.CreatedAt,
+		UpdatedAt:   bookmark.UpdatedAt,
+	}
+}
+
+func (req *WebhookForm) IsValid() *ValidationError {
+	parsedURL, err := url.Parse(req.URL)
+	if req.URL == "" {
+		return NewValidationError("Webhook is invalid", map[string]string{
+			"URL": "URL is required",
+		})
+	}
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return NewValidationError("Webhook is invalid", map[string]string{
+			"URL": "URL must be an http or https URL",
+		})
+	}
+	return nil
+}
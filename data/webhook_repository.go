@@ -0,0 +1,139 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db}
+}
+
+func (r *WebhookRepository) Create(form WebhookForm) (*Webhook, error) {
+	validationErr := form.IsValid()
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &Webhook{
+		URL:    form.URL,
+		Secret: secret,
+	}
+	result := r.db.Create(webhook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) List() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := r.db.Order("created_at asc").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Delete removes the webhook, its pending deliveries won't be sent anymore.
+func (r *WebhookRepository) Delete(id uint) error {
+	result := r.db.Delete(&Webhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("webhook with id %d not found", id)
+	}
+	return nil
+}
+
+// Deliveries returns the most recent deliveries for the delivery log.
+func (r *WebhookRepository) Deliveries(limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := r.db.
+		Preload("Webhook", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Order("created_at desc, id desc").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// DueDeliveries returns pending deliveries of existing webhooks which should
+// be attempted at the given time.
+func (r *WebhookRepository) DueDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := r.db.
+		Preload("Webhook").
+		Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id AND webhooks.deleted_at IS NULL").
+		Where("webhook_deliveries.state = ?", WebhookDeliveryPending).
+		Where("webhook_deliveries.next_attempt_at <= ?", now).
+		Order("webhook_deliveries.next_attempt_at asc, webhook_deliveries.id asc").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveAttempt stores the outcome of a delivery attempt.
+func (r *WebhookRepository) SaveAttempt(delivery *WebhookDelivery) error {
+	return r.db.Model(delivery).
+		Select("State", "Attempts", "NextAttemptAt", "LastStatus", "LastError", "DeliveredAt").
+		Updates(delivery).
+		Error
+}
+
+// enqueueWebhookEvent queues a delivery of the event for every webhook, it
+// is called within the transaction of the change it reports.
+func enqueueWebhookEvent(tx *gorm.DB, event WebhookEvent, data interface{}) error {
+	var webhooks []Webhook
+	err := tx.Find(&webhooks).Error
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		Timestamp: now,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			State:         WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	return tx.Omit("Webhook").Create(&deliveries).Error
+}
@@ -0,0 +1,175 @@
+package data_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestWebhookRepositoryCreate(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	webhook, err := repo.Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/hooks", webhook.URL)
+	require.NotEmpty(t, webhook.Secret)
+
+	_, err = repo.Create(data.WebhookForm{})
+	require.EqualError(t, err, "Webhook is invalid")
+	require.Equal(t, "URL is required", err.(*data.ValidationError).Fields["URL"])
+
+	_, err = repo.Create(data.WebhookForm{URL: "ftp://example.com"})
+	require.EqualError(t, err, "Webhook is invalid")
+	require.Equal(t, "URL must be an http or https URL", err.(*data.ValidationError).Fields["URL"])
+
+	webhooks, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+}
+
+func TestWebhookRepositoryDelete(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	webhook, err := repo.Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+
+	err = repo.Delete(webhook.ID)
+	require.NoError(t, err)
+	err = repo.Delete(webhook.ID)
+	require.EqualError(t, err, "webhook with id 1 not found")
+
+	// deliveries of deleted webhooks are not due anymore but stay in the log
+	due, err := repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Empty(t, due)
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "https://example.com/hooks", deliveries[0].Webhook.URL)
+}
+
+func TestWebhookEvents(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+	bookmarkRepo := data.NewBookmarkRepository(db)
+
+	// no deliveries without webhooks
+	_, err := bookmarkRepo.Create(data.BookmarkForm{URL: "https://example.org"})
+	require.NoError(t, err)
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	_, err = repo.Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+
+	bookmark, err := bookmarkRepo.Create(data.BookmarkForm{
+		URL:    "https://example.com",
+		Title:  "Example",
+		Public: true,
+		Tags:   "examples",
+	})
+	require.NoError(t, err)
+	err = bookmarkRepo.Update(bookmark.ID, data.BookmarkForm{
+		URL:   "https://example.com",
+		Title: "Updated",
+		Tags:  "examples, docs",
+	})
+	require.NoError(t, err)
+	_, err = data.NewTagRepository(db).Rename("docs", "Documentation")
+	require.NoError(t, err)
+	err = bookmarkRepo.Delete(bookmark.ID)
+	require.NoError(t, err)
+
+	deliveries, err = repo.DueDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 4)
+
+	events := []data.WebhookEvent{}
+	payloads := []map[string]interface{}{}
+	for _, delivery := range deliveries {
+		require.Equal(t, data.WebhookDeliveryPending, delivery.State)
+		require.Equal(t, "https://example.com/hooks", delivery.Webhook.URL)
+		events = append(events, delivery.Event)
+
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &payload))
+		require.Equal(t, string(delivery.Event), payload["event"])
+		payloads = append(payloads, payload["data"].(map[string]interface{}))
+	}
+	require.Equal(t, []data.WebhookEvent{
+		data.WebhookEventBookmarkCreated,
+		data.WebhookEventBookmarkUpdated,
+		data.WebhookEventTagRenamed,
+		data.WebhookEventBookmarkDeleted,
+	}, events)
+
+	require.Equal(t, "Example", payloads[0]["title"])
+	require.Equal(t, true, payloads[0]["public"])
+	require.Equal(t, []interface{}{"examples"}, payloads[0]["tags"])
+	require.Equal(t, "Updated", payloads[1]["title"])
+	require.Equal(t, []interface{}{"examples", "docs"}, payloads[1]["tags"])
+	require.Equal(t, "docs", payloads[2]["oldName"])
+	require.Equal(t, "documentation", payloads[2]["name"])
+	require.Equal(t, "Documentation", payloads[2]["displayName"])
+	require.Equal(t, float64(bookmark.ID), payloads[3]["id"])
+
+	// failed changes don't enqueue events
+	err = bookmarkRepo.Delete(bookmark.ID)
+	require.Error(t, err)
+	deliveries, err = repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 4)
+}
+
+func TestWebhookRepositorySaveAttempt(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	_, err := repo.Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	deliveries, err := repo.DueDeliveries(now, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	delivery := deliveries[0]
+	delivery.Attempts = 1
+	delivery.LastStatus = 500
+	delivery.LastError = "unexpected status code 500"
+	delivery.NextAttemptAt = now.Add(time.Minute)
+	require.NoError(t, repo.SaveAttempt(&delivery))
+
+	deliveries, err = repo.DueDeliveries(now, 10)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+	deliveries, err = repo.DueDeliveries(now.Add(2*time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, 1, deliveries[0].Attempts)
+	require.Equal(t, 500, deliveries[0].LastStatus)
+
+	delivery = deliveries[0]
+	delivery.State = data.WebhookDeliveryDelivered
+	delivery.DeliveredAt = &now
+	require.NoError(t, repo.SaveAttempt(&delivery))
+	deliveries, err = repo.DueDeliveries(now.Add(2*time.Minute), 10)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/activitypub"
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+const (
+	outboxPageSize  = 20
+	maxActivitySize = 1024 * 1024
+)
+
+type webFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+type webFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases"`
+	Links   []webFingerLink `json:"links"`
+}
+
+func WebFingerHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	baseURL := requestBaseURL(sc)
+	actorID := activitypub.ActorID(baseURL)
+	subject := fmt.Sprintf("acct:%s@%s", activitypub.Username, sc.Request().Host)
+
+	resource := sc.QueryParam("resource")
+	if resource != subject && resource != actorID {
+		return sc.JSONNotFound()
+	}
+
+	body, err := json.Marshal(webFinger{
+		Subject: subject,
+		Aliases: []string{actorID},
+		Links: []webFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorID},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: baseURL + "/"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return sc.Blob(http.StatusOK, "application/jrd+json; charset=utf-8", body)
+}
+
+func ActorHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	key, err := data.NewActivityPubRepository(sc.DB).ActorKey()
+	if err != nil {
+		return err
+	}
+	return renderActivityJSON(sc, http.StatusOK, activitypub.NewActor(requestBaseURL(sc), key.PublicKey))
+}
+
+// OutboxHandler publishes the public bookmarks as Create activities, newest
+// first and paginated like the bookmark lists.
+func OutboxHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	baseURL := requestBaseURL(sc)
+	outboxID := baseURL + "/outbox"
+	repo := data.NewBookmarkRepository(sc.DB)
+
+	if sc.QueryParam("page") == "" {
+		counts, err := repo.PrivacyCounts()
+		if err != nil {
+			return err
+		}
+		return renderActivityJSON(sc, http.StatusOK, activitypub.OrderedCollection{
+			Context:    "https://www.w3.org/ns/activitystreams",
+			ID:         outboxID,
+			Type:       "OrderedCollection",
+			TotalItems: counts[data.BookmarkPrivacyPublic],
+			First:      outboxID + "?page=true",
+		})
+	}
+
+	result, err := repo.List(data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyPublic,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: outboxPageSize,
+		Sort:    data.BookmarkSortNewest,
+
+		PaginationPathPrefix: outboxID + "?page=true&",
+	})
+	if err != nil {
+		return err
+	}
+
+	page := activitypub.OrderedCollectionPage{
+		Context:      "https://www.w3.org/ns/activitystreams",
+		ID:           baseURL + sc.Request().URL.RequestURI(),
+		Type:         "OrderedCollectionPage",
+		PartOf:       outboxID,
+		Next:         result.NextURL,
+		Prev:         result.PrevURL,
+		OrderedItems: []activitypub.Activity{},
+	}
+	for i := range result.Items {
+		page.OrderedItems = append(page.OrderedItems, activitypub.NewCreate(baseURL, &result.Items[i]))
+	}
+	return renderActivityJSON(sc, http.StatusOK, page)
+}
+
+// FollowersHandler only publishes the number of followers.
+func FollowersHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	followers, err := data.NewActivityPubRepository(sc.DB).Followers()
+	if err != nil {
+		return err
+	}
+	return renderActivityJSON(sc, http.StatusOK, activitypub.OrderedCollection{
+		Context:    "https://www.w3.org/ns/activitystreams",
+		ID:         requestBaseURL(sc) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: int64(len(followers)),
+	})
+}
+
+// InboxHandler accepts signed Follow and Undo Follow activities, everything
+// else is acknowledged and ignored.
+func InboxHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	body, err := io.ReadAll(io.LimitReader(sc.Request().Body, maxActivitySize))
+	if err != nil {
+		return sc.JSONError(http.StatusBadRequest, "Failed to read activity")
+	}
+	signer, err := activitypub.VerifyRequest(sc.Request(), body, activitypub.FetchKey)
+	if err != nil {
+		return sc.JSONError(http.StatusUnauthorized, "Signature is invalid")
+	}
+
+	var activity activitypub.IncomingActivity
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		return sc.JSONError(http.StatusBadRequest, "Activity is invalid")
+	}
+	if activity.Actor != signer {
+		return sc.JSONError(http.StatusUnauthorized, "Activity isn't signed by its actor")
+	}
+
+	repo := data.NewActivityPubRepository(sc.DB)
+	switch activity.Type {
+	case "Follow":
+		actorID := activitypub.ActorID(requestBaseURL(sc))
+		if activity.ObjectID() != actorID {
+			return sc.JSONError(http.StatusUnprocessableEntity, "Only this actor can be followed")
+		}
+		actor, err := activitypub.FetchActor(activity.Actor)
+		if err != nil {
+			return sc.JSONError(http.StatusBadGateway, "Failed to fetch actor")
+		}
+		sharedInbox := ""
+		if actor.Endpoints != nil {
+			sharedInbox = actor.Endpoints.SharedInbox
+		}
+		_, err = repo.Follow(data.FollowerCreate{
+			ActorID:      actor.ID,
+			Inbox:        actor.Inbox,
+			SharedInbox:  sharedInbox,
+			FollowID:     activity.ID,
+			LocalActorID: actorID,
+		})
+		if err != nil {
+			return err
+		}
+	case "Undo":
+		object := activity.EmbeddedObject()
+		if object != nil && object.Type == "Follow" {
+			err = repo.Unfollow(activity.Actor)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return sc.NoContent(http.StatusAccepted)
+}
+
+func renderActivityJSON(sc *middleware.SubmarineContext, status int, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return sc.Blob(status, activitypub.ContentType+"; charset=utf-8", body)
+}
+
+// wantsActivityJSON reports whether an ActivityPub client asked for the
+// JSON representation of a page.
+func wantsActivityJSON(sc *middleware.SubmarineContext) bool {
+	accept := sc.Request().Header.Get(echo.HeaderAccept)
+	return strings.Contains(accept, activitypub.ContentType) || strings.Contains(accept, "application/ld+json")
+}
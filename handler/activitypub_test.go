@@ -0,0 +1,284 @@
+package handler_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/activitypub"
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+// remoteActor is a stand-in for an actor on another fediverse server.
+type remoteActor struct {
+	ID       string
+	key      *rsa.PrivateKey
+	server   *httptest.Server
+	received []map[string]interface{}
+}
+
+func newRemoteActor(t *testing.T) *remoteActor {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	remote := &remoteActor{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", activitypub.ContentType)
+		_ = json.NewEncoder(w).Encode(activitypub.Actor{
+			ID:                remote.ID,
+			Type:              "Person",
+			PreferredUsername: "alice",
+			Inbox:             remote.ID + "/inbox",
+			PublicKey: activitypub.PublicKey{
+				ID:           remote.ID + "#main-key",
+				Owner:        remote.ID,
+				PublicKeyPem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+			},
+		})
+	})
+	mux.HandleFunc("/users/alice/inbox", func(w http.ResponseWriter, r *http.Request) {
+		var activity map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&activity)
+		remote.received = append(remote.received, activity)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote.server = httptest.NewServer(mux)
+	remote.ID = remote.server.URL + "/users/alice"
+	return remote
+}
+
+// send posts a signed activity to the inbox.
+func (r *remoteActor) send(t *testing.T, e http.Handler, activity interface{}) *httptest.ResponseRecorder {
+	body, err := json.Marshal(activity)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/inbox", bytes.NewReader(body))
+	req.Header.Set("Content-Type", activitypub.ContentType)
+	require.NoError(t, activitypub.SignRequest(req, r.ID+"#main-key", r.key, body))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func activityRequest(e http.Handler, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Accept", activitypub.ContentType)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebFingerHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	rec := activityRequest(e, "/.well-known/webfinger?resource=acct:submarine@example.com")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/jrd+json; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), `"subject":"acct:submarine@example.com"`)
+	require.Contains(t, rec.Body.String(), `"href":"http://example.com/actor"`)
+
+	rec = activityRequest(e, "/.well-known/webfinger?resource=acct:someone@example.com")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestActorHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	rec := activityRequest(e, "/actor")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/activity+json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var actor activitypub.Actor
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actor))
+	require.Equal(t, "http://example.com/actor", actor.ID)
+	require.Equal(t, "submarine", actor.PreferredUsername)
+	require.Equal(t, "http://example.com/inbox", actor.Inbox)
+	require.Equal(t, "http://example.com/outbox", actor.Outbox)
+	require.Equal(t, "http://example.com/actor#main-key", actor.PublicKey.ID)
+	_, err := activitypub.ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	require.NoError(t, err)
+}
+
+func TestOutboxHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+	for i := 0; i < 25; i++ {
+		_, err := repo.Create(data.BookmarkForm{
+			URL:    fmt.Sprintf("https://example-%d.com", i),
+			Public: i%5 != 0,
+		})
+		require.NoError(t, err)
+	}
+	e := router.New(db)
+
+	rec := activityRequest(e, "/outbox")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var collection activitypub.OrderedCollection
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &collection))
+	require.Equal(t, int64(20), collection.TotalItems)
+	require.Equal(t, "http://example.com/outbox?page=true", collection.First)
+
+	rec = activityRequest(e, "/outbox?page=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page struct {
+		Next         string
+		OrderedItems []struct {
+			Type   string
+			Object activitypub.Note
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.OrderedItems, 20)
+	require.Equal(t, "Create", page.OrderedItems[0].Type)
+	require.Equal(t, "https://example-24.com", page.OrderedItems[0].Object.URL)
+	require.True(t, strings.HasPrefix(page.Next, "http://example.com/outbox?page=true&cursor="))
+
+	rec = activityRequest(e, strings.TrimPrefix(page.Next, "http://example.com"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	page.Next = ""
+	page.OrderedItems = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Empty(t, page.OrderedItems)
+	require.Empty(t, page.Next)
+}
+
+func TestBookmarkShowHandlerActivityJSON(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+	public, err := repo.Create(data.BookmarkForm{URL: "https://example.com", Title: "Example", Public: true})
+	require.NoError(t, err)
+	private, err := repo.Create(data.BookmarkForm{URL: "https://example.com/private"})
+	require.NoError(t, err)
+	e := router.New(db)
+
+	rec := activityRequest(e, fmt.Sprintf("/bookmarks/%d", public.ID))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var note activitypub.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	require.Equal(t, "Note", note.Type)
+	require.Equal(t, fmt.Sprintf("http://example.com/bookmarks/%d", public.ID), note.ID)
+	require.Equal(t, "http://example.com/actor", note.AttributedTo)
+
+	rec = activityRequest(e, fmt.Sprintf("/bookmarks/%d", private.ID))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestInboxHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewActivityPubRepository(db)
+	e := router.New(db)
+
+	remote := newRemoteActor(t)
+	defer remote.server.Close()
+
+	follow := map[string]interface{}{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id":       remote.ID + "/follows/1",
+		"type":     "Follow",
+		"actor":    remote.ID,
+		"object":   "http://example.com/actor",
+	}
+
+	// unsigned
+	req := httptest.NewRequest(http.MethodPost, "/inbox", bytes.NewReader([]byte(`{}`)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// signed by someone else than the actor
+	forged := map[string]interface{}{}
+	for key, value := range follow {
+		forged[key] = value
+	}
+	forged["actor"] = "https://social.example/users/mallory"
+	rec = remote.send(t, e, forged)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// follow
+	rec = remote.send(t, e, follow)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	followers, err := repo.Followers()
+	require.NoError(t, err)
+	require.Len(t, followers, 1)
+	require.Equal(t, remote.ID, followers[0].ActorID)
+	require.Equal(t, remote.ID+"/inbox", followers[0].Inbox)
+	require.Equal(t, "http://example.com/actor", followers[0].LocalActorID)
+
+	rec = activityRequest(e, "/followers")
+	require.Contains(t, rec.Body.String(), `"totalItems":1`)
+
+	// new public bookmarks are delivered after the Accept
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com", Public: true})
+	require.NoError(t, err)
+	require.NoError(t, activitypub.NewWorker(db).DeliverDue())
+	require.Len(t, remote.received, 2)
+	require.Equal(t, "Accept", remote.received[0]["type"])
+	require.Equal(t, "Create", remote.received[1]["type"])
+	require.Equal(t, "http://example.com/actor", remote.received[1]["actor"])
+
+	// undo
+	rec = remote.send(t, e, map[string]interface{}{
+		"id":     remote.ID + "/follows/1/undo",
+		"type":   "Undo",
+		"actor":  remote.ID,
+		"object": follow,
+	})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	followers, err = repo.Followers()
+	require.NoError(t, err)
+	require.Empty(t, followers)
+}
+
+func TestInboxHandlerIgnoresOtherActivities(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	remote := newRemoteActor(t)
+	defer remote.server.Close()
+
+	rec := remote.send(t, e, map[string]interface{}{
+		"id":     remote.ID + "/likes/1",
+		"type":   "Like",
+		"actor":  remote.ID,
+		"object": "http://example.com/bookmarks/1",
+	})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// following someone else
+	rec = remote.send(t, e, map[string]interface{}{
+		"id":     remote.ID + "/follows/1",
+		"type":   "Follow",
+		"actor":  remote.ID,
+		"object": "http://example.com/users/someone",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	followers, err := data.NewActivityPubRepository(db).Followers()
+	require.NoError(t, err)
+	require.Empty(t, followers)
+}
@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+type apiBookmark struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Public      bool      `json:"public"`
+	Tags        []string  `json:"tags"`
+	Visits      uint      `json:"visits"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type apiBookmarkForm struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Public      bool     `json:"public,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type apiBookmarkList struct {
+	Items []apiBookmark `json:"items"`
+	Prev  string        `json:"prev,omitempty"`
+	Next  string        `json:"next,omitempty"`
+}
+
+type apiBookmarkSearchResult struct {
+	apiBookmarkList
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+type apiTag struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type apiTagCount struct {
+	apiTag
+	Count int64 `json:"count"`
+}
+
+type apiValidationError struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+func newAPIBookmark(bookmark *data.Bookmark) apiBookmark {
+	tags := []string{}
+	for _, tag := range bookmark.Tags {
+		tags = append(tags, tag.DisplayName)
+	}
+	return apiBookmark{
+		ID:          bookmark.ID,
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Public:      bookmark.IsPublic(),
+		Tags:        tags,
+		Visits:      bookmark.Visits,
+		CreatedAt:   bookmark.CreatedAt,
+		UpdatedAt:   bookmark.UpdatedAt,
+	}
+}
+
+func newAPIBookmarkList(bookmarks []data.Bookmark) apiBookmarkList {
+	list := apiBookmarkList{Items: []apiBookmark{}}
+	for i := range bookmarks {
+		list.Items = append(list.Items, newAPIBookmark(&bookmarks[i]))
+	}
+	return list
+}
+
+func APIBookmarksListHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	req := data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyQueryAll,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: data.DefaultPerPage,
+		Sort:    data.BookmarkSortNewest,
+		Domain:  sc.QueryParam("domain"),
+	}
+
+	params := url.Values{}
+	perPage, err := strconv.Atoi(sc.QueryParam("per_page"))
+	if err == nil && perPage > 0 {
+		req.PerPage = data.ClampPerPage(perPage)
+		params.Set("per_page", strconv.Itoa(req.PerPage))
+	}
+	sort, ok := data.ParseBookmarkSort(sc.QueryParam("sort"))
+	if ok && sort != data.BookmarkSortRelevance {
+		req.Sort = sort
+		params.Set("sort", string(sort))
+	}
+	if req.Domain != "" {
+		params.Set("domain", req.Domain)
+	}
+	switch privacy := data.BookmarkPrivacy(sc.QueryParam("privacy")); privacy {
+	case data.BookmarkPrivacyPublic, data.BookmarkPrivacyPrivate:
+		req.Privacy = privacy
+		params.Set("privacy", string(privacy))
+	}
+	if sc.QueryParam("untagged") == "true" {
+		req.Untagged = true
+		params.Set("untagged", "true")
+	}
+	if name := sc.QueryParam("tag"); name != "" {
+		tag, err := data.NewTagRepository(sc.DB).GetByName(name)
+		if err != nil {
+			return sc.JSONError(http.StatusInternalServerError, "Failed to fetch tag.")
+		}
+		if tag == nil {
+			return sc.JSON(http.StatusOK, apiBookmarkList{Items: []apiBookmark{}})
+		}
+		req.TagID = tag.ID
+		params.Set("tag", tag.Name)
+	}
+
+	req.PaginationPathPrefix = "/api/v1/bookmarks?"
+	if len(params) > 0 {
+		req.PaginationPathPrefix += params.Encode() + "&"
+	}
+
+	result, err := data.NewBookmarkRepository(sc.DB).List(req)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch bookmarks.")
+	}
+
+	list := newAPIBookmarkList(result.Items)
+	if result.HasPrev {
+		list.Prev = result.PrevURL
+	}
+	if result.HasNext {
+		list.Next = result.NextURL
+	}
+
+	return sc.JSON(http.StatusOK, list)
+}
+
+// APIBookmarksSearchHandler searches bookmarks, sorted by relevance unless
+// asked otherwise. Like the search page it retries with a spelling
+// suggestion when nothing matches.
+func APIBookmarksSearchHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	query := strings.TrimSpace(sc.QueryParam("q"))
+	if query == "" {
+		return sc.JSON(http.StatusOK, apiBookmarkSearchResult{
+			apiBookmarkList: apiBookmarkList{Items: []apiBookmark{}},
+		})
+	}
+
+	req := data.BookmarkSearchRequest{
+		Query:   query,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: data.DefaultPerPage,
+		Sort:    data.BookmarkSortRelevance,
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	perPage, err := strconv.Atoi(sc.QueryParam("per_page"))
+	if err == nil && perPage > 0 {
+		req.PerPage = data.ClampPerPage(perPage)
+		params.Set("per_page", strconv.Itoa(req.PerPage))
+	}
+	sort, ok := data.ParseBookmarkSort(sc.QueryParam("sort"))
+	if ok {
+		req.Sort = sort
+		params.Set("sort", string(sort))
+	}
+	req.PaginationPathPrefix = "/api/v1/bookmarks/search?" + params.Encode() + "&"
+
+	result, err := data.NewBookmarkRepository(sc.DB).Search(req)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to search bookmarks.")
+	}
+
+	list := apiBookmarkSearchResult{
+		apiBookmarkList: newAPIBookmarkList(result.Items),
+		Suggestion:      result.Suggestion,
+	}
+	if result.HasPrev {
+		list.Prev = result.PrevURL
+	}
+	if result.HasNext {
+		list.Next = result.NextURL
+	}
+
+	return sc.JSON(http.StatusOK, list)
+}
+
+func APIBookmarkShowHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	bookmark, err := getAPIBookmark(sc)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch bookmark.")
+	}
+	if bookmark == nil {
+		return sc.JSONNotFound()
+	}
+
+	return sc.JSON(http.StatusOK, newAPIBookmark(bookmark))
+}
+
+func APIBookmarksCreateHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	form, validationErr := parseAndValidateAPIForm(sc)
+	if validationErr != nil {
+		return sc.JSON(http.StatusUnprocessableEntity, apiValidationError{
+			Error:  validationErr.Error(),
+			Fields: validationErr.Fields,
+		})
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	bookmark, err := repo.Create(*form)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to create bookmark.")
+	}
+
+	return sc.JSON(http.StatusCreated, newAPIBookmark(bookmark))
+}
+
+func APIBookmarkUpdateHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	bookmark, err := getAPIBookmark(sc)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch bookmark.")
+	}
+	if bookmark == nil {
+		return sc.JSONNotFound()
+	}
+
+	form, validationErr := parseAndValidateAPIForm(sc)
+	if validationErr != nil {
+		return sc.JSON(http.StatusUnprocessableEntity, apiValidationError{
+			Error:  validationErr.Error(),
+			Fields: validationErr.Fields,
+		})
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	err = repo.Update(bookmark.ID, *form)
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to update bookmark.")
+	}
+	bookmark, err = repo.Get(bookmark.ID)
+	if err != nil || bookmark == nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch bookmark.")
+	}
+
+	return sc.JSON(http.StatusOK, newAPIBookmark(bookmark))
+}
+
+func APIBookmarkDeleteHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.JSONNotFound()
+	}
+	err = data.NewBookmarkRepository(sc.DB).Delete(uint(id))
+	if err != nil {
+		return sc.JSONNotFound()
+	}
+
+	return sc.NoContent(http.StatusNoContent)
+}
+
+func APITagsListHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	counts, err := data.NewTagRepository(sc.DB).Counts()
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch tags.")
+	}
+
+	tags := []apiTagCount{}
+	for _, count := range counts {
+		tags = append(tags, apiTagCount{
+			apiTag: apiTag{Name: count.Name, DisplayName: count.DisplayName},
+			Count:  count.Count,
+		})
+	}
+
+	return sc.JSON(http.StatusOK, tags)
+}
+
+func APITagsSearchHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	results, err := data.NewTagRepository(sc.DB).Search(sc.QueryParam("q"))
+	if err != nil {
+		return sc.JSONError(http.StatusInternalServerError, "Failed to fetch tags.")
+	}
+
+	tags := []apiTag{}
+	for _, tag := range results {
+		tags = append(tags, apiTag{Name: tag.Name, DisplayName: tag.DisplayName})
+	}
+
+	return sc.JSON(http.StatusOK, tags)
+}
+
+func getAPIBookmark(sc *middleware.SubmarineContext) (*data.Bookmark, error) {
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return nil, nil
+	}
+	return data.NewBookmarkRepository(sc.DB).Get(uint(id))
+}
+
+// parseAndValidateAPIForm decodes the JSON request body, a body that isn't a
+// JSON object or tags containing commas are reported like any other
+// validation error.
+func parseAndValidateAPIForm(sc *middleware.SubmarineContext) (*data.BookmarkForm, *data.ValidationError) {
+	var body apiBookmarkForm
+	err := json.NewDecoder(sc.Request().Body).Decode(&body)
+	if err != nil {
+		return nil, data.NewValidationError("Bookmark is invalid", map[string]string{
+			"body": "Request body must be a JSON object",
+		})
+	}
+
+	form := &data.BookmarkForm{
+		URL:         body.URL,
+		Title:       body.Title,
+		Description: body.Description,
+		Public:      body.Public,
+		Tags:        strings.Join(body.Tags, ","),
+	}
+	validationErr := form.IsValid()
+
+	// tags are stored comma separated, a comma would split a tag in two
+	for _, tag := range body.Tags {
+		if strings.Contains(tag, ",") {
+			if validationErr == nil {
+				validationErr = data.NewValidationError("Bookmark is invalid", map[string]string{})
+			}
+			validationErr.Fields["Tags"] = "Tags can't contain commas"
+			break
+		}
+	}
+	return form, validationErr
+}
@@ -0,0 +1,303 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func apiRequest(e http.Handler, secret, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	// missing token
+	rec := apiRequest(e, "", http.MethodGet, "/api/v1/bookmarks", "")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.JSONEq(t, `{"error":"Unauthorized"}`, rec.Body.String())
+
+	// invalid token
+	rec = apiRequest(e, "invalid", http.MethodGet, "/api/v1/bookmarks", "")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// session cookies aren't accepted
+	session, err := data.NewSessionRepository(db).Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bookmarks", nil)
+	req.AddCookie(&http.Cookie{Name: "SubmarineSessionToken", Value: session.Token})
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// read-only token
+	_, readSecret, err := data.NewTokenRepository(db).Create(data.TokenCreate{
+		Name:   "read-only",
+		Scopes: []data.TokenScope{data.TokenScopeRead},
+	})
+	require.NoError(t, err)
+	rec = apiRequest(e, readSecret, http.MethodGet, "/api/v1/bookmarks", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = apiRequest(e, readSecret, http.MethodPost, "/api/v1/bookmarks", `{"url": "https://example.com"}`)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// valid token, without CSRF token
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	rec = apiRequest(e, secret, http.MethodPost, "/api/v1/bookmarks", `{"url": "https://example.com"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestAPIBookmarks(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	// create
+	rec := apiRequest(e, secret, http.MethodPost, "/api/v1/bookmarks", `{
+		"url": "https://example.com",
+		"title": "Example",
+		"description": "An example",
+		"public": true,
+		"tags": ["Go", "examples"]
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &created)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", created["url"])
+	require.Equal(t, "Example", created["title"])
+	require.Equal(t, "An example", created["description"])
+	require.Equal(t, true, created["public"])
+	require.Equal(t, []interface{}{"Go", "examples"}, created["tags"])
+	id := int(created["id"].(float64))
+
+	// create with validation errors
+	rec = apiRequest(e, secret, http.MethodPost, "/api/v1/bookmarks", `{"url": "invalid"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.JSONEq(t, `{"error":"Bookmark is invalid","fields":{"URL":"URL format is invalid"}}`, rec.Body.String())
+
+	rec = apiRequest(e, secret, http.MethodPost, "/api/v1/bookmarks", `not json`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = apiRequest(e, secret, http.MethodPost, "/api/v1/bookmarks", `{"url": "https://example.com", "tags": ["a,b"]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.JSONEq(t, `{"error":"Bookmark is invalid","fields":{"Tags":"Tags can't contain commas"}}`, rec.Body.String())
+
+	// get
+	rec = apiRequest(e, secret, http.MethodGet, fmt.Sprintf("/api/v1/bookmarks/%d", id), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"title":"Example"`)
+
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks/42", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// update
+	rec = apiRequest(e, secret, http.MethodPut, fmt.Sprintf("/api/v1/bookmarks/%d", id), `{
+		"url": "https://example.org",
+		"title": "Updated",
+		"tags": ["go"]
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var updated map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &updated)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.org", updated["url"])
+	require.Equal(t, "Updated", updated["title"])
+	require.Equal(t, false, updated["public"])
+	require.Equal(t, []interface{}{"Go"}, updated["tags"])
+
+	rec = apiRequest(e, secret, http.MethodPut, "/api/v1/bookmarks/42", `{"url": "https://example.org"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiRequest(e, secret, http.MethodPut, fmt.Sprintf("/api/v1/bookmarks/%d", id), `{"url": ""}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.JSONEq(t, `{"error":"Bookmark is invalid","fields":{"URL":"URL is required"}}`, rec.Body.String())
+
+	// delete
+	rec = apiRequest(e, secret, http.MethodDelete, fmt.Sprintf("/api/v1/bookmarks/%d", id), "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = apiRequest(e, secret, http.MethodDelete, fmt.Sprintf("/api/v1/bookmarks/%d", id), "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIBookmarksList(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.Create(data.BookmarkForm{
+			URL:    fmt.Sprintf("https://example-%d.com", i),
+			Title:  fmt.Sprintf("Bookmark %d", i),
+			Public: i%2 == 0,
+			Tags:   "articles",
+		})
+		require.NoError(t, err)
+	}
+	_, err = repo.Create(data.BookmarkForm{URL: "https://other.org", Title: "Untagged"})
+	require.NoError(t, err)
+
+	type list struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+		Prev string `json:"prev"`
+		Next string `json:"next"`
+	}
+
+	// includes private bookmarks and paginates
+	rec := apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks?per_page=3", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page list
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 3)
+	require.Equal(t, "Untagged", page.Items[0].Title)
+	require.Equal(t, "Bookmark 1", page.Items[2].Title)
+	require.Empty(t, page.Prev)
+	require.True(t, strings.HasPrefix(page.Next, "/api/v1/bookmarks?per_page=3&cursor="))
+
+	rec = apiRequest(e, secret, http.MethodGet, page.Next, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = list{}
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "Bookmark 0", page.Items[0].Title)
+	require.NotEmpty(t, page.Prev)
+	require.Empty(t, page.Next)
+
+	// filters by tag
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks?tag=articles&sort=title", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = list{}
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 3)
+	require.Equal(t, "Bookmark 0", page.Items[0].Title)
+
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks?tag=missing", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+}
+
+func TestAPITags(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	for _, tags := range []string{"Go, databases", "Go"} {
+		_, err := repo.Create(data.BookmarkForm{URL: "https://example.com", Tags: tags})
+		require.NoError(t, err)
+	}
+
+	rec := apiRequest(e, secret, http.MethodGet, "/api/v1/tags", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[
+		{"name": "databases", "displayName": "databases", "count": 1},
+		{"name": "go", "displayName": "Go", "count": 2}
+	]`, rec.Body.String())
+
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/tags/search?q=data", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[{"name": "databases", "displayName": "databases"}]`, rec.Body.String())
+}
+
+func TestAPIBookmarksSearch(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	_, err = repo.Create(data.BookmarkForm{URL: "https://go.dev", Title: "The Go Programming Language", Public: true})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://www.rust-lang.org", Title: "Rust Programming Language", Tags: "rust"})
+	require.NoError(t, err)
+
+	type result struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+		Next       string `json:"next"`
+		Suggestion string `json:"suggestion"`
+	}
+
+	rec := apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks/search?q=programming&per_page=1&sort=title", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page result
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "Rust Programming Language", page.Items[0].Title)
+	require.True(t, strings.HasPrefix(page.Next, "/api/v1/bookmarks/search?per_page=1&q=programming&sort=title&cursor="))
+
+	rec = apiRequest(e, secret, http.MethodGet, page.Next, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = result{}
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "The Go Programming Language", page.Items[0].Title)
+	require.Empty(t, page.Next)
+
+	// searches for the suggestion when nothing matches
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks/search?q=programing", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = result{}
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	require.Equal(t, "programming", page.Suggestion)
+
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks/search?q=", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+
+	// privacy and untagged filters of the list
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks?privacy=public", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = result{}
+	err = json.Unmarshal(rec.Body.Bytes(), &page)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "The Go Programming Language", page.Items[0].Title)
+
+	rec = apiRequest(e, secret, http.MethodGet, "/api/v1/bookmarks?privacy=private&untagged=true", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type archiveYear struct {
+	Year   int
+	Count  int64
+	Months []data.BookmarkMonthCount
+}
+
+func ArchiveHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	repo := data.NewBookmarkRepository(sc.DB)
+
+	privacy := data.BookmarkPrivacyPublic
+	if sc.IsAuthenticated() {
+		privacy = data.BookmarkPrivacyQueryAll
+	}
+
+	counts, err := repo.MonthCounts(privacy)
+	if err != nil {
+		return sc.Render(http.StatusOK, "archive.html", map[string]interface{}{
+			"error": "Failed to fetch archive.",
+		})
+	}
+	years := groupArchiveYears(counts)
+
+	if sc.Param("year") == "" {
+		return sc.Render(http.StatusOK, "archive.html", map[string]interface{}{
+			"years": years,
+		})
+	}
+
+	year, err := strconv.Atoi(sc.Param("year"))
+	if err != nil || year < 1 || year > 9999 {
+		return sc.RenderNotFound()
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	until := from.AddDate(1, 0, 0)
+	title := fmt.Sprint(year)
+	action := fmt.Sprintf("/archive/%04d", year)
+
+	if sc.Param("month") != "" {
+		month, err := strconv.Atoi(sc.Param("month"))
+		if err != nil || month < 1 || month > 12 {
+			return sc.RenderNotFound()
+		}
+		from = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		until = from.AddDate(0, 1, 0)
+		title = from.Format("January 2006")
+		action = fmt.Sprintf("/archive/%04d/%02d", year, month)
+	}
+
+	opts := parseListOptions(sc, action)
+	result, err := repo.List(data.BookmarkListRequest{
+		Privacy:      privacy,
+		CreatedFrom:  from,
+		CreatedUntil: until,
+		Cursor:       sc.QueryParam("cursor"),
+		PerPage:      opts.PerPage,
+		Sort:         opts.Sort,
+
+		PaginationPathPrefix: opts.PaginationPathPrefix(),
+	})
+	if err != nil {
+		return sc.Render(http.StatusOK, "archive.html", map[string]interface{}{
+			"years": years,
+			"error": "Failed to fetch bookmarks.",
+		})
+	}
+
+	return sc.Render(http.StatusOK, "archive.html", map[string]interface{}{
+		"years":   years,
+		"title":   title,
+		"result":  result,
+		"options": opts,
+	})
+}
+
+func groupArchiveYears(counts []data.BookmarkMonthCount) []archiveYear {
+	years := []archiveYear{}
+	for _, count := range counts {
+		if len(years) == 0 || years[len(years)-1].Year != count.Year {
+			years = append(years, archiveYear{Year: count.Year})
+		}
+		current := &years[len(years)-1]
+		current.Count += count.Count
+		current.Months = append(current.Months, count)
+	}
+	return years
+}
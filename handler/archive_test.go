@@ -0,0 +1,112 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestArchiveHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	bookmarks := []data.Bookmark{
+		{
+			URL:     "https://example.com/2022",
+			Title:   "December public",
+			Privacy: data.BookmarkPrivacyPublic,
+			Model:   gorm.Model{CreatedAt: time.Date(2022, time.December, 24, 12, 0, 0, 0, time.Local)},
+		},
+		{
+			URL:     "https://example.com/2023-01",
+			Title:   "January public",
+			Privacy: data.BookmarkPrivacyPublic,
+			Model:   gorm.Model{CreatedAt: time.Date(2023, time.January, 2, 12, 0, 0, 0, time.Local)},
+		},
+		{
+			URL:     "https://example.com/2023-01-private",
+			Title:   "January private",
+			Privacy: data.BookmarkPrivacyPrivate,
+			Model:   gorm.Model{CreatedAt: time.Date(2023, time.January, 3, 12, 0, 0, 0, time.Local)},
+		},
+		{
+			URL:     "https://example.com/2023-03",
+			Title:   "March public",
+			Privacy: data.BookmarkPrivacyPublic,
+			Model:   gorm.Model{CreatedAt: time.Date(2023, time.March, 4, 12, 0, 0, 0, time.Local)},
+		},
+	}
+	for i := range bookmarks {
+		require.NoError(t, db.Create(&bookmarks[i]).Error)
+	}
+
+	e := router.NewBaseApp(db)
+
+	// month navigation
+	req := httptest.NewRequest(http.MethodGet, "/archive", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.ArchiveHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), `href="/archive/2023/01"`)
+	require.Contains(t, rec.Body.String(), `href="/archive/2023/03"`)
+	require.Contains(t, rec.Body.String(), `href="/archive/2022/12"`)
+	require.NotContains(t, rec.Body.String(), "January public")
+
+	// year - unauthenticated
+	req = httptest.NewRequest(http.MethodGet, "/archive/2023", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("year")
+	sc.SetParamValues("2023")
+	err = handler.ArchiveHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "January public")
+	require.Contains(t, rec.Body.String(), "March public")
+	require.NotContains(t, rec.Body.String(), "January private")
+	require.NotContains(t, rec.Body.String(), "December public")
+
+	// month - authenticated
+	req = httptest.NewRequest(http.MethodGet, "/archive/2023/01", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("year", "month")
+	sc.SetParamValues("2023", "01")
+	err = handler.ArchiveHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "January 2023")
+	require.Contains(t, rec.Body.String(), "January public")
+	require.Contains(t, rec.Body.String(), "January private")
+	require.NotContains(t, rec.Body.String(), "March public")
+
+	// invalid month
+	req = httptest.NewRequest(http.MethodGet, "/archive/2023/13", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("year", "month")
+	sc.SetParamValues("2023", "13")
+	err = handler.ArchiveHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+
+	// invalid year
+	req = httptest.NewRequest(http.MethodGet, "/archive/notayear", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("year")
+	sc.SetParamValues("notayear")
+	err = handler.ArchiveHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/middleware"
@@ -41,8 +42,87 @@ func LoginHandler(c echo.Context) error {
 		return sc.Render(http.StatusOK, "login.html", genericError)
 	}
 
-	repo := data.NewSessionRepository(sc.DB)
-	session, err := repo.Create(&data.SessionCreate{
+	if settings.TwoFactorEnabled() {
+		if settings.TwoFactorLocked() {
+			return sc.Render(http.StatusOK, "login.html", twoFactorLockedError(next))
+		}
+		challenge, err := data.NewLoginChallengeRepository(sc.DB).Create()
+		if err != nil {
+			return sc.Render(http.StatusOK, "login.html", genericError)
+		}
+		return sc.Render(http.StatusOK, "login_2fa.html", map[string]interface{}{
+			"challenge": challenge,
+			"next":      next,
+		})
+	}
+
+	return startSession(sc, next)
+}
+
+// LoginTwoFactorHandler is the second login step when two-factor
+// authentication is enabled, it accepts a TOTP code or a recovery code.
+func LoginTwoFactorHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	next := sc.FormValue("next")
+	repo := data.NewLoginChallengeRepository(sc.DB)
+	challenge, err := repo.GetByToken(sc.FormValue("challenge"))
+	if err != nil || challenge == nil {
+		return sc.Render(http.StatusOK, "login.html", map[string]interface{}{
+			"error": "Login expired, please try again!",
+			"next":  next,
+		})
+	}
+
+	settingsRepo := data.NewSettingsRepository(sc.DB)
+	ok, err := settingsRepo.VerifyTwoFactor(sc.FormValue("code"))
+	if err != nil {
+		return sc.Render(http.StatusOK, "login.html", map[string]interface{}{
+			"error": "Login failed!",
+			"next":  next,
+		})
+	}
+	if !ok {
+		// wrong codes are also counted across challenges, a new password
+		// login doesn't help once that locks the second factor
+		settings, err := settingsRepo.Get()
+		if err != nil || settings == nil || settings.TwoFactorLocked() {
+			_ = repo.Delete(challenge)
+			return sc.Render(http.StatusOK, "login.html", twoFactorLockedError(next))
+		}
+
+		err = repo.RecordFailure(challenge)
+		if err != nil || challenge.Attempts >= data.MaxLoginChallengeAttempts {
+			return sc.Render(http.StatusOK, "login.html", map[string]interface{}{
+				"error": "Too many wrong codes, please try again!",
+				"next":  next,
+			})
+		}
+		return sc.Render(http.StatusOK, "login_2fa.html", map[string]interface{}{
+			"error":     "Code is wrong!",
+			"challenge": sc.FormValue("challenge"),
+			"next":      next,
+		})
+	}
+
+	err = repo.Delete(challenge)
+	if err != nil {
+		return err
+	}
+	return startSession(sc, next)
+}
+
+func twoFactorLockedError(next string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": "Too many wrong codes, please try again later!",
+		"next":  next,
+	}
+}
+
+// startSession logs in and redirects to next, which is a base64 encoded
+// local path.
+func startSession(sc *middleware.SubmarineContext, next string) error {
+	session, err := data.NewSessionRepository(sc.DB).Create(&data.SessionCreate{
 		IP:        sc.RealIP(),
 		UserAgent: sc.Request().UserAgent(),
 	})
@@ -52,11 +132,11 @@ func LoginHandler(c echo.Context) error {
 			"next":  next,
 		})
 	}
-	middleware.SetCookieSessionToken(sc, session.Token)
+	middleware.SetCookieSessionToken(sc, session.Token, session.ExpiresAt)
 
 	if next != "" {
 		next, err := base64.StdEncoding.DecodeString(next)
-		if err == nil {
+		if err == nil && isLocalPath(string(next)) {
 			return sc.Redirect(http.StatusFound, string(next))
 		}
 	}
@@ -67,7 +147,19 @@ func LoginHandler(c echo.Context) error {
 func LogoutHandler(c echo.Context) error {
 	sc := c.(*middleware.SubmarineContext)
 
+	if token := middleware.GetCookieSessionToken(sc); token != "" {
+		err := data.NewSessionRepository(sc.DB).DeleteByToken(token)
+		if err != nil {
+			return err
+		}
+	}
 	middleware.ClearCookieSessionToken(c)
 
 	return sc.Redirect(http.StatusFound, "/")
 }
+
+// isLocalPath reports whether path can be redirected to after logging in
+// without leaving the site.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
@@ -1,16 +1,22 @@
 package handler_test
 
 import (
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/handler"
 	"github.com/chdorner/submarine/router"
 	"github.com/chdorner/submarine/test"
+	"github.com/chdorner/submarine/util"
 	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,9 +68,36 @@ func TestLoginHandler(t *testing.T) {
 	uuid.MustParse(cookie["SubmarineSessionToken"].(string))
 }
 
+func TestLoginHandlerNext(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	err := data.NewSettingsRepository(db).Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+	e := router.NewBaseApp(db)
+
+	for next, expected := range map[string]string{
+		"/auth?client_id=https%3A%2F%2Fapp.example%2F": "/auth?client_id=https%3A%2F%2Fapp.example%2F",
+		"//evil.example/":       "/",
+		"https://evil.example/": "/",
+	} {
+		form := url.Values{
+			"password": {"secret"},
+			"next":     {base64.StdEncoding.EncodeToString([]byte(next))},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+		err = handler.LoginHandler(sc)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusFound, rec.Code)
+		require.Equal(t, expected, rec.Header().Get("Location"))
+	}
+}
+
 func TestLogoutHandler(t *testing.T) {
 	e := router.NewBaseApp(nil)
-	req := httptest.NewRequest(http.MethodGet, "/logout", strings.NewReader(""))
+	req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(""))
 	rec := httptest.NewRecorder()
 	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), nil)
 	err := handler.LogoutHandler(sc)
@@ -74,3 +107,174 @@ func TestLogoutHandler(t *testing.T) {
 	cookie := test.ParseCookie(t, rec.Header().Get("Set-Cookie"))
 	require.Empty(t, cookie["SubmarineSessionToken"])
 }
+
+func TestLogoutHandlerDeletesSession(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+	session, err := repo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "SubmarineSessionToken", Value: session.Token})
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.LogoutHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+
+	// the cookie can't be used anymore, even if it was kept
+	session, err = repo.GetByToken(session.Token)
+	require.NoError(t, err)
+	require.Nil(t, session)
+}
+
+func TestLogoutNeedsCSRFToken(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	require.NoError(t, data.NewSettingsRepository(db).Upsert(data.SettingsUpsert{Password: "secret"}))
+	server := httptest.NewServer(router.New(db))
+	defer server.Close()
+	browser := newBrowser(t)
+	repo := data.NewSessionRepository(db)
+
+	resp, err := browser.Get(server.URL + "/login")
+	require.NoError(t, err)
+	resp = submitForm(t, browser, server.URL+"/login", resp, url.Values{"password": {"secret"}})
+	resp.Body.Close()
+	require.Equal(t, "/", resp.Request.URL.Path)
+
+	// links and forms of other sites can't log out
+	resp, err = browser.Get(server.URL + "/logout")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp, err = browser.PostForm(server.URL+"/logout", url.Values{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	sessions, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	// the logout button submits the CSRF token
+	resp, err = browser.Get(server.URL + "/")
+	require.NoError(t, err)
+	resp = submitForm(t, browser, server.URL+"/logout", resp, url.Values{})
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessions, err = repo.List()
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestLoginTwoFactorHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	counter := util.TOTPCounter(time.Now())
+	code, err := util.TOTPCode(secret, counter)
+	require.NoError(t, err)
+	recoveryCodes, err := repo.EnableTwoFactor(secret, code)
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	post := func(h echo.HandlerFunc, target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+		require.NoError(t, h(sc))
+		return rec
+	}
+	challengePattern := regexp.MustCompile(`name="challenge" value="([^"]+)"`)
+	login := func() string {
+		next := base64.StdEncoding.EncodeToString([]byte("/settings"))
+		rec := post(handler.LoginHandler, "/login", url.Values{"password": {"secret"}, "next": {next}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Header().Get("Set-Cookie"))
+		require.Contains(t, rec.Body.String(), `action="/login/2fa"`)
+		require.Contains(t, rec.Body.String(), next)
+		match := challengePattern.FindStringSubmatch(rec.Body.String())
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	// the password alone doesn't start a session
+	challenge := login()
+	sessions, err := data.NewSessionRepository(db).List()
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	// wrong code
+	rec := post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {"wrong"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Code is wrong!")
+	require.Contains(t, rec.Body.String(), challenge)
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+
+	// the code used for enabling can't be used again
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {code}})
+	require.Contains(t, rec.Body.String(), "Code is wrong!")
+
+	// correct code
+	next, err := util.TOTPCode(secret, counter+1)
+	require.NoError(t, err)
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{
+		"challenge": {challenge},
+		"code":      {next},
+		"next":      {base64.StdEncoding.EncodeToString([]byte("/settings"))},
+	})
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+	cookie := test.ParseCookie(t, rec.Header().Get("Set-Cookie"))
+	uuid.MustParse(cookie["SubmarineSessionToken"].(string))
+	require.Equal(t, "/", cookie["Path"])
+
+	// challenges can only be used once
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {recoveryCodes[0]}})
+	require.Contains(t, rec.Body.String(), "Login expired, please try again!")
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+
+	// recovery code
+	challenge = login()
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {recoveryCodes[0]}})
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/", rec.Header().Get("Location"))
+
+	// too many wrong codes
+	challenge = login()
+	for i := 1; i < data.MaxLoginChallengeAttempts; i++ {
+		rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {"wrong"}})
+		require.Contains(t, rec.Body.String(), "Code is wrong!")
+	}
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {"wrong"}})
+	require.Contains(t, rec.Body.String(), "Too many wrong codes, please try again!")
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {recoveryCodes[1]}})
+	require.Contains(t, rec.Body.String(), "Login expired, please try again!")
+
+	// wrong codes are counted across challenges until the second factor is
+	// locked, entering the password again doesn't help
+	challenge = login()
+	for i := data.MaxLoginChallengeAttempts + 1; i < data.MaxTwoFactorFailures; i++ {
+		rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {"wrong"}})
+		require.Contains(t, rec.Body.String(), "Code is wrong!")
+	}
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {"wrong"}})
+	require.Contains(t, rec.Body.String(), "Too many wrong codes, please try again later!")
+	rec = post(handler.LoginHandler, "/login", url.Values{"password": {"secret"}})
+	require.Contains(t, rec.Body.String(), "Too many wrong codes, please try again later!")
+	require.NotContains(t, rec.Body.String(), `action="/login/2fa"`)
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+
+	// the lockout ends after a while
+	require.NoError(t, db.Model(&data.Settings{}).Where("1 = 1").Update("two_factor_failed_at", time.Now().Add(-data.TwoFactorLockout)).Error)
+	challenge = login()
+	rec = post(handler.LoginTwoFactorHandler, "/login/2fa", url.Values{"challenge": {challenge}, "code": {recoveryCodes[1]}})
+	require.Equal(t, http.StatusFound, rec.Code)
+}
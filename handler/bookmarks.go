@@ -8,6 +8,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/chdorner/submarine/activitypub"
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/middleware"
 )
@@ -20,16 +21,14 @@ func BookmarksListHandler(c echo.Context) error {
 	if sc.IsAuthenticated() {
 		privacy = data.BookmarkPrivacyQueryAll
 	}
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
-	if err != nil {
-		offset = 0
-	}
+	opts := parseListOptions(sc, "/")
 	result, err := repo.List(data.BookmarkListRequest{
 		Privacy: privacy,
-		Order:   "created_at desc",
-		Offset:  offset,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: opts.PerPage,
+		Sort:    opts.Sort,
 
-		PaginationPathPrefix: "/?",
+		PaginationPathPrefix: opts.PaginationPathPrefix(),
 	})
 	if err != nil {
 		return sc.Render(http.StatusOK, "bookmarks_list.html", map[string]interface{}{
@@ -38,7 +37,8 @@ func BookmarksListHandler(c echo.Context) error {
 	}
 
 	err = sc.Render(http.StatusOK, "bookmarks_list.html", map[string]interface{}{
-		"result": result,
+		"result":  result,
+		"options": opts,
 	})
 
 	return err
@@ -60,11 +60,61 @@ func BookmarkShowHandler(c echo.Context) error {
 		return sc.RenderNotFound()
 	}
 
+	// bookmarks are federated as notes, which are identified by this URL
+	if wantsActivityJSON(sc) {
+		if !bookmark.IsPublic() {
+			return sc.JSONNotFound()
+		}
+		note := activitypub.NewNote(requestBaseURL(sc), bookmark)
+		note.Context = "https://www.w3.org/ns/activitystreams"
+		return renderActivityJSON(sc, http.StatusOK, note)
+	}
+
 	return sc.Render(http.StatusOK, "bookmarks_show.html", map[string]interface{}{
 		"bookmark": bookmark,
 	})
 }
 
+// BookmarkVisitHandler counts a visit and redirects to the bookmarked URL.
+// Visits are counted on GET so that the links keep working as plain links,
+// when opened in new tabs or copied. Prefetch requests are still redirected
+// but not counted, and the links are marked nofollow for crawlers.
+func BookmarkVisitHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	repo := data.NewBookmarkRepository(sc.DB)
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+	bookmark, err := repo.Get(uint(id))
+	if err != nil || bookmark == nil {
+		return sc.RenderNotFound()
+	}
+	if bookmark.Privacy == data.BookmarkPrivacyPrivate && !sc.IsAuthenticated() {
+		return sc.RenderNotFound()
+	}
+
+	if !isPrefetch(sc.Request()) {
+		// a failure to count the visit shouldn't keep anyone from the bookmark
+		_ = repo.Visit(bookmark.ID)
+	}
+
+	return sc.Redirect(http.StatusFound, bookmark.URL)
+}
+
+// isPrefetch reports whether a browser requests the page speculatively,
+// before or without the user following the link.
+func isPrefetch(req *http.Request) bool {
+	for _, header := range []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(req.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
+
 func BookmarksNewHandler(c echo.Context) error {
 	sc := c.(*middleware.SubmarineContext)
 	if !sc.IsAuthenticated() {
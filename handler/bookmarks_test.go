@@ -165,6 +165,135 @@ func TestBookmarksListHandler(t *testing.T) {
 	for _, title := range append(publicTitles, privateTitles...) {
 		require.Contains(t, rec.Body.String(), title)
 	}
+
+	// page size and sort order
+	req = httptest.NewRequest(http.MethodGet, "/?per_page=2&sort=oldest", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.BookmarksListHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Bookmark 0 public")
+	require.Contains(t, rec.Body.String(), "Bookmark 1 private")
+	require.NotContains(t, rec.Body.String(), "Bookmark 2 public")
+	require.Contains(t, rec.Body.String(), `href="/?per_page=2&amp;sort=oldest&amp;cursor=`)
+	require.Contains(t, rec.Body.String(), `<option value="oldest" selected>`)
+
+	// default page size and sort order from settings
+	settingsRepo := data.NewSettingsRepository(db)
+	err = settingsRepo.Upsert(data.SettingsUpsert{Password: "secret", PerPage: 3, Sort: data.BookmarkSortOldest})
+	require.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.BookmarksListHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Bookmark 2 public")
+	require.NotContains(t, rec.Body.String(), "Bookmark 3 private")
+	require.Contains(t, rec.Body.String(), `href="/?cursor=`)
+
+	// invalid sort order is ignored
+	req = httptest.NewRequest(http.MethodGet, "/?sort=id", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.BookmarksListHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Bookmark 0 public")
+}
+
+func TestBookmarkVisitHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	publicBookmark, err := repo.Create(data.BookmarkForm{
+		URL:    "https://example.com/public",
+		Public: true,
+	})
+	require.NoError(t, err)
+	privateBookmark, err := repo.Create(data.BookmarkForm{
+		URL: "https://example.com/private",
+	})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	// public bookmark - unauthenticated
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bookmarks/%d/visit", publicBookmark.ID), nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(publicBookmark.ID))
+
+	err = handler.BookmarkVisitHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	require.Equal(t, publicBookmark.URL, rec.Header().Get("Location"))
+	actual, err := repo.Get(publicBookmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), actual.Visits)
+
+	// prefetches are redirected without counting a visit
+	for header, value := range map[string]string{
+		"Sec-Purpose": "prefetch;prerender",
+		"Purpose":     "prefetch",
+		"X-Moz":       "prefetch",
+		"X-Purpose":   "preview",
+	} {
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bookmarks/%d/visit", publicBookmark.ID), nil)
+		req.Header.Set(header, value)
+		rec = httptest.NewRecorder()
+		sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+		sc.SetParamNames("id")
+		sc.SetParamValues(fmt.Sprint(publicBookmark.ID))
+
+		err = handler.BookmarkVisitHandler(sc)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	}
+	actual, err = repo.Get(publicBookmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), actual.Visits)
+
+	// private bookmark - unauthenticated
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bookmarks/%d/visit", privateBookmark.ID), nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(privateBookmark.ID))
+
+	err = handler.BookmarkVisitHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
+	actual, err = repo.Get(privateBookmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(0), actual.Visits)
+
+	// private bookmark - authenticated
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bookmarks/%d/visit", privateBookmark.ID), nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(privateBookmark.ID))
+
+	err = handler.BookmarkVisitHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	require.Equal(t, privateBookmark.URL, rec.Header().Get("Location"))
+
+	// non-existing id
+	req = httptest.NewRequest(http.MethodGet, "/bookmarks/42/visit", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues("42")
+
+	err = handler.BookmarkVisitHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Result().StatusCode)
 }
 
 func TestBookmarksShowHandler(t *testing.T) {
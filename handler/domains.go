@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func DomainsHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	repo := data.NewBookmarkRepository(sc.DB)
+
+	privacy := data.BookmarkPrivacyPublic
+	if sc.IsAuthenticated() {
+		privacy = data.BookmarkPrivacyQueryAll
+	}
+
+	domains, err := repo.DomainCounts(privacy)
+	if err != nil {
+		return sc.Render(http.StatusOK, "domains_list.html", map[string]interface{}{
+			"error": "Failed to fetch domains.",
+		})
+	}
+
+	return sc.Render(http.StatusOK, "domains_list.html", map[string]interface{}{
+		"domains": domains,
+	})
+}
+
+func DomainHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	repo := data.NewBookmarkRepository(sc.DB)
+
+	domain := strings.ToLower(sc.Param("host"))
+	if domain == "" {
+		return sc.RenderNotFound()
+	}
+
+	privacy := data.BookmarkPrivacyPublic
+	if sc.IsAuthenticated() {
+		privacy = data.BookmarkPrivacyQueryAll
+	}
+
+	opts := parseListOptions(sc, fmt.Sprintf("/domains/%s", domain))
+	result, err := repo.List(data.BookmarkListRequest{
+		Privacy: privacy,
+		Domain:  domain,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: opts.PerPage,
+		Sort:    opts.Sort,
+
+		PaginationPathPrefix: opts.PaginationPathPrefix(),
+	})
+	if err != nil {
+		return sc.Render(http.StatusOK, "domains_show.html", map[string]interface{}{
+			"domain": domain,
+			"error":  "Failed to fetch bookmarks.",
+		})
+	}
+	if len(result.Items) == 0 && sc.QueryParam("cursor") == "" {
+		return sc.RenderNotFound()
+	}
+
+	return sc.Render(http.StatusOK, "domains_show.html", map[string]interface{}{
+		"domain":  domain,
+		"result":  result,
+		"options": opts,
+	})
+}
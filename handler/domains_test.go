@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainsHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	_, err := repo.Create(data.BookmarkForm{URL: "https://public.example.com", Public: true})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://private.example.com", Public: false})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	// lists public domains when logged out
+	req := httptest.NewRequest(http.MethodGet, "/domains", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.DomainsHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), `href="/domains/public.example.com"`)
+	require.NotContains(t, rec.Body.String(), "private.example.com")
+
+	// lists all domains when logged in
+	req = httptest.NewRequest(http.MethodGet, "/domains", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.DomainsHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), `href="/domains/public.example.com"`)
+	require.Contains(t, rec.Body.String(), `href="/domains/private.example.com"`)
+}
+
+func TestDomainHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	_, err := repo.Create(data.BookmarkForm{URL: "https://example.com/public", Title: "Public bookmark", Public: true})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://example.com/private", Title: "Private bookmark", Public: false})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://other.org", Title: "Other bookmark", Public: true})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	// queries public bookmarks when logged out
+	req := httptest.NewRequest(http.MethodGet, "/domains/example.com", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("host")
+	sc.SetParamValues("example.com")
+
+	err = handler.DomainHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Public bookmark")
+	require.NotContains(t, rec.Body.String(), "Private bookmark")
+	require.NotContains(t, rec.Body.String(), "Other bookmark")
+
+	// queries all bookmarks when logged in
+	req = httptest.NewRequest(http.MethodGet, "/domains/example.com", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("host")
+	sc.SetParamValues("example.com")
+
+	err = handler.DomainHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Public bookmark")
+	require.Contains(t, rec.Body.String(), "Private bookmark")
+	require.NotContains(t, rec.Body.String(), "Other bookmark")
+
+	// domain without bookmarks
+	req = httptest.NewRequest(http.MethodGet, "/domains/missing.com", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("host")
+	sc.SetParamValues("missing.com")
+
+	err = handler.DomainHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+func SettingsFeedTokensCreateHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	repo := data.NewFeedTokenRepository(sc.DB)
+	token, secret, err := repo.Create(data.FeedTokenCreate{Name: sc.FormValue("name")})
+	if err != nil {
+		tplData := map[string]interface{}{
+			"feedTokenError": "Failed to create feed token.",
+		}
+		if validationErr, ok := err.(*data.ValidationError); ok {
+			tplData["feedTokenValidationErrors"] = validationErr.Fields
+		}
+		return renderSettings(sc, tplData)
+	}
+
+	// the secret can't be shown again, so render instead of redirecting
+	return renderSettings(sc, map[string]interface{}{
+		"newFeedToken":       token,
+		"newFeedTokenSecret": secret,
+	})
+}
+
+func SettingsFeedTokenRevokeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+	err = data.NewFeedTokenRepository(sc.DB).Revoke(uint(id))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestSettingsFeedTokensCreateHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewFeedTokenRepository(db)
+
+	contentType := "application/x-www-form-urlencoded"
+	e := router.NewBaseApp(db)
+
+	// unauthenticated
+	req := httptest.NewRequest(http.MethodPost, "/settings/feeds", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.SettingsFeedTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	// success
+	form := url.Values{}
+	form.Add("name", "Feed reader")
+	req = httptest.NewRequest(http.MethodPost, "/settings/feeds", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsFeedTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "won't be shown again")
+	require.Contains(t, rec.Body.String(), "http://example.com/feeds/")
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, "Feed reader", tokens[0].Name)
+
+	// validation errors
+	req = httptest.NewRequest(http.MethodPost, "/settings/feeds", strings.NewReader(""))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsFeedTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Name is required")
+}
+
+func TestSettingsFeedTokenRevokeHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewFeedTokenRepository(db)
+
+	token, _, err := repo.Create(data.FeedTokenCreate{Name: "Feed reader"})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	target := fmt.Sprintf("/settings/feeds/%d/revoke", token.ID)
+
+	// success
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(token.ID))
+	err = handler.SettingsFeedTokenRevokeHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	// not found
+	req = httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(token.ID))
+	err = handler.SettingsFeedTokenRevokeHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+// feedSize is the number of latest bookmarks published in a feed.
+const feedSize = 50
+
+// feed holds what all feed formats have in common, paths are relative to
+// the base URL of the request.
+type feed struct {
+	Title     string
+	SelfPath  string
+	HTMLPath  string
+	Bookmarks []data.Bookmark
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	ExternalURL   string   `json:"external_url"`
+	Title         string   `json:"title"`
+	ContentText   string   `json:"content_text"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+func AtomFeedHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	f, err := loadFeed(sc, data.BookmarkPrivacyPublic, "", "feed.atom")
+	if err != nil || f == nil {
+		return sc.RenderNotFound()
+	}
+	return renderAtom(sc, f)
+}
+
+func RSSFeedHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	f, err := loadFeed(sc, data.BookmarkPrivacyPublic, "", "feed.rss")
+	if err != nil || f == nil {
+		return sc.RenderNotFound()
+	}
+	return renderRSS(sc, f)
+}
+
+// JSONFeedHandler includes private bookmarks for logged in users, like the
+// bookmark lists do.
+func JSONFeedHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	privacy := data.BookmarkPrivacyPublic
+	if sc.IsAuthenticated() {
+		privacy = data.BookmarkPrivacyQueryAll
+	}
+
+	f, err := loadFeed(sc, privacy, "", "feed.json")
+	if err != nil || f == nil {
+		return sc.RenderNotFound()
+	}
+	return renderJSONFeed(sc, f)
+}
+
+func PrivateAtomFeedHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	f, err := loadPrivateFeed(sc, "feed.atom")
+	if err != nil || f == nil {
+		return sc.RenderNotFound()
+	}
+	return renderAtom(sc, f)
+}
+
+func PrivateJSONFeedHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	f, err := loadPrivateFeed(sc, "feed.json")
+	if err != nil || f == nil {
+		return sc.RenderNotFound()
+	}
+	return renderJSONFeed(sc, f)
+}
+
+// loadPrivateFeed loads a feed including private bookmarks, the request
+// needs to be authenticated with a feed token.
+func loadPrivateFeed(sc *middleware.SubmarineContext, name string) (*feed, error) {
+	if _, ok := sc.Get("FeedToken").(*data.FeedToken); !ok {
+		return nil, nil
+	}
+	f, err := loadFeed(sc, data.BookmarkPrivacyQueryAll, fmt.Sprintf("/feeds/%s", sc.Param("token")), name)
+	if err != nil || f == nil {
+		return nil, err
+	}
+	f.Title = strings.Replace(f.Title, "Submarine", "Submarine (private)", 1)
+	return f, nil
+}
+
+// loadFeed loads the latest bookmarks, limited to the tag in the path if
+// there is one. It returns nil if the tag does not exist.
+func loadFeed(sc *middleware.SubmarineContext, privacy data.BookmarkPrivacy, prefix, name string) (*feed, error) {
+	req := data.BookmarkListRequest{
+		Privacy: privacy,
+		PerPage: feedSize,
+		Sort:    data.BookmarkSortNewest,
+	}
+	f := &feed{
+		Title:    "Submarine",
+		SelfPath: fmt.Sprintf("%s/%s", prefix, name),
+		HTMLPath: "/",
+	}
+
+	if sc.Param("name") != "" {
+		tag, err := data.NewTagRepository(sc.DB).GetByName(sc.Param("name"))
+		if err != nil || tag == nil {
+			return nil, err
+		}
+		req.TagID = tag.ID
+		f.Title = fmt.Sprintf("Submarine: %s", tag.DisplayName)
+		f.HTMLPath = fmt.Sprintf("/tags/%s", tag.Name)
+		f.SelfPath = fmt.Sprintf("%s/tags/%s/%s", prefix, tag.Name, name)
+	}
+
+	result, err := data.NewBookmarkRepository(sc.DB).List(req)
+	if err != nil {
+		return nil, err
+	}
+	f.Bookmarks = result.Items
+	return f, nil
+}
+
+func renderAtom(sc *middleware.SubmarineContext, f *feed) error {
+	baseURL := requestBaseURL(sc)
+	atom := atomFeed{
+		Title:   f.Title,
+		ID:      baseURL + f.SelfPath,
+		Updated: f.Updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: baseURL + f.SelfPath},
+			{Rel: "alternate", Type: "text/html", Href: baseURL + f.HTMLPath},
+		},
+		Author:  atomAuthor{Name: "submarine"},
+		Entries: []atomEntry{},
+	}
+	for _, bookmark := range f.Bookmarks {
+		permalink := fmt.Sprintf("%s/bookmarks/%d", baseURL, bookmark.ID)
+		entry := atomEntry{
+			Title: bookmarkFeedTitle(bookmark),
+			ID:    permalink,
+			Links: []atomLink{
+				{Rel: "alternate", Href: bookmark.URL},
+				{Rel: "related", Type: "text/html", Href: permalink},
+			},
+			Published: bookmark.CreatedAt.Format(time.RFC3339),
+			Updated:   bookmark.UpdatedAt.Format(time.RFC3339),
+			Summary:   bookmark.Description,
+		}
+		for _, tag := range bookmark.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag.DisplayName})
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+
+	return renderFeedXML(sc, "application/atom+xml", atom)
+}
+
+func renderRSS(sc *middleware.SubmarineContext, f *feed) error {
+	baseURL := requestBaseURL(sc)
+	rss := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          baseURL + f.HTMLPath,
+			Description:   fmt.Sprintf("Latest bookmarks of %s", f.Title),
+			LastBuildDate: f.Updated().Format(time.RFC1123Z),
+			AtomLink:      atomLink{Rel: "self", Type: "application/rss+xml", Href: baseURL + f.SelfPath},
+			Items:         []rssItem{},
+		},
+	}
+	for _, bookmark := range f.Bookmarks {
+		item := rssItem{
+			Title:       bookmarkFeedTitle(bookmark),
+			Link:        bookmark.URL,
+			Description: bookmark.Description,
+			GUID: rssGUID{
+				IsPermaLink: true,
+				Value:       fmt.Sprintf("%s/bookmarks/%d", baseURL, bookmark.ID),
+			},
+			PubDate: bookmark.CreatedAt.Format(time.RFC1123Z),
+		}
+		for _, tag := range bookmark.Tags {
+			item.Categories = append(item.Categories, tag.DisplayName)
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+
+	return renderFeedXML(sc, "application/rss+xml", rss)
+}
+
+func renderJSONFeed(sc *middleware.SubmarineContext, f *feed) error {
+	baseURL := requestBaseURL(sc)
+	jf := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: baseURL + f.HTMLPath,
+		FeedURL:     baseURL + f.SelfPath,
+		Items:       []jsonFeedItem{},
+	}
+	for _, bookmark := range f.Bookmarks {
+		item := jsonFeedItem{
+			ID:            fmt.Sprintf("%s/bookmarks/%d", baseURL, bookmark.ID),
+			URL:           fmt.Sprintf("%s/bookmarks/%d", baseURL, bookmark.ID),
+			ExternalURL:   bookmark.URL,
+			Title:         bookmarkFeedTitle(bookmark),
+			ContentText:   bookmark.Description,
+			DatePublished: bookmark.CreatedAt.Format(time.RFC3339),
+			DateModified:  bookmark.UpdatedAt.Format(time.RFC3339),
+		}
+		for _, tag := range bookmark.Tags {
+			item.Tags = append(item.Tags, tag.DisplayName)
+		}
+		jf.Items = append(jf.Items, item)
+	}
+
+	body, err := json.Marshal(jf)
+	if err != nil {
+		return err
+	}
+	return sc.Blob(http.StatusOK, "application/feed+json; charset=utf-8", body)
+}
+
+func renderFeedXML(sc *middleware.SubmarineContext, contentType string, value interface{}) error {
+	body, err := xml.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return sc.Blob(http.StatusOK, contentType+"; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// Updated returns when the most recently changed bookmark was updated.
+func (f *feed) Updated() time.Time {
+	updated := time.Unix(0, 0)
+	for _, bookmark := range f.Bookmarks {
+		if bookmark.UpdatedAt.After(updated) {
+			updated = bookmark.UpdatedAt
+		}
+	}
+	return updated
+}
+
+func bookmarkFeedTitle(bookmark data.Bookmark) string {
+	if bookmark.Title != "" {
+		return bookmark.Title
+	}
+	return bookmark.URL
+}
+
+func requestBaseURL(sc *middleware.SubmarineContext) string {
+	return fmt.Sprintf("%s://%s", sc.Scheme(), sc.Request().Host)
+}
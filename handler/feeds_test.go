@@ -0,0 +1,331 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+type testAtomFeed struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+type testRSSFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title      string   `xml:"title"`
+			Link       string   `xml:"link"`
+			GUID       string   `xml:"guid"`
+			Categories []string `xml:"category"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type testJSONFeed struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	FeedURL string `json:"feed_url"`
+	Items   []struct {
+		Title       string   `json:"title"`
+		ExternalURL string   `json:"external_url"`
+		ContentText string   `json:"content_text"`
+		Tags        []string `json:"tags"`
+	} `json:"items"`
+}
+
+func createFeedBookmarks(t *testing.T, repo *data.BookmarkRepository) {
+	for i, tags := range []string{"go", "go, Articles", "articles"} {
+		_, err := repo.Create(data.BookmarkForm{
+			URL:    fmt.Sprintf("https://example-%d.com", i),
+			Title:  fmt.Sprintf("Public %d", i),
+			Public: true,
+			Tags:   tags,
+		})
+		require.NoError(t, err)
+	}
+	_, err := repo.Create(data.BookmarkForm{
+		URL:   "https://example.com/private",
+		Title: "Private",
+		Tags:  "go",
+	})
+	require.NoError(t, err)
+}
+
+func TestAtomFeedHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	createFeedBookmarks(t, data.NewBookmarkRepository(db))
+
+	e := router.NewBaseApp(db)
+
+	// only public bookmarks, even when authenticated
+	req := httptest.NewRequest(http.MethodGet, "/feed.atom", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.AtomFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/atom+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var atom testAtomFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &atom))
+	require.Equal(t, "Submarine", atom.Title)
+	require.Len(t, atom.Entries, 3)
+	require.Equal(t, "Public 2", atom.Entries[0].Title)
+	require.Equal(t, "https://example-2.com", atom.Entries[0].Links[0].Href)
+	require.Equal(t, "http://example.com/bookmarks/3", atom.Entries[0].Links[1].Href)
+	require.Equal(t, "Articles", atom.Entries[0].Categories[0].Term)
+
+	// tag feed
+	req = httptest.NewRequest(http.MethodGet, "/tags/go/feed.atom", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("go")
+	err = handler.AtomFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	atom = testAtomFeed{}
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &atom))
+	require.Equal(t, "Submarine: go", atom.Title)
+	require.Len(t, atom.Entries, 2)
+	require.Equal(t, "Public 1", atom.Entries[0].Title)
+	require.Equal(t, "Public 0", atom.Entries[1].Title)
+
+	// unknown tag
+	req = httptest.NewRequest(http.MethodGet, "/tags/unknown/feed.atom", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("unknown")
+	err = handler.AtomFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRSSFeedHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	createFeedBookmarks(t, data.NewBookmarkRepository(db))
+
+	e := router.NewBaseApp(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.RSSFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/rss+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var rss testRSSFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &rss))
+	require.Equal(t, "Submarine", rss.Channel.Title)
+	require.Len(t, rss.Channel.Items, 3)
+	require.Equal(t, "Public 2", rss.Channel.Items[0].Title)
+	require.Equal(t, "https://example-2.com", rss.Channel.Items[0].Link)
+	require.Equal(t, "http://example.com/bookmarks/3", rss.Channel.Items[0].GUID)
+
+	// tag feed
+	req = httptest.NewRequest(http.MethodGet, "/tags/articles/feed.rss", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("articles")
+	err = handler.RSSFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rss = testRSSFeed{}
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &rss))
+	require.Equal(t, "Submarine: Articles", rss.Channel.Title)
+	require.Len(t, rss.Channel.Items, 2)
+	require.ElementsMatch(t, []string{"go", "Articles"}, rss.Channel.Items[1].Categories)
+}
+
+func TestJSONFeedHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+	createFeedBookmarks(t, repo)
+	_, err := repo.Create(data.BookmarkForm{
+		URL:         "https://example.com/described",
+		Title:       "Described",
+		Description: "A bookmark with a description",
+		Public:      true,
+	})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	// public bookmarks when logged out
+	req := httptest.NewRequest(http.MethodGet, "/feed.json", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.JSONFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/feed+json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var jf testJSONFeed
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jf))
+	require.Equal(t, "https://jsonfeed.org/version/1.1", jf.Version)
+	require.Equal(t, "Submarine", jf.Title)
+	require.Equal(t, "http://example.com/feed.json", jf.FeedURL)
+	require.Len(t, jf.Items, 4)
+	require.Equal(t, "Described", jf.Items[0].Title)
+	require.Equal(t, "A bookmark with a description", jf.Items[0].ContentText)
+	require.Nil(t, jf.Items[0].Tags)
+	require.ElementsMatch(t, []string{"go", "Articles"}, jf.Items[2].Tags)
+
+	// all bookmarks of a tag when logged in
+	req = httptest.NewRequest(http.MethodGet, "/tags/go/feed.json", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("go")
+	err = handler.JSONFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	jf = testJSONFeed{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jf))
+	require.Equal(t, "Submarine: go", jf.Title)
+	require.Equal(t, "http://example.com/tags/go/feed.json", jf.FeedURL)
+	require.Len(t, jf.Items, 3)
+	require.Equal(t, "Private", jf.Items[0].Title)
+
+	// public bookmarks of a tag when logged out
+	req = httptest.NewRequest(http.MethodGet, "/tags/go/feed.json", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("go")
+	err = handler.JSONFeedHandler(sc)
+	require.NoError(t, err)
+
+	jf = testJSONFeed{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jf))
+	require.Len(t, jf.Items, 2)
+
+	// unknown tag
+	req = httptest.NewRequest(http.MethodGet, "/tags/unknown/feed.json", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("unknown")
+	err = handler.JSONFeedHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestFeedAlternateLinks(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	createFeedBookmarks(t, data.NewBookmarkRepository(db))
+
+	e := router.NewBaseApp(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/tags/go", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("name")
+	sc.SetParamValues("go")
+	err := handler.TagHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), `href="/feed.atom"`)
+	require.Contains(t, rec.Body.String(), `href="/feed.rss"`)
+	require.Contains(t, rec.Body.String(), `href="/tags/go/feed.atom"`)
+	require.Contains(t, rec.Body.String(), `href="/tags/go/feed.rss"`)
+	require.Contains(t, rec.Body.String(), `href="/feed.json"`)
+	require.Contains(t, rec.Body.String(), `href="/tags/go/feed.json"`)
+}
+
+func TestPrivateFeedHandlers(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	createFeedBookmarks(t, data.NewBookmarkRepository(db))
+	_, secret, err := data.NewFeedTokenRepository(db).Create(data.FeedTokenCreate{Name: "Feed reader"})
+	require.NoError(t, err)
+
+	e := router.New(db)
+
+	// Atom feed includes private bookmarks
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/feeds/%s/feed.atom", secret), nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var atom testAtomFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &atom))
+	require.Equal(t, "Submarine (private)", atom.Title)
+	require.Len(t, atom.Entries, 4)
+	require.Equal(t, "Private", atom.Entries[0].Title)
+
+	// JSON feed of a tag
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/feeds/%s/tags/go/feed.json", secret), nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/feed+json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var jf testJSONFeed
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jf))
+	require.Equal(t, "https://jsonfeed.org/version/1.1", jf.Version)
+	require.Equal(t, "Submarine (private): go", jf.Title)
+	require.Equal(t, fmt.Sprintf("http://example.com/feeds/%s/tags/go/feed.json", secret), jf.FeedURL)
+	require.Len(t, jf.Items, 3)
+	require.Equal(t, "Private", jf.Items[0].Title)
+	require.Equal(t, "https://example.com/private", jf.Items[0].ExternalURL)
+	require.Equal(t, []string{"go"}, jf.Items[0].Tags)
+
+	// unknown tag
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/feeds/%s/tags/unknown/feed.json", secret), nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// invalid token, even with a session
+	req = httptest.NewRequest(http.MethodGet, "/feeds/invalid/feed.atom", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.NotContains(t, rec.Body.String(), "Private")
+
+	// API tokens don't work for feeds
+	_, apiSecret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "api", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/feeds/%s/feed.atom", apiSecret), nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// feed tokens don't work for the API
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/bookmarks", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+type indieAuthMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RevocationEndpoint            string   `json:"revocation_endpoint"`
+	ScopesSupported               []string `json:"scopes_supported"`
+	ResponseTypesSupported        []string `json:"response_types_supported"`
+	GrantTypesSupported           []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+	AuthorizationResponseIssParam bool     `json:"authorization_response_iss_parameter_supported"`
+	RevocationEndpointAuthMethods []string `json:"revocation_endpoint_auth_methods_supported"`
+}
+
+type indieAuthProfile struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type indieAuthResponse struct {
+	Me          string            `json:"me"`
+	Profile     *indieAuthProfile `json:"profile,omitempty"`
+	AccessToken string            `json:"access_token,omitempty"`
+	TokenType   string            `json:"token_type,omitempty"`
+	Scope       string            `json:"scope,omitempty"`
+}
+
+type oauthError struct {
+	status      int
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+type indieAuthScope struct {
+	Name        string
+	Description string
+}
+
+var indieAuthScopeDescriptions = map[string]string{
+	data.IndieAuthScopeCreate:  "Create bookmarks",
+	data.IndieAuthScopeRead:    "Read all bookmarks, including private ones",
+	data.IndieAuthScopeProfile: "See the profile URL",
+}
+
+// IndieAuthMetadataHandler serves the authorization server metadata clients
+// discover through the indieauth-metadata link.
+func IndieAuthMetadataHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	baseURL := requestBaseURL(sc)
+	return sc.JSON(http.StatusOK, indieAuthMetadata{
+		Issuer:                        indieAuthMe(sc),
+		AuthorizationEndpoint:         baseURL + "/auth",
+		TokenEndpoint:                 baseURL + "/token",
+		RevocationEndpoint:            baseURL + "/token/revoke",
+		ScopesSupported:               data.IndieAuthScopes,
+		ResponseTypesSupported:        []string{"code"},
+		GrantTypesSupported:           []string{"authorization_code"},
+		CodeChallengeMethodsSupported: []string{"S256"},
+		AuthorizationResponseIssParam: true,
+		RevocationEndpointAuthMethods: []string{"none"},
+	})
+}
+
+// AuthorizeViewHandler asks to approve an authorization request, requests
+// with an untrusted client or redirect URI are never redirected back.
+func AuthorizeViewHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	req := parseAuthorizationRequest(sc)
+	validationErr := req.IsValidClient()
+	if validationErr != nil {
+		return renderAuthorize(sc, http.StatusBadRequest, map[string]interface{}{
+			"errors": validationErr.Fields,
+		})
+	}
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+	validationErr = req.IsValid()
+	if validationErr != nil {
+		return redirectAuthorizationError(sc, req, "invalid_request", validationErr.Error())
+	}
+
+	scopes := []indieAuthScope{}
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, indieAuthScope{Name: scope, Description: indieAuthScopeDescriptions[scope]})
+	}
+	return renderAuthorize(sc, http.StatusOK, map[string]interface{}{
+		"request": req,
+		"scope":   strings.Join(req.Scopes, " "),
+		"scopes":  scopes,
+	})
+}
+
+// AuthorizeHandler issues an authorization code for an approved request, the
+// scopes can be narrowed down on approval.
+func AuthorizeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	req := parseAuthorizationRequest(sc)
+	validationErr := req.IsValidClient()
+	if validationErr != nil {
+		return renderAuthorize(sc, http.StatusBadRequest, map[string]interface{}{
+			"errors": validationErr.Fields,
+		})
+	}
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+	if sc.FormValue("decision") != "approve" {
+		return redirectAuthorizationError(sc, req, "access_denied", "The request was denied")
+	}
+
+	form, err := sc.FormParams()
+	if err != nil {
+		return redirectAuthorizationError(sc, req, "invalid_request", "Request is invalid")
+	}
+	approved := []string{}
+	for _, scope := range req.Scopes {
+		for _, s := range form["approved_scopes"] {
+			if s == scope {
+				approved = append(approved, scope)
+				break
+			}
+		}
+	}
+	req.Scopes = approved
+
+	code, err := data.NewIndieAuthRepository(sc.DB).CreateCode(req)
+	if err != nil {
+		if validationErr, ok := err.(*data.ValidationError); ok {
+			return redirectAuthorizationError(sc, req, "invalid_request", validationErr.Error())
+		}
+		return redirectAuthorizationError(sc, req, "server_error", "Failed to create authorization code")
+	}
+
+	return redirectAuthorization(sc, req, url.Values{"code": {code}})
+}
+
+// AuthorizationCodeHandler redeems authorization codes for clients which only
+// need to know who logged in.
+func AuthorizationCodeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	code, oauthErr := redeemAuthorizationCode(sc)
+	if oauthErr != nil {
+		return sc.JSON(oauthErr.status, oauthErr)
+	}
+
+	return sc.JSON(http.StatusOK, newIndieAuthResponse(sc, code))
+}
+
+// TokenHandler exchanges authorization codes for API tokens, the legacy
+// "action=revoke" requests are supported as well.
+func TokenHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if sc.FormValue("action") == "revoke" {
+		return TokenRevokeHandler(c)
+	}
+
+	code, oauthErr := redeemAuthorizationCode(sc)
+	if oauthErr != nil {
+		return sc.JSON(oauthErr.status, oauthErr)
+	}
+	scopes := code.TokenScopes()
+	if len(scopes) == 0 {
+		return renderOAuthError(sc, http.StatusBadRequest, "invalid_grant", "Authorization code doesn't grant any token scopes")
+	}
+
+	_, secret, err := data.NewTokenRepository(sc.DB).Create(data.TokenCreate{
+		Name:     code.ClientID,
+		Scopes:   scopes,
+		ClientID: code.ClientID,
+	})
+	if err != nil {
+		return renderOAuthError(sc, http.StatusInternalServerError, "server_error", "Failed to create token")
+	}
+
+	response := newIndieAuthResponse(sc, code)
+	response.AccessToken = secret
+	response.TokenType = "Bearer"
+	response.Scope = code.Scope
+	return sc.JSON(http.StatusOK, response)
+}
+
+// TokenRevokeHandler revokes the given token, unknown tokens are ignored as
+// the outcome is the same.
+func TokenRevokeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+
+	repo := data.NewTokenRepository(sc.DB)
+	token, err := repo.GetBySecret(sc.FormValue("token"))
+	if err != nil {
+		return renderOAuthError(sc, http.StatusInternalServerError, "server_error", "Failed to fetch token")
+	}
+	if token != nil {
+		err = repo.Revoke(token.ID)
+		if err != nil {
+			return renderOAuthError(sc, http.StatusInternalServerError, "server_error", "Failed to revoke token")
+		}
+	}
+
+	return sc.NoContent(http.StatusOK)
+}
+
+func parseAuthorizationRequest(sc *middleware.SubmarineContext) data.AuthorizationRequest {
+	return data.AuthorizationRequest{
+		ResponseType:        sc.FormValue("response_type"),
+		ClientID:            sc.FormValue("client_id"),
+		RedirectURI:         sc.FormValue("redirect_uri"),
+		State:               sc.FormValue("state"),
+		CodeChallenge:       sc.FormValue("code_challenge"),
+		CodeChallengeMethod: sc.FormValue("code_challenge_method"),
+		Scopes:              data.ParseIndieAuthScopes(sc.FormValue("scope")),
+	}
+}
+
+// redeemAuthorizationCode redeems the code of a token request.
+func redeemAuthorizationCode(sc *middleware.SubmarineContext) (*data.AuthorizationCode, *oauthError) {
+	grantType := sc.FormValue("grant_type")
+	if grantType != "" && grantType != "authorization_code" {
+		return nil, &oauthError{http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code is supported"}
+	}
+
+	code, err := data.NewIndieAuthRepository(sc.DB).RedeemCode(data.AuthorizationCodeRedeem{
+		Code:         sc.FormValue("code"),
+		ClientID:     sc.FormValue("client_id"),
+		RedirectURI:  sc.FormValue("redirect_uri"),
+		CodeVerifier: sc.FormValue("code_verifier"),
+	})
+	if err != nil {
+		return nil, &oauthError{http.StatusInternalServerError, "server_error", "Failed to redeem authorization code"}
+	}
+	if code == nil {
+		return nil, &oauthError{http.StatusBadRequest, "invalid_grant", "Authorization code is invalid"}
+	}
+	return code, nil
+}
+
+func newIndieAuthResponse(sc *middleware.SubmarineContext, code *data.AuthorizationCode) indieAuthResponse {
+	response := indieAuthResponse{Me: indieAuthMe(sc)}
+	if code.HasScope(data.IndieAuthScopeProfile) {
+		response.Profile = &indieAuthProfile{Name: "Submarine", URL: response.Me}
+	}
+	return response
+}
+
+// indieAuthMe is the profile URL identifying the single user.
+func indieAuthMe(sc *middleware.SubmarineContext) string {
+	return requestBaseURL(sc) + "/"
+}
+
+func redirectAuthorizationError(sc *middleware.SubmarineContext, req data.AuthorizationRequest, code, description string) error {
+	return redirectAuthorization(sc, req, url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+}
+
+func redirectAuthorization(sc *middleware.SubmarineContext, req data.AuthorizationRequest, params url.Values) error {
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return err
+	}
+	query := redirectURI.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	query.Set("iss", indieAuthMe(sc))
+	redirectURI.RawQuery = query.Encode()
+	return sc.Redirect(http.StatusFound, redirectURI.String())
+}
+
+func renderAuthorize(sc *middleware.SubmarineContext, status int, tplData map[string]interface{}) error {
+	return sc.Render(status, "authorize.html", tplData)
+}
+
+func renderOAuthError(sc *middleware.SubmarineContext, status int, code, description string) error {
+	return sc.JSON(status, oauthError{Code: code, Description: description})
+}
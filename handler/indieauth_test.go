@@ -0,0 +1,349 @@
+package handler_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+var csrfInputPattern = regexp.MustCompile(`name="_csrf" value="([^"]+)"`)
+
+// indieAuthClient is a stand-in for a Micropub client which signs in with
+// IndieAuth, its callback exchanges the code for an access token.
+type indieAuthClient struct {
+	server   *httptest.Server
+	verifier string
+	token    map[string]interface{}
+	callback url.Values
+}
+
+func newIndieAuthClient(t *testing.T, tokenEndpoint string) *indieAuthClient {
+	client := &indieAuthClient{verifier: strings.Repeat("verifier", 8)}
+	client.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client.callback = r.URL.Query()
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		resp, err := http.PostForm(tokenEndpoint, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"client_id":     {client.ID()},
+			"redirect_uri":  {client.RedirectURI()},
+			"code_verifier": {client.verifier},
+		})
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		client.token = map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&client.token))
+		w.WriteHeader(resp.StatusCode)
+	}))
+	return client
+}
+
+func (c *indieAuthClient) ID() string {
+	return c.server.URL + "/"
+}
+
+func (c *indieAuthClient) RedirectURI() string {
+	return c.server.URL + "/callback"
+}
+
+func (c *indieAuthClient) AuthorizationURL(server, scope string) string {
+	hash := sha256.Sum256([]byte(c.verifier))
+	return server + "/auth?" + url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ID()},
+		"redirect_uri":          {c.RedirectURI()},
+		"state":                 {"the-state"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(hash[:])},
+		"code_challenge_method": {"S256"},
+		"scope":                 {scope},
+		"me":                    {server + "/"},
+	}.Encode()
+}
+
+func newBrowser(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &http.Client{Jar: jar}
+}
+
+// submitForm posts the form fields of a page including its CSRF token.
+func submitForm(t *testing.T, browser *http.Client, target string, page *http.Response, fields url.Values) *http.Response {
+	body, err := io.ReadAll(page.Body)
+	require.NoError(t, err)
+	page.Body.Close()
+	match := csrfInputPattern.FindSubmatch(body)
+	require.NotNil(t, match, string(body))
+	fields.Set("_csrf", string(match[1]))
+
+	resp, err := browser.PostForm(target, fields)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestIndieAuthFlow(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	require.NoError(t, data.NewSettingsRepository(db).Upsert(data.SettingsUpsert{Password: "secret"}))
+	server := httptest.NewServer(router.New(db))
+	defer server.Close()
+	client := newIndieAuthClient(t, server.URL+"/token")
+	defer client.server.Close()
+	browser := newBrowser(t)
+
+	// discovery
+	resp, err := http.Get(server.URL + "/.well-known/oauth-authorization-server")
+	require.NoError(t, err)
+	var metadata map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&metadata))
+	resp.Body.Close()
+	require.Equal(t, server.URL+"/", metadata["issuer"])
+	require.Equal(t, server.URL+"/auth", metadata["authorization_endpoint"])
+	require.Equal(t, server.URL+"/token", metadata["token_endpoint"])
+	require.Equal(t, []interface{}{"S256"}, metadata["code_challenge_methods_supported"])
+
+	// the authorization request needs a login first, which returns to the request
+	resp, err = browser.Get(client.AuthorizationURL(server.URL, "create profile"))
+	require.NoError(t, err)
+	require.Equal(t, "/login", resp.Request.URL.Path)
+	resp = submitForm(t, browser, server.URL+"/login", resp, url.Values{
+		"password": {"secret"},
+		"next":     {resp.Request.URL.Query().Get("next")},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "/auth", resp.Request.URL.Path)
+
+	// approving redirects to the client which exchanges the code
+	resp = submitForm(t, browser, server.URL+"/auth/confirm", resp, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID()},
+		"redirect_uri":          {client.RedirectURI()},
+		"state":                 {"the-state"},
+		"code_challenge":        {resp.Request.URL.Query().Get("code_challenge")},
+		"code_challenge_method": {"S256"},
+		"scope":                 {"create profile"},
+		"approved_scopes":       {"create", "profile"},
+		"decision":              {"approve"},
+	})
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "the-state", client.callback.Get("state"))
+	require.Equal(t, server.URL+"/", client.callback.Get("iss"))
+	require.Equal(t, server.URL+"/", client.token["me"])
+	require.Equal(t, "Bearer", client.token["token_type"])
+	require.Equal(t, "create profile", client.token["scope"])
+	require.Equal(t, map[string]interface{}{"name": "Submarine", "url": server.URL + "/"}, client.token["profile"])
+	accessToken := client.token["access_token"].(string)
+
+	tokens, err := data.NewTokenRepository(db).List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, client.ID(), tokens[0].ClientID)
+	require.Equal(t, "create", tokens[0].Scopes)
+
+	// the token can be used for Micropub
+	micropub := func() int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/micropub", strings.NewReader("h=entry&bookmark-of=https://example.com"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusCreated, micropub())
+
+	// but not to change bookmarks through the JSON API
+	bookmark, err := data.NewBookmarkRepository(db).GetByURL("https://example.com")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api/v1/bookmarks/%d", server.URL, bookmark.ID), nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// revoked tokens can't be used anymore
+	resp, err = http.PostForm(server.URL+"/token/revoke", url.Values{"token": {accessToken}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, http.StatusUnauthorized, micropub())
+}
+
+func TestAuthorizeHandlers(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	client := newIndieAuthClient(t, "")
+	client.server.Close()
+
+	session, err := data.NewSessionRepository(db).Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.AddCookie(&http.Cookie{Name: "SubmarineSessionToken", Value: session.Token})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// consent page
+	rec := get(client.AuthorizationURL("", "create read update"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Sign in to "+client.ID())
+	require.Contains(t, rec.Body.String(), `name="approved_scopes" value="create"`)
+	require.Contains(t, rec.Body.String(), `name="approved_scopes" value="read"`)
+	require.NotContains(t, rec.Body.String(), `value="update"`)
+	require.Contains(t, rec.Body.String(), `name="scope" value="create read"`)
+
+	// untrusted redirect URIs aren't redirected to
+	target := strings.Replace(client.AuthorizationURL("", "create"), url.QueryEscape(client.RedirectURI()), url.QueryEscape("https://evil.example/callback"), 1)
+	rec = get(target)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "Redirect URI must be on the same host as the client ID")
+
+	// other errors are redirected back to the client
+	target = strings.Replace(client.AuthorizationURL("", "create"), "code_challenge_method=S256", "code_challenge_method=plain", 1)
+	rec = get(target)
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, client.RedirectURI(), location.Scheme+"://"+location.Host+location.Path)
+	require.Equal(t, "invalid_request", location.Query().Get("error"))
+	require.Equal(t, "the-state", location.Query().Get("state"))
+
+	// denied
+	form := url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID()},
+		"redirect_uri":  {client.RedirectURI()},
+		"state":         {"the-state"},
+		"decision":      {"deny"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/confirm", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "SubmarineSessionToken", Value: session.Token})
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	// the approval form is CSRF protected
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/confirm", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	require.NoError(t, handler.AuthorizeHandler(sc))
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err = url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "access_denied", location.Query().Get("error"))
+	require.Empty(t, location.Query().Get("code"))
+}
+
+func TestTokenHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewIndieAuthRepository(db)
+
+	verifier := strings.Repeat("verifier", 8)
+	hash := sha256.Sum256([]byte(verifier))
+	createCode := func(scopes ...string) string {
+		code, err := repo.CreateCode(data.AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            "https://app.example/",
+			RedirectURI:         "https://app.example/callback",
+			State:               "the-state",
+			CodeChallenge:       base64.RawURLEncoding.EncodeToString(hash[:]),
+			CodeChallengeMethod: "S256",
+			Scopes:              scopes,
+		})
+		require.NoError(t, err)
+		return code
+	}
+	post := func(target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	exchange := func(target, code string) *httptest.ResponseRecorder {
+		return post(target, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"client_id":     {"https://app.example/"},
+			"redirect_uri":  {"https://app.example/callback"},
+			"code_verifier": {verifier},
+		})
+	}
+
+	// profile only codes are redeemed at the authorization endpoint
+	code := createCode(data.IndieAuthScopeProfile)
+	rec := exchange("/token", code)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.JSONEq(t, `{"error": "invalid_grant", "error_description": "Authorization code doesn't grant any token scopes"}`, rec.Body.String())
+
+	code = createCode(data.IndieAuthScopeProfile)
+	rec = exchange("/auth", code)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"me": "http://example.com/", "profile": {"name": "Submarine", "url": "http://example.com/"}}`, rec.Body.String())
+
+	// invalid codes
+	rec = exchange("/token", "invalid")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.JSONEq(t, `{"error": "invalid_grant", "error_description": "Authorization code is invalid"}`, rec.Body.String())
+
+	rec = post("/token", url.Values{"grant_type": {"password"}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"error":"unsupported_grant_type"`)
+
+	// tokens
+	code = createCode(data.IndieAuthScopeCreate, data.IndieAuthScopeRead)
+	rec = exchange("/token", code)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, "create read", response["scope"])
+	require.Nil(t, response["profile"])
+
+	token, err := data.NewTokenRepository(db).GetBySecret(response["access_token"].(string))
+	require.NoError(t, err)
+	require.True(t, token.HasScope(data.TokenScopeRead))
+	require.True(t, token.HasScope(data.TokenScopeCreate))
+	require.False(t, token.HasScope(data.TokenScopeWrite))
+
+	// legacy revocation
+	rec = post("/token", url.Values{"action": {"revoke"}, "token": {response["access_token"].(string)}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	token, err = data.NewTokenRepository(db).GetBySecret(response["access_token"].(string))
+	require.NoError(t, err)
+	require.Nil(t, token)
+
+	// unknown tokens are ignored
+	rec = post("/token/revoke", url.Values{"token": {"unknown"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
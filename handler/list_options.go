@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+type listOptions struct {
+	Action  string
+	Query   string
+	PerPage int
+	Sort    data.BookmarkSort
+	Sorts   []data.BookmarkSort
+
+	params url.Values
+}
+
+// parseListOptions reads the page size and sort order from the query
+// parameters, falling back to the defaults stored in settings.
+func parseListOptions(sc *middleware.SubmarineContext, action string) *listOptions {
+	opts := defaultListOptions(sc, action)
+	opts.parse(sc)
+	return opts
+}
+
+// parseSearchOptions is like parseListOptions but sorts by relevance unless
+// asked otherwise.
+func parseSearchOptions(sc *middleware.SubmarineContext, action, query string) *listOptions {
+	opts := defaultListOptions(sc, action)
+	opts.Query = query
+	opts.Sort = data.BookmarkSortRelevance
+	opts.Sorts = append([]data.BookmarkSort{data.BookmarkSortRelevance}, data.BookmarkSorts...)
+	opts.parse(sc)
+	return opts
+}
+
+func defaultListOptions(sc *middleware.SubmarineContext, action string) *listOptions {
+	opts := &listOptions{
+		Action:  action,
+		PerPage: data.DefaultPerPage,
+		Sort:    data.BookmarkSortNewest,
+		Sorts:   data.BookmarkSorts,
+		params:  url.Values{},
+	}
+
+	if sc.DB != nil {
+		settings, err := data.NewSettingsRepository(sc.DB).Get()
+		if err == nil && settings != nil {
+			if settings.PerPage != 0 {
+				opts.PerPage = data.ClampPerPage(settings.PerPage)
+			}
+			if settings.Sort != "" {
+				opts.Sort = settings.Sort
+			}
+		}
+	}
+
+	return opts
+}
+
+func (o *listOptions) parse(sc *middleware.SubmarineContext) {
+	perPage, err := strconv.Atoi(sc.QueryParam("per_page"))
+	if err == nil && perPage > 0 {
+		o.PerPage = data.ClampPerPage(perPage)
+		o.params.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+
+	sort, ok := data.ParseBookmarkSort(sc.QueryParam("sort"))
+	if ok && o.isValidSort(sort) {
+		o.Sort = sort
+		o.params.Set("sort", string(sort))
+	}
+}
+
+func (o *listOptions) PerPageOptions() []int {
+	return []int{10, 25, 50, data.MaxPerPage}
+}
+
+func (o *listOptions) isValidSort(sort data.BookmarkSort) bool {
+	for _, valid := range o.Sorts {
+		if sort == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// PaginationPathPrefix keeps explicitly requested options when paginating.
+func (o *listOptions) PaginationPathPrefix() string {
+	params := url.Values{}
+	if o.Query != "" {
+		params.Set("q", o.Query)
+	}
+	for key, values := range o.params {
+		params[key] = values
+	}
+
+	if len(params) == 0 {
+		return o.Action + "?"
+	}
+	return o.Action + "?" + params.Encode() + "&"
+}
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+type micropubError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type micropubPostType struct {
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	Properties []string `json:"properties"`
+}
+
+type micropubConfig struct {
+	Q           []string           `json:"q"`
+	SyndicateTo []string           `json:"syndicate-to"`
+	PostTypes   []micropubPostType `json:"post-types"`
+	Visibility  []string           `json:"visibility"`
+}
+
+type micropubSource struct {
+	Type       []string            `json:"type,omitempty"`
+	Properties map[string][]string `json:"properties"`
+}
+
+// micropubRequest is a form-encoded or JSON Micropub request, property values
+// are flattened to strings.
+type micropubRequest struct {
+	Type       string
+	Action     string
+	Properties url.Values
+}
+
+type micropubJSONRequest struct {
+	Type       []string                     `json:"type"`
+	Action     string                       `json:"action"`
+	Properties map[string][]json.RawMessage `json:"properties"`
+}
+
+// micropubReservedFields are form fields which aren't post properties.
+var micropubReservedFields = map[string]bool{
+	"h":            true,
+	"action":       true,
+	"url":          true,
+	"access_token": true,
+}
+
+// MicropubQueryHandler answers the "config" and "source" queries, the config
+// is available to any token.
+func MicropubQueryHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderMicropubError(sc, http.StatusUnauthorized, "unauthorized", "")
+	}
+
+	switch sc.QueryParam("q") {
+	case "config":
+		return sc.JSON(http.StatusOK, micropubConfig{
+			Q:           []string{"config", "source"},
+			SyndicateTo: []string{},
+			PostTypes: []micropubPostType{
+				{Type: "bookmark", Name: "Bookmark", Properties: []string{"bookmark-of", "name", "content", "category"}},
+			},
+			Visibility: []string{"public", "private"},
+		})
+	case "source":
+		if !sc.HasTokenScope(data.TokenScopeRead) {
+			return renderMicropubError(sc, http.StatusForbidden, "insufficient_scope", "Token is missing the read scope")
+		}
+		bookmark, err := getMicropubBookmark(sc, sc.QueryParam("url"))
+		if err != nil {
+			return renderMicropubError(sc, http.StatusInternalServerError, "server_error", "Failed to fetch bookmark")
+		}
+		if bookmark == nil {
+			return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "Post doesn't exist")
+		}
+		return sc.JSON(http.StatusOK, newMicropubSource(bookmark, micropubQueryProperties(sc)))
+	}
+
+	return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "Query isn't supported")
+}
+
+// MicropubHandler creates bookmarks from h-entry posts with a bookmark-of
+// property, other post types and actions aren't supported. Tokens need
+// either the write or the create scope.
+func MicropubHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderMicropubError(sc, http.StatusUnauthorized, "unauthorized", "")
+	}
+	if !sc.HasTokenScope(data.TokenScopeWrite) && !sc.HasTokenScope(data.TokenScopeCreate) {
+		return renderMicropubError(sc, http.StatusForbidden, "insufficient_scope", "Token is missing the create scope")
+	}
+
+	req, err := parseMicropubRequest(sc)
+	if err != nil {
+		return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object")
+	}
+	if req.Action != "" {
+		return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "Only creating posts is supported")
+	}
+	if req.Type != "h-entry" {
+		return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "Only h-entry posts are supported")
+	}
+
+	form := data.BookmarkForm{
+		URL:         req.Properties.Get("bookmark-of"),
+		Title:       req.Properties.Get("name"),
+		Description: req.Properties.Get("content"),
+		Public:      req.Properties.Get("visibility") == "public",
+		Tags:        strings.Join(req.Properties["category"], ","),
+	}
+	validationErr := form.IsValid()
+	if validationErr != nil {
+		return renderMicropubError(sc, http.StatusBadRequest, "invalid_request", "bookmark-of: "+validationErr.Fields["URL"])
+	}
+
+	bookmark, err := data.NewBookmarkRepository(sc.DB).Create(form)
+	if err != nil {
+		return renderMicropubError(sc, http.StatusInternalServerError, "server_error", "Failed to create bookmark")
+	}
+
+	sc.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("%s/bookmarks/%d", requestBaseURL(sc), bookmark.ID))
+	return sc.NoContent(http.StatusCreated)
+}
+
+func parseMicropubRequest(sc *middleware.SubmarineContext) (*micropubRequest, error) {
+	req := &micropubRequest{Properties: url.Values{}}
+
+	if strings.HasPrefix(sc.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var body micropubJSONRequest
+		err := json.NewDecoder(sc.Request().Body).Decode(&body)
+		if err != nil {
+			return nil, err
+		}
+		if len(body.Type) > 0 {
+			req.Type = body.Type[0]
+		}
+		req.Action = body.Action
+		for name, values := range body.Properties {
+			for _, value := range values {
+				if s, ok := micropubJSONValue(value); ok {
+					req.Properties.Add(name, s)
+				}
+			}
+		}
+		return req, nil
+	}
+
+	params, err := sc.FormParams()
+	if err != nil {
+		return nil, err
+	}
+	if h := params.Get("h"); h != "" {
+		req.Type = "h-" + h
+	}
+	req.Action = params.Get("action")
+	for name, values := range params {
+		if micropubReservedFields[name] {
+			continue
+		}
+		name = strings.TrimSuffix(name, "[]")
+		for _, value := range values {
+			req.Properties.Add(name, value)
+		}
+	}
+	return req, nil
+}
+
+// micropubJSONValue returns plain string values and the text of
+// {"value": …} or {"html": …} objects used for content.
+func micropubJSONValue(raw json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, true
+	}
+	var object struct {
+		Value string `json:"value"`
+		HTML  string `json:"html"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		if object.Value != "" {
+			return object.Value, true
+		}
+		return object.HTML, object.HTML != ""
+	}
+	return "", false
+}
+
+// getMicropubBookmark looks up a bookmark by the URL of its page.
+func getMicropubBookmark(sc *middleware.SubmarineContext, rawURL string) (*data.Bookmark, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil
+	}
+	path, found := strings.CutPrefix(parsed.Path, "/bookmarks/")
+	if !found {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(path)
+	if err != nil {
+		return nil, nil
+	}
+	return data.NewBookmarkRepository(sc.DB).Get(uint(id))
+}
+
+func micropubQueryProperties(sc *middleware.SubmarineContext) []string {
+	params := sc.QueryParams()
+	return append(params["properties"], params["properties[]"]...)
+}
+
+// newMicropubSource returns the properties of a bookmark, only the given
+// properties are returned when there are any.
+func newMicropubSource(bookmark *data.Bookmark, only []string) micropubSource {
+	visibility := "private"
+	if bookmark.IsPublic() {
+		visibility = "public"
+	}
+	categories := []string{}
+	for _, tag := range bookmark.Tags {
+		categories = append(categories, tag.DisplayName)
+	}
+	properties := map[string][]string{
+		"bookmark-of": {bookmark.URL},
+		"name":        {bookmark.Title},
+		"content":     {bookmark.Description},
+		"category":    categories,
+		"visibility":  {visibility},
+		"published":   {bookmark.CreatedAt.UTC().Format(time.RFC3339)},
+	}
+
+	if len(only) == 0 {
+		return micropubSource{Type: []string{"h-entry"}, Properties: properties}
+	}
+	filtered := map[string][]string{}
+	for _, name := range only {
+		if values, ok := properties[name]; ok {
+			filtered[name] = values
+		}
+	}
+	return micropubSource{Properties: filtered}
+}
+
+func renderMicropubError(sc *middleware.SubmarineContext, status int, code, description string) error {
+	return sc.JSON(status, micropubError{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,180 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func micropubRequest(e http.Handler, method, target, contentType, body, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMicropubAuthentication(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	tokenRepo := data.NewTokenRepository(db)
+	_, readSecret, err := tokenRepo.Create(data.TokenCreate{Name: "read", Scopes: []data.TokenScope{data.TokenScopeRead}})
+	require.NoError(t, err)
+
+	rec := micropubRequest(e, http.MethodGet, "/micropub?q=config", "", "", "")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.JSONEq(t, `{"error": "unauthorized"}`, rec.Body.String())
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=config", "", "", "invalid")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=config", "", "", readSecret)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	form := url.Values{"h": {"entry"}, "bookmark-of": {"https://example.com"}}
+	rec = micropubRequest(e, http.MethodPost, "/micropub", "application/x-www-form-urlencoded", form.Encode(), readSecret)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), `"error":"insufficient_scope"`)
+
+	// the token can also be passed in the body
+	_, writeSecret, err := tokenRepo.Create(data.TokenCreate{Name: "write", Scopes: []data.TokenScope{data.TokenScopeWrite}})
+	require.NoError(t, err)
+	form.Set("access_token", writeSecret)
+	rec = micropubRequest(e, http.MethodPost, "/micropub", "application/x-www-form-urlencoded", form.Encode(), "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	_, createSecret, err := tokenRepo.Create(data.TokenCreate{Name: "create", Scopes: []data.TokenScope{data.TokenScopeCreate}})
+	require.NoError(t, err)
+	form.Set("access_token", createSecret)
+	form.Set("bookmark-of", "https://example.org")
+	rec = micropubRequest(e, http.MethodPost, "/micropub", "application/x-www-form-urlencoded", form.Encode(), "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestMicropubCreate(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "micropub", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	repo := data.NewBookmarkRepository(db)
+
+	// form-encoded
+	form := url.Values{
+		"h":           {"entry"},
+		"bookmark-of": {"https://example.com"},
+		"name":        {"Example"},
+		"content":     {"An example"},
+		"category[]":  {"Go", "web"},
+		"visibility":  {"public"},
+	}
+	rec := micropubRequest(e, http.MethodPost, "/micropub", "application/x-www-form-urlencoded", form.Encode(), secret)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "http://example.com/bookmarks/1", rec.Header().Get("Location"))
+
+	bookmark, err := repo.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", bookmark.URL)
+	require.Equal(t, "Example", bookmark.Title)
+	require.Equal(t, "An example", bookmark.Description)
+	require.True(t, bookmark.IsPublic())
+	require.Len(t, bookmark.Tags, 2)
+
+	// JSON
+	body := `{
+		"type": ["h-entry"],
+		"properties": {
+			"bookmark-of": ["https://example.com/json"],
+			"name": ["JSON"],
+			"content": [{"html": "<p>Some HTML</p>"}],
+			"category": ["json"]
+		}
+	}`
+	rec = micropubRequest(e, http.MethodPost, "/micropub", "application/json", body, secret)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "http://example.com/bookmarks/2", rec.Header().Get("Location"))
+
+	bookmark, err = repo.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/json", bookmark.URL)
+	require.Equal(t, "<p>Some HTML</p>", bookmark.Description)
+	require.False(t, bookmark.IsPublic())
+	require.Equal(t, "json", bookmark.Tags[0].Name)
+
+	// invalid
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		description string
+	}{
+		{"application/x-www-form-urlencoded", "h=entry", "bookmark-of: URL is required"},
+		{"application/x-www-form-urlencoded", "h=entry&bookmark-of=example", "bookmark-of: URL format is invalid"},
+		{"application/x-www-form-urlencoded", "h=event&bookmark-of=https://example.com", "Only h-entry posts are supported"},
+		{"application/x-www-form-urlencoded", "action=delete&url=http://example.com/bookmarks/1", "Only creating posts is supported"},
+		{"application/json", "invalid", "Request body must be a JSON object"},
+	} {
+		rec = micropubRequest(e, http.MethodPost, "/micropub", tc.contentType, tc.body, secret)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.JSONEq(t, `{"error": "invalid_request", "error_description": "`+tc.description+`"}`, rec.Body.String())
+	}
+}
+
+func TestMicropubQuery(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "micropub", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{
+		URL:    "https://example.com",
+		Title:  "Example",
+		Public: true,
+		Tags:   "go",
+	})
+	require.NoError(t, err)
+
+	rec := micropubRequest(e, http.MethodGet, "/micropub?q=config", "", "", secret)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &config))
+	require.Equal(t, []interface{}{"config", "source"}, config["q"])
+	require.Equal(t, "bookmark", config["post-types"].([]interface{})[0].(map[string]interface{})["type"])
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=source&url=http://example.com/bookmarks/1", "", "", secret)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var source struct {
+		Type       []string
+		Properties map[string][]string
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &source))
+	require.Equal(t, []string{"h-entry"}, source.Type)
+	require.Equal(t, []string{"https://example.com"}, source.Properties["bookmark-of"])
+	require.Equal(t, []string{"Example"}, source.Properties["name"])
+	require.Equal(t, []string{"go"}, source.Properties["category"])
+	require.Equal(t, []string{"public"}, source.Properties["visibility"])
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=source&properties[]=name&properties[]=category&url=http://example.com/bookmarks/1", "", "", secret)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"properties": {"name": ["Example"], "category": ["go"]}}`, rec.Body.String())
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=source&url=http://example.com/bookmarks/2", "", "", secret)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = micropubRequest(e, http.MethodGet, "/micropub?q=syndicate-to", "", "", secret)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
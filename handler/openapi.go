@@ -0,0 +1,838 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+const (
+	openAPISecurityBearer   = "bearerAuth"
+	openAPISecurityCookie   = "cookieAuth"
+	openAPISecurityPinboard = "pinboardAuth"
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type openAPIParam struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      map[string]interface{}
+}
+
+type openAPIResponse struct {
+	Status      int
+	Description string
+	Body        interface{}
+}
+
+// openAPIOperation describes a JSON route, request and response bodies are
+// given as values of the types the handlers encode and decode. Routes which
+// take form-encoded bodies describe the fields as FormParams, ContentType
+// overrides the media type of responses for JSON formats like JSON Feed.
+type openAPIOperation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Security    string
+	Params      []openAPIParam
+	FormParams  []openAPIParam
+	RequestBody interface{}
+	ContentType string
+	Responses   []openAPIResponse
+}
+
+var openAPIIDParam = openAPIParam{
+	Name:        "id",
+	In:          "path",
+	Description: "ID of the bookmark",
+	Schema:      map[string]interface{}{"type": "integer"},
+}
+
+func openAPIQueryParam(name, description string) openAPIParam {
+	return openAPIParam{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      map[string]interface{}{"type": "string"},
+	}
+}
+
+func openAPIPathParam(name, description string) openAPIParam {
+	return openAPIParam{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Schema:      map[string]interface{}{"type": "string"},
+	}
+}
+
+func openAPIFormParam(name, description string, required bool) openAPIParam {
+	return openAPIParam{
+		Name:        name,
+		In:          "form",
+		Description: description,
+		Required:    required,
+		Schema:      map[string]interface{}{"type": "string"},
+	}
+}
+
+func openAPIOperations() []openAPIOperation {
+	sorts := []string{}
+	for _, sort := range data.BookmarkSorts {
+		sorts = append(sorts, string(sort))
+	}
+	unauthorized := openAPIResponse{http.StatusUnauthorized, "Missing or invalid credentials", apiError{}}
+	notFound := openAPIResponse{http.StatusNotFound, "Bookmark not found", apiError{}}
+	invalid := openAPIResponse{http.StatusUnprocessableEntity, "Invalid bookmark", apiValidationError{}}
+	perPage := openAPIParam{
+		Name:        "per_page",
+		In:          "query",
+		Description: "Number of bookmarks per page",
+		Schema:      map[string]interface{}{"type": "integer", "minimum": 1, "maximum": data.MaxPerPage},
+	}
+
+	operations := []openAPIOperation{
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/v1/bookmarks",
+			Summary:  "List bookmarks, newest first unless sorted otherwise",
+			Security: openAPISecurityBearer,
+			Params: []openAPIParam{
+				openAPIQueryParam("cursor", "Opaque cursor taken from the prev or next URL"),
+				perPage,
+				{
+					Name:        "sort",
+					In:          "query",
+					Description: "Sort order",
+					Schema:      map[string]interface{}{"type": "string", "enum": sorts},
+				},
+				openAPIQueryParam("tag", "Only list bookmarks with this tag"),
+				openAPIQueryParam("domain", "Only list bookmarks of this domain"),
+				{
+					Name:        "privacy",
+					In:          "query",
+					Description: "Only list public or private bookmarks",
+					Schema:      map[string]interface{}{"type": "string", "enum": []string{string(data.BookmarkPrivacyPublic), string(data.BookmarkPrivacyPrivate)}},
+				},
+				{
+					Name:        "untagged",
+					In:          "query",
+					Description: "Only list bookmarks without tags",
+					Schema:      map[string]interface{}{"type": "boolean"},
+				},
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "A page of bookmarks", apiBookmarkList{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/bookmarks",
+			Summary:     "Create a bookmark",
+			Security:    openAPISecurityBearer,
+			RequestBody: apiBookmarkForm{},
+			Responses: []openAPIResponse{
+				{http.StatusCreated, "The created bookmark", apiBookmark{}},
+				unauthorized,
+				invalid,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/v1/bookmarks/search",
+			Summary:  "Search bookmarks, by relevance unless sorted otherwise",
+			Security: openAPISecurityBearer,
+			Params: []openAPIParam{
+				openAPIQueryParam("q", "Search query"),
+				openAPIQueryParam("cursor", "Opaque cursor taken from the prev or next URL"),
+				perPage,
+				{
+					Name:        "sort",
+					In:          "query",
+					Description: "Sort order",
+					Schema:      map[string]interface{}{"type": "string", "enum": append([]string{string(data.BookmarkSortRelevance)}, sorts...)},
+				},
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "A page of matching bookmarks, searched for the suggestion when given", apiBookmarkSearchResult{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/v1/bookmarks/:id",
+			Summary:  "Get a bookmark",
+			Security: openAPISecurityBearer,
+			Params:   []openAPIParam{openAPIIDParam},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The bookmark", apiBookmark{}},
+				unauthorized,
+				notFound,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/bookmarks/:id",
+			Summary:     "Replace a bookmark",
+			Security:    openAPISecurityBearer,
+			Params:      []openAPIParam{openAPIIDParam},
+			RequestBody: apiBookmarkForm{},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The updated bookmark", apiBookmark{}},
+				unauthorized,
+				notFound,
+				invalid,
+			},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/api/v1/bookmarks/:id",
+			Summary:  "Delete a bookmark",
+			Security: openAPISecurityBearer,
+			Params:   []openAPIParam{openAPIIDParam},
+			Responses: []openAPIResponse{
+				{http.StatusNoContent, "The bookmark was deleted", nil},
+				unauthorized,
+				notFound,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/v1/tags",
+			Summary:  "List tags in use with their number of bookmarks",
+			Security: openAPISecurityBearer,
+			Responses: []openAPIResponse{
+				{http.StatusOK, "All tags in use", []apiTagCount{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/v1/tags/search",
+			Summary:  "Search tags",
+			Security: openAPISecurityBearer,
+			Params:   []openAPIParam{openAPIQueryParam("q", "Search query")},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "Matching tags", []apiTag{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/tags/suggest",
+			Summary:  "Suggest tags for autocompletion",
+			Security: openAPISecurityCookie,
+			Params:   []openAPIParam{openAPIQueryParam("q", "Search query")},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "Matching tags", []tagSuggestion{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/bookmarks/suggest",
+			Summary:  "Suggest bookmarks for autocompletion",
+			Security: openAPISecurityCookie,
+			Params:   []openAPIParam{openAPIQueryParam("q", "Search query")},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "Matching bookmarks", []bookmarkSuggestion{}},
+				unauthorized,
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/api/openapi.json",
+			Summary: "This OpenAPI document",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "OpenAPI document", map[string]interface{}{}},
+			},
+		},
+	}
+
+	operations = append(operations, openAPIFeedOperations()...)
+	operations = append(operations, openAPIPinboardOperations()...)
+	operations = append(operations, openAPIIndieAuthOperations()...)
+	return operations
+}
+
+func openAPIFeedOperations() []openAPIOperation {
+	tagParam := openAPIPathParam("name", "Name of the tag")
+	tokenParam := openAPIPathParam("token", "Feed token")
+	notFound := openAPIResponse{http.StatusNotFound, "Tag or feed token not found", nil}
+	suggestions := []interface{}{"", []string{}, []string{}, []string{}}
+
+	return []openAPIOperation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/feed.json",
+			Summary:     "JSON Feed of the newest bookmarks, private ones are included when logged in",
+			ContentType: "application/feed+json",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The feed", jsonFeed{}},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/tags/:name/feed.json",
+			Summary:     "JSON Feed of the newest bookmarks with a tag, private ones are included when logged in",
+			Params:      []openAPIParam{tagParam},
+			ContentType: "application/feed+json",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The feed", jsonFeed{}},
+				notFound,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/feeds/:token/feed.json",
+			Summary:     "JSON Feed of the newest bookmarks including private ones",
+			Params:      []openAPIParam{tokenParam},
+			ContentType: "application/feed+json",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The feed", jsonFeed{}},
+				notFound,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/feeds/:token/tags/:name/feed.json",
+			Summary:     "JSON Feed of the newest bookmarks with a tag including private ones",
+			Params:      []openAPIParam{tokenParam, tagParam},
+			ContentType: "application/feed+json",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The feed", jsonFeed{}},
+				notFound,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/search/suggestions",
+			Summary:     "OpenSearch suggestions for the address bar, empty when not logged in",
+			Security:    openAPISecurityCookie,
+			Params:      []openAPIParam{openAPIQueryParam("q", "Search query")},
+			ContentType: openSearchSuggestionsContentType,
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The query followed by the titles, URLs and visit URLs of matching bookmarks", suggestions},
+			},
+		},
+	}
+}
+
+// openAPIPinboardOperations describes the Pinboard v1 API with format=json,
+// without it the same values are encoded as XML.
+func openAPIPinboardOperations() []openAPIOperation {
+	format := openAPIParam{
+		Name:        "format",
+		In:          "query",
+		Description: "Respond with JSON instead of XML",
+		Required:    true,
+		Schema:      map[string]interface{}{"type": "string", "enum": []string{"json"}},
+	}
+	unauthorized := openAPIResponse{http.StatusUnauthorized, "Missing or invalid auth_token", pinboardResultCode{}}
+	readForbidden := openAPIResponse{http.StatusForbidden, "Token is missing the read scope", pinboardResultCode{}}
+	writeForbidden := openAPIResponse{http.StatusForbidden, "Token is missing the write scope", pinboardResultCode{}}
+	tagParam := openAPIQueryParam("tag", "Only list bookmarks with this tag, Pinboard allows up to three but only the first is used")
+
+	operations := []openAPIOperation{
+		{
+			Path:    "/v1/posts/add",
+			Summary: "Create a bookmark or replace the one with the same URL",
+			Params: []openAPIParam{
+				openAPIQueryParam("url", "URL of the bookmark"),
+				openAPIQueryParam("description", "Title of the bookmark"),
+				openAPIQueryParam("extended", "Description of the bookmark"),
+				openAPIQueryParam("tags", "Space or comma separated tags"),
+				openAPIQueryParam("shared", `"no" to make the bookmark private`),
+				openAPIQueryParam("replace", `"no" to keep an existing bookmark with the same URL`),
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, `Result code "done" or why the bookmark wasn't saved`, pinboardResultCode{}},
+				unauthorized,
+				writeForbidden,
+			},
+		},
+		{
+			Path:    "/v1/posts/delete",
+			Summary: "Delete the bookmark with a URL",
+			Params:  []openAPIParam{openAPIQueryParam("url", "URL of the bookmark")},
+			Responses: []openAPIResponse{
+				{http.StatusOK, `Result code "done" or "item not found"`, pinboardResultCode{}},
+				unauthorized,
+				writeForbidden,
+			},
+		},
+		{
+			Path:    "/v1/posts/get",
+			Summary: "Get the bookmark with a URL or the bookmarks created on a day, the most recent one by default",
+			Params: []openAPIParam{
+				openAPIQueryParam("url", "URL of the bookmark"),
+				openAPIQueryParam("dt", "Day in local time formatted as YYYY-MM-DD"),
+				tagParam,
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The matching bookmarks", pinboardPosts{}},
+				{http.StatusBadRequest, "Invalid date", pinboardResultCode{}},
+				unauthorized,
+				readForbidden,
+			},
+		},
+		{
+			Path:    "/v1/posts/recent",
+			Summary: "List the newest bookmarks",
+			Params: []openAPIParam{
+				tagParam,
+				{
+					Name:        "count",
+					In:          "query",
+					Description: "Number of bookmarks",
+					Schema:      map[string]interface{}{"type": "integer", "minimum": 1, "maximum": pinboardMaxRecent, "default": pinboardRecentCount},
+				},
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The newest bookmarks", pinboardPosts{}},
+				unauthorized,
+				readForbidden,
+			},
+		},
+		{
+			Path:    "/v1/posts/all",
+			Summary: "List all bookmarks, newest first",
+			Params: []openAPIParam{
+				tagParam,
+				{
+					Name:        "start",
+					In:          "query",
+					Description: "Number of bookmarks to skip",
+					Schema:      map[string]interface{}{"type": "integer", "minimum": 0},
+				},
+				{
+					Name:        "results",
+					In:          "query",
+					Description: "Maximum number of bookmarks",
+					Schema:      map[string]interface{}{"type": "integer", "minimum": 0},
+				},
+				{
+					Name:        "fromdt",
+					In:          "query",
+					Description: "Only list bookmarks created at or after this time",
+					Schema:      map[string]interface{}{"type": "string", "format": "date-time"},
+				},
+				{
+					Name:        "todt",
+					In:          "query",
+					Description: "Only list bookmarks created before this time",
+					Schema:      map[string]interface{}{"type": "string", "format": "date-time"},
+				},
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The bookmarks", []pinboardPost{}},
+				{http.StatusBadRequest, "Invalid date", pinboardResultCode{}},
+				unauthorized,
+				readForbidden,
+			},
+		},
+		{
+			Path:    "/v1/tags/get",
+			Summary: "List tags in use with their number of bookmarks",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "Number of bookmarks by tag", map[string]int64{}},
+				unauthorized,
+				readForbidden,
+			},
+		},
+		{
+			Path:    "/v1/tags/rename",
+			Summary: "Rename a tag, merging it into an existing one with the new name",
+			Params: []openAPIParam{
+				openAPIQueryParam("old", "Name of the tag"),
+				openAPIQueryParam("new", "New name of the tag"),
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, `Result "done" or why the tag wasn't renamed`, pinboardResult{}},
+				unauthorized,
+				writeForbidden,
+			},
+		},
+		{
+			Path:    "/v1/user/api_token",
+			Summary: "Get the secret of the token used for the request",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The token secret", pinboardResult{}},
+				unauthorized,
+				readForbidden,
+			},
+		},
+	}
+
+	// Pinboard clients use GET for every request
+	for i := range operations {
+		operations[i].Method = http.MethodGet
+		operations[i].Security = openAPISecurityPinboard
+		operations[i].Params = append(operations[i].Params, format)
+	}
+	return operations
+}
+
+func openAPIIndieAuthOperations() []openAPIOperation {
+	redeemParams := []openAPIParam{
+		openAPIFormParam("grant_type", `"authorization_code", the only supported grant type`, false),
+		openAPIFormParam("code", "Authorization code", true),
+		openAPIFormParam("client_id", "Client the code was issued to", true),
+		openAPIFormParam("redirect_uri", "Redirect URI of the authorization request", true),
+		openAPIFormParam("code_verifier", "PKCE code verifier, required when the authorization request had a code challenge", false),
+	}
+	invalidGrant := openAPIResponse{http.StatusBadRequest, "Invalid or unsupported grant", oauthError{}}
+	micropubUnauthorized := openAPIResponse{http.StatusUnauthorized, "Missing or invalid token", micropubError{}}
+	micropubForbidden := openAPIResponse{http.StatusForbidden, "Token is missing a scope", micropubError{}}
+	micropubInvalid := openAPIResponse{http.StatusBadRequest, "Invalid or unsupported request", micropubError{}}
+
+	return []openAPIOperation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/.well-known/oauth-authorization-server",
+			Summary: "IndieAuth authorization server metadata",
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The server metadata", indieAuthMetadata{}},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/auth",
+			Summary:    "Redeem an authorization code for the profile URL of the user",
+			FormParams: redeemParams,
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The profile URL", indieAuthResponse{}},
+				invalidGrant,
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/token",
+			Summary: "Redeem an authorization code for an access token",
+			FormParams: append(redeemParams,
+				openAPIFormParam("action", `"revoke" to revoke the token instead, like /token/revoke`, false),
+				openAPIFormParam("token", "Token to revoke with action=revoke", false),
+			),
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The access token and profile URL", indieAuthResponse{}},
+				invalidGrant,
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/token/revoke",
+			Summary:    "Revoke an access token, unknown tokens are ignored",
+			FormParams: []openAPIParam{openAPIFormParam("token", "Token to revoke", true)},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The token was revoked", nil},
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/micropub",
+			Summary:  "Micropub config, or with q=source the properties of a bookmark",
+			Security: openAPISecurityBearer,
+			Params: []openAPIParam{
+				{
+					Name:        "q",
+					In:          "query",
+					Description: "Query",
+					Required:    true,
+					Schema:      map[string]interface{}{"type": "string", "enum": []string{"config", "source"}},
+				},
+				openAPIQueryParam("url", "URL of the bookmark for q=source"),
+				openAPIQueryParam("properties[]", "Properties to include for q=source, all by default"),
+			},
+			Responses: []openAPIResponse{
+				{http.StatusOK, "The config, or the properties of the bookmark for q=source", micropubConfig{}},
+				micropubInvalid,
+				micropubUnauthorized,
+				micropubForbidden,
+			},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/micropub",
+			Summary:  "Create a bookmark from an h-entry with a bookmark-of property, JSON requests are accepted as well",
+			Security: openAPISecurityBearer,
+			FormParams: []openAPIParam{
+				openAPIFormParam("h", `"entry", the only supported type`, true),
+				openAPIFormParam("bookmark-of", "URL of the bookmark", true),
+				openAPIFormParam("name", "Title of the bookmark", false),
+				openAPIFormParam("content", "Description of the bookmark", false),
+				openAPIFormParam("category[]", "Tag, can be repeated", false),
+				openAPIFormParam("visibility", `"public" to make the bookmark public`, false),
+				openAPIFormParam("access_token", "Token, when not passed as bearer token", false),
+			},
+			Responses: []openAPIResponse{
+				{http.StatusCreated, "The bookmark was created, the Location header is its URL", nil},
+				micropubInvalid,
+				micropubUnauthorized,
+				micropubForbidden,
+			},
+		},
+	}
+}
+
+// openAPISchemaNames names the types which are shared through components.
+var openAPISchemaNames = map[reflect.Type]string{
+	reflect.TypeOf(apiBookmark{}):             "Bookmark",
+	reflect.TypeOf(apiBookmarkForm{}):         "BookmarkForm",
+	reflect.TypeOf(apiBookmarkList{}):         "BookmarkList",
+	reflect.TypeOf(apiBookmarkSearchResult{}): "BookmarkSearchResult",
+	reflect.TypeOf(apiTag{}):                  "Tag",
+	reflect.TypeOf(apiTagCount{}):             "TagCount",
+	reflect.TypeOf(tagSuggestion{}):           "TagSuggestion",
+	reflect.TypeOf(bookmarkSuggestion{}):      "BookmarkSuggestion",
+	reflect.TypeOf(apiValidationError{}):      "ValidationError",
+	reflect.TypeOf(apiError{}):                "Error",
+	reflect.TypeOf(jsonFeed{}):                "JSONFeed",
+	reflect.TypeOf(jsonFeedItem{}):            "JSONFeedItem",
+	reflect.TypeOf(pinboardPost{}):            "PinboardPost",
+	reflect.TypeOf(pinboardPosts{}):           "PinboardPosts",
+	reflect.TypeOf(pinboardResult{}):          "PinboardResult",
+	reflect.TypeOf(pinboardResultCode{}):      "PinboardResultCode",
+	reflect.TypeOf(indieAuthMetadata{}):       "IndieAuthMetadata",
+	reflect.TypeOf(indieAuthResponse{}):       "IndieAuthResponse",
+	reflect.TypeOf(oauthError{}):              "OAuthError",
+	reflect.TypeOf(micropubConfig{}):          "MicropubConfig",
+	reflect.TypeOf(micropubError{}):           "MicropubError",
+}
+
+// newOpenAPISpec builds the OpenAPI document of all JSON routes, schemas are
+// derived from the types encoded by the handlers.
+func newOpenAPISpec() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range openAPIOperations() {
+		operation := map[string]interface{}{
+			"summary": op.Summary,
+		}
+		if op.Security != "" {
+			operation["security"] = []map[string][]string{{op.Security: {}}}
+		}
+
+		if len(op.Params) > 0 {
+			params := []map[string]interface{}{}
+			for _, param := range op.Params {
+				params = append(params, map[string]interface{}{
+					"name":        param.Name,
+					"in":          param.In,
+					"description": param.Description,
+					"required":    param.Required || param.In == "path",
+					"schema":      param.Schema,
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if op.RequestBody != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					echo.MIMEApplicationJSON: map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(op.RequestBody), schemas),
+					},
+				},
+			}
+		}
+		if len(op.FormParams) > 0 {
+			properties := map[string]interface{}{}
+			required := []string{}
+			for _, param := range op.FormParams {
+				schema := map[string]interface{}{"description": param.Description}
+				for key, value := range param.Schema {
+					schema[key] = value
+				}
+				properties[param.Name] = schema
+				if param.Required {
+					required = append(required, param.Name)
+				}
+			}
+			schema := map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+			}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					echo.MIMEApplicationForm: map[string]interface{}{
+						"schema": schema,
+					},
+				},
+			}
+		}
+
+		contentType := echo.MIMEApplicationJSON
+		if op.ContentType != "" {
+			contentType = op.ContentType
+		}
+
+		responses := map[string]interface{}{}
+		for _, res := range op.Responses {
+			response := map[string]interface{}{
+				"description": res.Description,
+			}
+			if res.Body != nil {
+				response["content"] = map[string]interface{}{
+					contentType: map[string]interface{}{
+						"schema": openAPISchema(reflect.TypeOf(res.Body), schemas),
+					},
+				}
+			}
+			responses[strconv.Itoa(res.Status)] = response
+		}
+		operation["responses"] = responses
+
+		path := openAPIPath(op.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Submarine",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				openAPISecurityBearer: map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+				openAPISecurityCookie: map[string]interface{}{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": "SubmarineSessionToken",
+				},
+				openAPISecurityPinboard: map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "query",
+					"name":        "auth_token",
+					"description": "API token formatted as <user>:<secret>, the user is ignored",
+				},
+			},
+		},
+	}
+}
+
+func OpenAPIHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	return sc.JSON(http.StatusOK, newOpenAPISpec())
+}
+
+// openAPIPath turns echo path parameters like ":id" into "{id}".
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// openAPISchema describes t, named struct types are added to schemas and
+// referenced.
+func openAPISchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = openAPISchema(t.Elem(), schemas)
+		}
+		return schema
+	case reflect.Struct:
+		name, ok := openAPISchemaNames[t]
+		if !ok {
+			return openAPIObjectSchema(t, schemas)
+		}
+		if _, exists := schemas[name]; !exists {
+			schemas[name] = openAPIObjectSchema(t, schemas)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func openAPIObjectSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	openAPIAddFields(t, schemas, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func openAPIAddFields(t reflect.Type, schemas, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			openAPIAddFields(field.Type, schemas, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = openAPISchema(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
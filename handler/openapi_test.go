@@ -0,0 +1,143 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+// undocumentedRoutes are the routes which don't serve JSON, every other route
+// needs to be described in the OpenAPI document.
+var undocumentedRoutes = map[string]bool{
+	// HTML pages and forms
+	"GET /":                              true,
+	"GET /archive":                       true,
+	"GET /archive/:year":                 true,
+	"GET /archive/:year/:month":          true,
+	"GET /auth":                          true,
+	"POST /auth/confirm":                 true,
+	"GET /bookmarks/:id":                 true,
+	"GET /bookmarks/:id/edit":            true,
+	"POST /bookmarks/:id/edit":           true,
+	"POST /bookmarks/:id/delete":         true,
+	"GET /bookmarks/:id/visit":           true,
+	"GET /bookmarks/new":                 true,
+	"POST /bookmarks":                    true,
+	"GET /domains":                       true,
+	"GET /domains/:host":                 true,
+	"GET /login":                         true,
+	"POST /login":                        true,
+	"POST /login/2fa":                    true,
+	"POST /logout":                       true,
+	"GET /search":                        true,
+	"GET /settings":                      true,
+	"POST /settings/preferences":         true,
+	"POST /settings/password":            true,
+	"GET /settings/2fa":                  true,
+	"POST /settings/2fa":                 true,
+	"POST /settings/2fa/disable":         true,
+	"POST /settings/2fa/recovery-codes":  true,
+	"POST /settings/tokens":              true,
+	"POST /settings/tokens/:id/revoke":   true,
+	"POST /settings/feeds":               true,
+	"POST /settings/feeds/:id/revoke":    true,
+	"POST /settings/webhooks":            true,
+	"POST /settings/webhooks/:id/delete": true,
+	"GET /settings/sessions":             true,
+	"POST /settings/sessions/revoke":     true,
+	"POST /settings/sessions/:id/revoke": true,
+	"GET /stats":                         true,
+	"GET /tags/:name":                    true,
+	"GET /static/*":                      true,
+
+	// Atom, RSS and OpenSearch XML
+	"GET /feed.atom":                         true,
+	"GET /feed.rss":                          true,
+	"GET /tags/:name/feed.atom":              true,
+	"GET /tags/:name/feed.rss":               true,
+	"GET /feeds/:token/feed.atom":            true,
+	"GET /feeds/:token/tags/:name/feed.atom": true,
+	"GET /opensearch.xml":                    true,
+
+	// ActivityPub and WebFinger, described by their own specifications
+	"GET /.well-known/webfinger": true,
+	"GET /actor":                 true,
+	"POST /inbox":                true,
+	"GET /outbox":                true,
+	"GET /followers":             true,
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var spec struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+				Required   []string               `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &spec)
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", spec.OpenAPI)
+
+	// documented operations match the registered routes which aren't listed
+	// as HTML, XML or ActivityPub routes
+	documented := []string{}
+	for path, operations := range spec.Paths {
+		path = strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	registered := []string{}
+	for _, route := range e.Routes() {
+		// groups register catch-all routes for their middlewares
+		if strings.HasPrefix(route.Name, "github.com/labstack/echo") {
+			continue
+		}
+		if !undocumentedRoutes[route.Method+" "+route.Path] {
+			registered = append(registered, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(documented)
+	sort.Strings(registered)
+	require.Equal(t, registered, documented)
+
+	// schemas follow the encoded types
+	bookmark := spec.Components.Schemas["Bookmark"]
+	for _, name := range []string{"id", "url", "title", "description", "public", "tags", "visits", "createdAt", "updatedAt"} {
+		require.Contains(t, bookmark.Properties, name)
+	}
+	require.Equal(t, []string{"url"}, spec.Components.Schemas["BookmarkForm"].Required)
+	require.Contains(t, spec.Components.Schemas["TagCount"].Properties, "displayName")
+	require.Contains(t, spec.Components.Schemas["TagCount"].Properties, "count")
+	require.Contains(t, spec.Components.Schemas["ValidationError"].Properties, "fields")
+	require.Contains(t, spec.Components.Schemas["PinboardPost"].Properties, "href")
+	require.Contains(t, spec.Components.Schemas["PinboardPost"].Properties, "tags")
+	require.Contains(t, spec.Components.Schemas["JSONFeed"].Properties, "items")
+	require.Contains(t, spec.Components.Schemas["IndieAuthResponse"].Properties, "access_token")
+
+	// form-encoded bodies and JSON formats keep their media types
+	require.Contains(t, spec.Paths["/token"]["post"], "requestBody")
+	require.Contains(t, rec.Body.String(), `"application/x-www-form-urlencoded"`)
+	require.Contains(t, rec.Body.String(), `"application/feed+json"`)
+}
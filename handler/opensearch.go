@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+const (
+	openSearchContentType            = "application/opensearchdescription+xml"
+	openSearchSuggestionsContentType = "application/x-suggestions+json"
+)
+
+type openSearchImage struct {
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Type   string `xml:"type,attr"`
+	URL    string `xml:",chardata"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Method   string `xml:"method,attr,omitempty"`
+	Template string `xml:"template,attr"`
+}
+
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Image         openSearchImage `xml:"Image"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+// OpenSearchHandler describes the search so browsers can add it as search
+// engine.
+func OpenSearchHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	baseURL := requestBaseURL(sc)
+
+	body, err := xml.MarshalIndent(openSearchDescription{
+		ShortName:     "Submarine",
+		Description:   "Search bookmarks on " + sc.Request().Host,
+		InputEncoding: "UTF-8",
+		Image: openSearchImage{
+			Width:  16,
+			Height: 16,
+			Type:   "image/svg+xml",
+			URL:    baseURL + StaticAssetPath("logo.svg"),
+		},
+		URLs: []openSearchURL{
+			{Type: "text/html", Method: "get", Template: baseURL + "/search?q={searchTerms}"},
+			{Type: openSearchSuggestionsContentType, Method: "get", Template: baseURL + "/search/suggestions?q={searchTerms}"},
+			{Type: openSearchContentType, Rel: "self", Template: baseURL + "/opensearch.xml"},
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return sc.Blob(http.StatusOK, openSearchContentType+"; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// OpenSearchSuggestionsHandler suggests bookmark titles for the address bar
+// in the OpenSearch suggestions format: the query followed by completions,
+// descriptions and the URLs to visit the bookmarks. Search needs a login, so
+// there are no suggestions without one.
+func OpenSearchSuggestionsHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	query := sc.QueryParam("q")
+	completions := []string{}
+	descriptions := []string{}
+	urls := []string{}
+
+	if sc.IsAuthenticated() && strings.TrimSpace(query) != "" {
+		bookmarks, err := data.NewBookmarkRepository(sc.DB).Suggest(query, suggestLimit)
+		if err != nil {
+			return sc.JSONError(http.StatusInternalServerError, "Failed to fetch bookmarks.")
+		}
+		baseURL := requestBaseURL(sc)
+		for _, bookmark := range bookmarks {
+			completion := bookmark.Title
+			if completion == "" {
+				completion = bookmark.URL
+			}
+			completions = append(completions, completion)
+			descriptions = append(descriptions, bookmark.URL)
+			urls = append(urls, fmt.Sprintf("%s/bookmarks/%d/visit", baseURL, bookmark.ID))
+		}
+	}
+
+	return renderOpenSearchSuggestions(sc, []interface{}{query, completions, descriptions, urls})
+}
+
+func renderOpenSearchSuggestions(sc *middleware.SubmarineContext, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return sc.Blob(http.StatusOK, openSearchSuggestionsContentType+"; charset=utf-8", body)
+}
@@ -0,0 +1,86 @@
+package handler_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestOpenSearchHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/opensearchdescription+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var description struct {
+		XMLName   xml.Name `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+		ShortName string   `xml:"ShortName"`
+		URLs      []struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &description))
+	require.Equal(t, "Submarine", description.ShortName)
+	require.Len(t, description.URLs, 3)
+	require.Equal(t, "text/html", description.URLs[0].Type)
+	require.Equal(t, "http://example.com/search?q={searchTerms}", description.URLs[0].Template)
+	require.Equal(t, "application/x-suggestions+json", description.URLs[1].Type)
+	require.Equal(t, "http://example.com/search/suggestions?q={searchTerms}", description.URLs[1].Template)
+
+	// linked from every page
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Contains(t, rec.Body.String(), `<link rel="search" type="application/opensearchdescription+xml" title="Submarine" href="/opensearch.xml">`)
+}
+
+func TestOpenSearchSuggestionsHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+	_, err := repo.Create(data.BookmarkForm{URL: "https://go.dev", Title: "The Go Programming Language"})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://gobyexample.com"})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{URL: "https://www.rust-lang.org", Title: "Rust"})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/search/suggestions?q=go", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.OpenSearchSuggestionsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/x-suggestions+json; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `[
+		"go",
+		["The Go Programming Language", "https://gobyexample.com"],
+		["https://go.dev", "https://gobyexample.com"],
+		["http://example.com/bookmarks/1/visit", "http://example.com/bookmarks/2/visit"]
+	]`, rec.Body.String())
+
+	// no suggestions without a login
+	req = httptest.NewRequest(http.MethodGet, "/search/suggestions?q=go", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.OpenSearchSuggestionsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `["go", [], [], []]`, rec.Body.String())
+}
@@ -0,0 +1,437 @@
+package handler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+const (
+	pinboardUser         = "submarine"
+	pinboardRecentCount  = 15
+	pinboardMaxRecent    = 100
+	pinboardDateFormat   = "2006-01-02"
+	pinboardResultDone   = "done"
+	pinboardItemNotFound = "item not found"
+)
+
+type pinboardPost struct {
+	XMLName     xml.Name `xml:"post" json:"-"`
+	Href        string   `xml:"href,attr" json:"href"`
+	Description string   `xml:"description,attr" json:"description"`
+	Extended    string   `xml:"extended,attr" json:"extended"`
+	Meta        string   `xml:"meta,attr" json:"meta"`
+	Hash        string   `xml:"hash,attr" json:"hash"`
+	Time        string   `xml:"time,attr" json:"time"`
+	Shared      string   `xml:"shared,attr" json:"shared"`
+	ToRead      string   `xml:"toread,attr" json:"toread"`
+	Tags        string   `xml:"tag,attr" json:"tags"`
+}
+
+type pinboardPosts struct {
+	XMLName xml.Name       `xml:"posts" json:"-"`
+	User    string         `xml:"user,attr" json:"user"`
+	Date    string         `xml:"dt,attr,omitempty" json:"date"`
+	Posts   []pinboardPost `json:"posts"`
+}
+
+type pinboardTag struct {
+	Tag   string `xml:"tag,attr"`
+	Count int64  `xml:"count,attr"`
+}
+
+type pinboardTags struct {
+	XMLName xml.Name      `xml:"tags"`
+	Tags    []pinboardTag `xml:"tag"`
+}
+
+type pinboardResultCode struct {
+	XMLName xml.Name `xml:"result" json:"-"`
+	Code    string   `xml:"code,attr" json:"result_code"`
+}
+
+type pinboardResult struct {
+	XMLName xml.Name `xml:"result" json:"-"`
+	Result  string   `xml:",chardata" json:"result"`
+}
+
+func newPinboardPost(bookmark *data.Bookmark) pinboardPost {
+	// Pinboard separates tags with spaces, so whitespace within a tag name is
+	// replaced with underscores. The mapping is lossy, importing the post
+	// again creates a tag named with underscores.
+	tags := []string{}
+	for _, tag := range bookmark.Tags {
+		tags = append(tags, strings.Join(strings.Fields(tag.DisplayName), "_"))
+	}
+	shared := "no"
+	if bookmark.IsPublic() {
+		shared = "yes"
+	}
+	return pinboardPost{
+		Href:        bookmark.URL,
+		Description: bookmark.Title,
+		Extended:    bookmark.Description,
+		Meta:        md5Hex(bookmark.UpdatedAt.UTC().Format(time.RFC3339Nano)),
+		Hash:        md5Hex(bookmark.URL),
+		Time:        bookmark.CreatedAt.UTC().Format(time.RFC3339),
+		Shared:      shared,
+		ToRead:      "no",
+		Tags:        strings.Join(tags, " "),
+	}
+}
+
+func PinboardPostsAddHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeWrite) {
+		return renderPinboardForbidden(sc)
+	}
+
+	form := data.BookmarkForm{
+		URL:         sc.QueryParam("url"),
+		Title:       sc.QueryParam("description"),
+		Description: sc.QueryParam("extended"),
+		Public:      sc.QueryParam("shared") != "no",
+		Tags:        strings.Join(strings.Fields(strings.ReplaceAll(sc.QueryParam("tags"), ",", " ")), ","),
+	}
+	validationErr := form.IsValid()
+	if validationErr != nil {
+		return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: "missing url"})
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	existing, err := repo.GetByURL(form.URL)
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+	if existing != nil {
+		if sc.QueryParam("replace") == "no" {
+			return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: "item already exists"})
+		}
+		err = repo.Update(existing.ID, form)
+	} else {
+		_, err = repo.Create(form)
+	}
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+
+	return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: pinboardResultDone})
+}
+
+func PinboardPostsDeleteHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeWrite) {
+		return renderPinboardForbidden(sc)
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	bookmark, err := repo.GetByURL(sc.QueryParam("url"))
+	if err != nil || bookmark == nil {
+		return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: pinboardItemNotFound})
+	}
+	err = repo.Delete(bookmark.ID)
+	if err != nil {
+		return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: pinboardItemNotFound})
+	}
+
+	return renderPinboard(sc, http.StatusOK, pinboardResultCode{Code: pinboardResultDone})
+}
+
+// PinboardPostsGetHandler returns the bookmark with the given URL, or the
+// bookmarks created on the given date, which defaults to the most recent
+// date with any bookmarks.
+func PinboardPostsGetHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeRead) {
+		return renderPinboardForbidden(sc)
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	posts := pinboardPosts{User: pinboardUser, Posts: []pinboardPost{}}
+
+	if url := sc.QueryParam("url"); url != "" {
+		bookmark, err := repo.GetByURL(url)
+		if err != nil {
+			return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+		}
+		if bookmark != nil {
+			posts.Date = bookmark.CreatedAt.UTC().Format(time.RFC3339)
+			posts.Posts = append(posts.Posts, newPinboardPost(bookmark))
+		}
+		return renderPinboard(sc, http.StatusOK, posts)
+	}
+
+	req, ok := pinboardListRequest(sc)
+	if !ok {
+		return renderPinboard(sc, http.StatusOK, posts)
+	}
+
+	var day time.Time
+	if dt := sc.QueryParam("dt"); dt != "" {
+		var err error
+		day, err = time.ParseInLocation(pinboardDateFormat, dt, time.Local)
+		if err != nil {
+			return renderPinboard(sc, http.StatusBadRequest, pinboardResultCode{Code: "invalid date"})
+		}
+	} else {
+		req.PerPage = 1
+		latest, err := repo.List(req)
+		if err != nil {
+			return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+		}
+		if len(latest.Items) == 0 {
+			return renderPinboard(sc, http.StatusOK, posts)
+		}
+		createdAt := latest.Items[0].CreatedAt.In(time.Local)
+		day = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), 0, 0, 0, 0, time.Local)
+	}
+
+	req.CreatedFrom = day
+	req.CreatedUntil = day.AddDate(0, 0, 1)
+	bookmarks, err := listAllBookmarks(repo, req)
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+
+	posts.Date = day.Format(time.RFC3339)
+	for i := range bookmarks {
+		posts.Posts = append(posts.Posts, newPinboardPost(&bookmarks[i]))
+	}
+	return renderPinboard(sc, http.StatusOK, posts)
+}
+
+func PinboardPostsRecentHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeRead) {
+		return renderPinboardForbidden(sc)
+	}
+
+	posts := pinboardPosts{
+		User:  pinboardUser,
+		Date:  time.Now().UTC().Format(time.RFC3339),
+		Posts: []pinboardPost{},
+	}
+
+	req, ok := pinboardListRequest(sc)
+	if !ok {
+		return renderPinboard(sc, http.StatusOK, posts)
+	}
+	req.PerPage = pinboardRecentCount
+	if count, err := strconv.Atoi(sc.QueryParam("count")); err == nil && count > 0 {
+		req.PerPage = count
+		if count > pinboardMaxRecent {
+			req.PerPage = pinboardMaxRecent
+		}
+	}
+
+	result, err := data.NewBookmarkRepository(sc.DB).List(req)
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+
+	if len(result.Items) > 0 {
+		posts.Date = result.Items[0].CreatedAt.UTC().Format(time.RFC3339)
+	}
+	for i := range result.Items {
+		posts.Posts = append(posts.Posts, newPinboardPost(&result.Items[i]))
+	}
+	return renderPinboard(sc, http.StatusOK, posts)
+}
+
+func PinboardPostsAllHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeRead) {
+		return renderPinboardForbidden(sc)
+	}
+
+	posts := pinboardPosts{User: pinboardUser, Posts: []pinboardPost{}}
+	req, ok := pinboardListRequest(sc)
+	if !ok {
+		return renderPinboardAll(sc, posts)
+	}
+
+	for param, field := range map[string]*time.Time{"fromdt": &req.CreatedFrom, "todt": &req.CreatedUntil} {
+		if value := sc.QueryParam(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return renderPinboard(sc, http.StatusBadRequest, pinboardResultCode{Code: "invalid date"})
+			}
+			// created_at is stored with the local offset and compared as text
+			*field = parsed.In(time.Local)
+		}
+	}
+
+	bookmarks, err := listAllBookmarks(data.NewBookmarkRepository(sc.DB), req)
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+
+	if start, err := strconv.Atoi(sc.QueryParam("start")); err == nil && start > 0 {
+		if start > len(bookmarks) {
+			start = len(bookmarks)
+		}
+		bookmarks = bookmarks[start:]
+	}
+	if results, err := strconv.Atoi(sc.QueryParam("results")); err == nil && results >= 0 && results < len(bookmarks) {
+		bookmarks = bookmarks[:results]
+	}
+
+	for i := range bookmarks {
+		posts.Posts = append(posts.Posts, newPinboardPost(&bookmarks[i]))
+	}
+	return renderPinboardAll(sc, posts)
+}
+
+func PinboardTagsGetHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeRead) {
+		return renderPinboardForbidden(sc)
+	}
+
+	counts, err := data.NewTagRepository(sc.DB).Counts()
+	if err != nil {
+		return renderPinboard(sc, http.StatusInternalServerError, pinboardResultCode{Code: "something went wrong"})
+	}
+
+	if sc.QueryParam("format") == "json" {
+		tags := map[string]int64{}
+		for _, count := range counts {
+			tags[count.DisplayName] = count.Count
+		}
+		return sc.JSON(http.StatusOK, tags)
+	}
+
+	tags := pinboardTags{Tags: []pinboardTag{}}
+	for _, count := range counts {
+		tags.Tags = append(tags.Tags, pinboardTag{Tag: count.DisplayName, Count: count.Count})
+	}
+	return sc.XML(http.StatusOK, tags)
+}
+
+func PinboardTagsRenameHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeWrite) {
+		return renderPinboardForbidden(sc)
+	}
+
+	_, err := data.NewTagRepository(sc.DB).Rename(sc.QueryParam("old"), sc.QueryParam("new"))
+	if err != nil {
+		return renderPinboard(sc, http.StatusOK, pinboardResult{Result: err.Error()})
+	}
+
+	return renderPinboard(sc, http.StatusOK, pinboardResult{Result: pinboardResultDone})
+}
+
+// PinboardUserAPITokenHandler returns the secret of the token used for the
+// request, the stored token hash can't be turned back into a secret.
+func PinboardUserAPITokenHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return renderPinboardUnauthorized(sc)
+	}
+	if !sc.HasTokenScope(data.TokenScopeRead) {
+		return renderPinboardForbidden(sc)
+	}
+
+	_, secret, _ := strings.Cut(sc.QueryParam("auth_token"), ":")
+	return renderPinboard(sc, http.StatusOK, pinboardResult{Result: secret})
+}
+
+// pinboardListRequest builds the list request for the tag filter of Pinboard
+// requests, it returns false when a requested tag doesn't exist.
+func pinboardListRequest(sc *middleware.SubmarineContext) (data.BookmarkListRequest, bool) {
+	req := data.BookmarkListRequest{
+		Privacy: data.BookmarkPrivacyQueryAll,
+		Sort:    data.BookmarkSortNewest,
+	}
+
+	// Pinboard filters by up to three space separated tags, submarine only
+	// filters by one tag at a time
+	names := strings.Fields(sc.QueryParam("tag"))
+	if len(names) == 0 {
+		return req, true
+	}
+	tag, err := data.NewTagRepository(sc.DB).GetByName(names[0])
+	if err != nil || tag == nil {
+		return req, false
+	}
+	req.TagID = tag.ID
+	return req, true
+}
+
+// listAllBookmarks fetches every page of bookmarks matching req.
+func listAllBookmarks(repo *data.BookmarkRepository, req data.BookmarkListRequest) ([]data.Bookmark, error) {
+	bookmarks := []data.Bookmark{}
+	req.PerPage = data.MaxPerPage
+	req.PaginationPathPrefix = "?"
+	for {
+		result, err := repo.List(req)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, result.Items...)
+		if !result.HasNext {
+			return bookmarks, nil
+		}
+		req.Cursor = strings.TrimPrefix(result.NextURL, "?cursor=")
+	}
+}
+
+func renderPinboard(sc *middleware.SubmarineContext, status int, value interface{}) error {
+	if sc.QueryParam("format") == "json" {
+		return sc.JSON(status, value)
+	}
+	return sc.XML(status, value)
+}
+
+// renderPinboardAll renders posts as a plain JSON array, like Pinboard does
+// for posts/all.
+func renderPinboardAll(sc *middleware.SubmarineContext, posts pinboardPosts) error {
+	if sc.QueryParam("format") == "json" {
+		return sc.JSON(http.StatusOK, posts.Posts)
+	}
+	return sc.XML(http.StatusOK, posts)
+}
+
+func renderPinboardUnauthorized(sc *middleware.SubmarineContext) error {
+	return renderPinboard(sc, http.StatusUnauthorized, pinboardResultCode{Code: "authentication failed"})
+}
+
+func renderPinboardForbidden(sc *middleware.SubmarineContext) error {
+	return renderPinboard(sc, http.StatusForbidden, pinboardResultCode{Code: "token is missing the write scope"})
+}
+
+func md5Hex(value string) string {
+	hash := md5.Sum([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
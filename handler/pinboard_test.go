@@ -0,0 +1,300 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func pinboardRequest(e http.Handler, path string, params url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func pinboardParams(secret string, pairs ...string) url.Values {
+	params := url.Values{}
+	params.Set("auth_token", "user:"+secret)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		params.Set(pairs[i], pairs[i+1])
+	}
+	return params
+}
+
+func TestPinboardAuthentication(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	tokenRepo := data.NewTokenRepository(db)
+
+	rec := pinboardRequest(e, "/v1/posts/recent", url.Values{})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = pinboardRequest(e, "/v1/posts/recent", pinboardParams("invalid"))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	_, secret, err := tokenRepo.Create(data.TokenCreate{Name: "read-only", Scopes: []data.TokenScope{data.TokenScopeRead}})
+	require.NoError(t, err)
+
+	rec = pinboardRequest(e, "/v1/posts/recent", pinboardParams(secret))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = pinboardRequest(e, "/v1/posts/add", pinboardParams(secret, "url", "https://example.com"))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = pinboardRequest(e, "/v1/user/api_token", pinboardParams(secret, "format", "json"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"result": "`+secret+`"}`, rec.Body.String())
+
+	_, secret, err = tokenRepo.Create(data.TokenCreate{Name: "write-only", Scopes: []data.TokenScope{data.TokenScopeWrite}})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/v1/posts/get", "/v1/posts/recent", "/v1/posts/all", "/v1/tags/get", "/v1/user/api_token"} {
+		rec = pinboardRequest(e, path, pinboardParams(secret))
+		require.Equal(t, http.StatusForbidden, rec.Code, path)
+	}
+
+	rec = pinboardRequest(e, "/v1/posts/add", pinboardParams(secret, "url", "https://example.com"))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPinboardPosts(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "pinboard", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	// add
+	rec := pinboardRequest(e, "/v1/posts/add", pinboardParams(secret,
+		"url", "https://example.com",
+		"description", "Example",
+		"extended", "An example",
+		"tags", "go databases",
+		"shared", "no",
+	))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `<result code="done"></result>`)
+
+	bookmark, err := repo.GetByURL("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Example", bookmark.Title)
+	require.Equal(t, "An example", bookmark.Description)
+	require.False(t, bookmark.IsPublic())
+	require.Len(t, bookmark.Tags, 2)
+
+	// add without replacing
+	rec = pinboardRequest(e, "/v1/posts/add", pinboardParams(secret,
+		"url", "https://example.com",
+		"description", "Replaced",
+		"replace", "no",
+		"format", "json",
+	))
+	require.JSONEq(t, `{"result_code": "item already exists"}`, rec.Body.String())
+
+	// add replaces by default
+	rec = pinboardRequest(e, "/v1/posts/add", pinboardParams(secret,
+		"url", "https://example.com",
+		"description", "Replaced",
+		"tags", "go",
+		"format", "json",
+	))
+	require.JSONEq(t, `{"result_code": "done"}`, rec.Body.String())
+	bookmark, err = repo.GetByURL("https://example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Replaced", bookmark.Title)
+	require.True(t, bookmark.IsPublic())
+	require.Len(t, bookmark.Tags, 1)
+
+	_, err = repo.Create(data.BookmarkForm{URL: "https://example.org", Title: "Other", Tags: "databases"})
+	require.NoError(t, err)
+
+	// recent
+	rec = pinboardRequest(e, "/v1/posts/recent", pinboardParams(secret, "format", "json"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var recent struct {
+		User  string `json:"user"`
+		Posts []struct {
+			Href        string `json:"href"`
+			Description string `json:"description"`
+			Shared      string `json:"shared"`
+			Tags        string `json:"tags"`
+		} `json:"posts"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &recent)
+	require.NoError(t, err)
+	require.Len(t, recent.Posts, 2)
+	require.Equal(t, "https://example.org", recent.Posts[0].Href)
+	require.Equal(t, "no", recent.Posts[0].Shared)
+	require.Equal(t, "Replaced", recent.Posts[1].Description)
+	require.Equal(t, "yes", recent.Posts[1].Shared)
+	require.Equal(t, "go", recent.Posts[1].Tags)
+
+	rec = pinboardRequest(e, "/v1/posts/recent", pinboardParams(secret, "tag", "go", "count", "1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var recentXML struct {
+		Posts []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"post"`
+	}
+	err = xml.Unmarshal(rec.Body.Bytes(), &recentXML)
+	require.NoError(t, err)
+	require.Len(t, recentXML.Posts, 1)
+	require.Equal(t, "https://example.com", recentXML.Posts[0].Href)
+
+	// get
+	rec = pinboardRequest(e, "/v1/posts/get", pinboardParams(secret, "url", "https://example.org"))
+	require.Contains(t, rec.Body.String(), `href="https://example.org"`)
+	require.NotContains(t, rec.Body.String(), `href="https://example.com"`)
+
+	rec = pinboardRequest(e, "/v1/posts/get", pinboardParams(secret))
+	require.Contains(t, rec.Body.String(), `href="https://example.org"`)
+	require.Contains(t, rec.Body.String(), `href="https://example.com"`)
+
+	rec = pinboardRequest(e, "/v1/posts/get", pinboardParams(secret, "dt", "2000-01-01"))
+	require.NotContains(t, rec.Body.String(), "<post ")
+
+	// all
+	rec = pinboardRequest(e, "/v1/posts/all", pinboardParams(secret, "format", "json", "start", "1"))
+	var all []struct {
+		Href string `json:"href"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &all)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, "https://example.com", all[0].Href)
+
+	// delete
+	rec = pinboardRequest(e, "/v1/posts/delete", pinboardParams(secret, "url", "https://example.com", "format", "json"))
+	require.JSONEq(t, `{"result_code": "done"}`, rec.Body.String())
+	rec = pinboardRequest(e, "/v1/posts/delete", pinboardParams(secret, "url", "https://example.com", "format", "json"))
+	require.JSONEq(t, `{"result_code": "item not found"}`, rec.Body.String())
+}
+
+func TestPinboardPostsLocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("JST", 9*60*60)
+	defer func() { time.Local = local }()
+
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "pinboard", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	// example.com is created on 2023-01-01 locally but on 2022-12-31 in UTC
+	for url, createdAt := range map[string]time.Time{
+		"https://example.com": time.Date(2023, time.January, 1, 1, 0, 0, 0, time.Local),
+		"https://example.org": time.Date(2022, time.December, 31, 23, 0, 0, 0, time.Local),
+	} {
+		result := db.Create(&data.Bookmark{
+			URL:     url,
+			Privacy: data.BookmarkPrivacyPublic,
+			Model:   gorm.Model{CreatedAt: createdAt},
+		})
+		require.NoError(t, result.Error)
+	}
+
+	for _, params := range []url.Values{
+		pinboardParams(secret),
+		pinboardParams(secret, "dt", "2023-01-01"),
+	} {
+		rec := pinboardRequest(e, "/v1/posts/get", params)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `dt="2023-01-01T00:00:00+09:00"`)
+		require.Contains(t, rec.Body.String(), `href="https://example.com"`)
+		require.NotContains(t, rec.Body.String(), `href="https://example.org"`)
+	}
+
+	rec := pinboardRequest(e, "/v1/posts/all", pinboardParams(secret,
+		"format", "json",
+		"fromdt", "2022-12-31T15:00:00Z",
+		"todt", "2023-01-01T15:00:00Z",
+	))
+	var all []struct {
+		Href string `json:"href"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &all)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, "https://example.com", all[0].Href)
+}
+
+func TestPinboardPostsTagsWithSpaces(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "pinboard", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	_, err = repo.Create(data.BookmarkForm{URL: "https://example.com", Tags: "machine  learning, go"})
+	require.NoError(t, err)
+
+	rec := pinboardRequest(e, "/v1/posts/get", pinboardParams(secret, "url", "https://example.com", "format", "json"))
+	var posts struct {
+		Posts []struct {
+			Tags string `json:"tags"`
+		} `json:"posts"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &posts)
+	require.NoError(t, err)
+	require.Len(t, posts.Posts, 1)
+	require.ElementsMatch(t, []string{"machine_learning", "go"}, strings.Fields(posts.Posts[0].Tags))
+
+	rec = pinboardRequest(e, "/v1/posts/add", pinboardParams(secret,
+		"url", "https://example.org",
+		"tags", posts.Posts[0].Tags,
+	))
+	require.Equal(t, http.StatusOK, rec.Code)
+	bookmark, err := repo.GetByURL("https://example.org")
+	require.NoError(t, err)
+	require.Len(t, bookmark.Tags, 2)
+}
+
+func TestPinboardTags(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	e := router.New(db)
+	repo := data.NewBookmarkRepository(db)
+
+	_, secret, err := data.NewTokenRepository(db).Create(data.TokenCreate{Name: "pinboard", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	for _, tags := range []string{"golang, databases", "go"} {
+		_, err := repo.Create(data.BookmarkForm{URL: "https://example.com", Tags: tags})
+		require.NoError(t, err)
+	}
+
+	rec := pinboardRequest(e, "/v1/tags/get", pinboardParams(secret, "format", "json"))
+	require.JSONEq(t, `{"databases": 1, "go": 1, "golang": 1}`, rec.Body.String())
+
+	rec = pinboardRequest(e, "/v1/tags/get", pinboardParams(secret))
+	require.Contains(t, rec.Body.String(), `<tag tag="databases" count="1"></tag>`)
+
+	rec = pinboardRequest(e, "/v1/tags/rename", pinboardParams(secret, "old", "golang", "new", "go"))
+	require.Contains(t, rec.Body.String(), `<result>done</result>`)
+
+	rec = pinboardRequest(e, "/v1/tags/get", pinboardParams(secret, "format", "json"))
+	require.JSONEq(t, `{"databases": 1, "go": 2}`, rec.Body.String())
+
+	rec = pinboardRequest(e, "/v1/tags/rename", pinboardParams(secret, "old", "missing", "new", "go", "format", "json"))
+	require.JSONEq(t, `{"result": "tag with name missing not found"}`, rec.Body.String())
+}
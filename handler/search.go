@@ -2,8 +2,6 @@ package handler
 
 import (
 	"net/http"
-	"net/url"
-	"strconv"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/middleware"
@@ -22,29 +20,31 @@ func SearchHandler(c echo.Context) error {
 	var tags []data.Tag
 	var bookmarkResults *data.BookmarkSearchResponse
 
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
-	if err != nil {
-		offset = 0
-	}
-
 	query := sc.QueryParam("q")
-
-	params := url.Values{}
-	params.Add("q", query)
+	opts := parseSearchOptions(sc, "/search", query)
 
 	if query != "" {
 		tags, _ = tagRepo.Search(query)
 		bookmarkResults, _ = bookmarkRepo.Search(data.BookmarkSearchRequest{
 			Query:                query,
-			Offset:               offset,
-			PaginationPathPrefix: "/search?" + params.Encode() + "&",
+			Cursor:               sc.QueryParam("cursor"),
+			PerPage:              opts.PerPage,
+			Sort:                 opts.Sort,
+			PaginationPathPrefix: opts.PaginationPathPrefix(),
 		})
 	}
 
+	suggestion := ""
+	if bookmarkResults != nil {
+		suggestion = bookmarkResults.Suggestion
+	}
+
 	return sc.Render(http.StatusOK, "search.html", map[string]interface{}{
 		"query":      query,
+		"suggestion": suggestion,
 		"tags":       tags,
-		"hasResults": bookmarkResults != nil && bookmarkResults.Count > int64(0),
+		"hasResults": bookmarkResults != nil && len(bookmarkResults.Items) > 0,
 		"result":     bookmarkResults,
+		"options":    opts,
 	})
 }
@@ -71,6 +71,19 @@ func TestSearchHandler(t *testing.T) {
 	require.Contains(t, rec.Body.String(), "Matching Tags")
 	require.Contains(t, rec.Body.String(), "Matching Bookmarks")
 
+	// suggestion for typos
+	q = make(url.Values)
+	q.Set("q", "exmple")
+	req = httptest.NewRequest(http.MethodGet, "/search?"+q.Encode(), nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SearchHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "Did you mean")
+	require.Contains(t, rec.Body.String(), `href="/search?q=example"`)
+	require.Contains(t, rec.Body.String(), "Matching Bookmarks")
+
 	// unauthenticated
 	req = httptest.NewRequest(http.MethodGet, "/search", nil)
 	rec = httptest.NewRecorder()
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+func SettingsSessionsHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	tplData := map[string]interface{}{
+		"currentSessionID": sc.SessionID(),
+	}
+	sessions, err := data.NewSessionRepository(sc.DB).List()
+	if err != nil {
+		tplData["sessionError"] = "Failed to fetch sessions."
+	}
+	tplData["sessions"] = sessions
+
+	return sc.Render(http.StatusOK, "sessions.html", tplData)
+}
+
+// SettingsSessionRevokeHandler logs out a single session, revoking the
+// current session logs out right away.
+func SettingsSessionRevokeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+	err = data.NewSessionRepository(sc.DB).Delete(uint(id))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+
+	if uint(id) == sc.SessionID() {
+		middleware.ClearCookieSessionToken(sc)
+		return sc.Redirect(http.StatusFound, "/login")
+	}
+	return sc.Redirect(http.StatusFound, "/settings/sessions")
+}
+
+func SettingsSessionsRevokeAllHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	err := data.NewSessionRepository(sc.DB).DeleteAll()
+	if err != nil {
+		return err
+	}
+
+	middleware.ClearCookieSessionToken(sc)
+	return sc.Redirect(http.StatusFound, "/login")
+}
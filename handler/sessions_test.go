@@ -0,0 +1,127 @@
+package handler_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+// sessionRequest calls h as if the request was authenticated with session,
+// form is sent as request body when given.
+func sessionRequest(t *testing.T, db *gorm.DB, session *data.Session, method, target string, form url.Values, h echo.HandlerFunc, params ...string) *httptest.ResponseRecorder {
+	e := router.NewBaseApp(db)
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req := httptest.NewRequest(method, target, body)
+	if form != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if len(params) > 0 {
+		c.SetParamNames(params[0])
+		c.SetParamValues(params[1])
+	}
+	sc := test.NewAuthenticatedContext(c, db)
+	sc.Set("SessionID", session.ID)
+	require.NoError(t, h(sc))
+	return rec
+}
+
+func TestSettingsSessionsHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+	current, err := repo.Create(&data.SessionCreate{UserAgent: "Firefox", IP: "192.0.2.1"})
+	require.NoError(t, err)
+	_, err = repo.Create(&data.SessionCreate{UserAgent: "Safari", IP: "192.0.2.2"})
+	require.NoError(t, err)
+
+	rec := sessionRequest(t, db, current, http.MethodGet, "/settings/sessions", nil, handler.SettingsSessionsHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, "Firefox")
+	require.Contains(t, body, "192.0.2.1")
+	require.Contains(t, body, "Safari")
+	require.Contains(t, body, "192.0.2.2")
+	require.Equal(t, 1, strings.Count(body, "This session"))
+
+	// requires a login
+	e := router.NewBaseApp(db)
+	req := httptest.NewRequest(http.MethodGet, "/settings/sessions", nil)
+	rec = httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsSessionsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+}
+
+func TestSettingsSessionRevokeHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+	current, err := repo.Create(&data.SessionCreate{UserAgent: "Firefox"})
+	require.NoError(t, err)
+	other, err := repo.Create(&data.SessionCreate{UserAgent: "Safari"})
+	require.NoError(t, err)
+
+	// another session
+	id := fmt.Sprint(other.ID)
+	rec := sessionRequest(t, db, current, http.MethodPost, "/settings/sessions/"+id+"/revoke", nil, handler.SettingsSessionRevokeHandler, "id", id)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings/sessions", rec.Header().Get("Location"))
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+	session, err := repo.GetByToken(other.Token)
+	require.NoError(t, err)
+	require.Nil(t, session)
+
+	// unknown session
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/sessions/"+id+"/revoke", nil, handler.SettingsSessionRevokeHandler, "id", id)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// the current session logs out
+	id = fmt.Sprint(current.ID)
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/sessions/"+id+"/revoke", nil, handler.SettingsSessionRevokeHandler, "id", id)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/login", rec.Header().Get("Location"))
+	cookie := test.ParseCookie(t, rec.Header().Get("Set-Cookie"))
+	require.Empty(t, cookie["SubmarineSessionToken"])
+	session, err = repo.GetByToken(current.Token)
+	require.NoError(t, err)
+	require.Nil(t, session)
+}
+
+func TestSettingsSessionsRevokeAllHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+	current, err := repo.Create(&data.SessionCreate{UserAgent: "Firefox"})
+	require.NoError(t, err)
+	_, err = repo.Create(&data.SessionCreate{UserAgent: "Safari"})
+	require.NoError(t, err)
+
+	rec := sessionRequest(t, db, current, http.MethodPost, "/settings/sessions/revoke", nil, handler.SettingsSessionsRevokeAllHandler)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/login", rec.Header().Get("Location"))
+	cookie := test.ParseCookie(t, rec.Header().Get("Set-Cookie"))
+	require.Empty(t, cookie["SubmarineSessionToken"])
+
+	sessions, err := repo.List()
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
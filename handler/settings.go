@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/chdorner/submarine/activitypub"
+	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/middleware"
+	"github.com/chdorner/submarine/util"
 	"github.com/labstack/echo/v4"
 )
 
@@ -13,8 +18,150 @@ func SettingsHandler(c echo.Context) error {
 		return sc.RedirectToLogin()
 	}
 
-	return sc.Render(http.StatusOK, "settings.html", map[string]interface{}{
-		"scheme": c.Scheme(),
-		"host":   c.Request().Host,
+	return renderSettings(sc, map[string]interface{}{})
+}
+
+func SettingsPreferencesHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	perPage, err := strconv.Atoi(sc.FormValue("per_page"))
+	if err != nil || perPage <= 0 || perPage > data.MaxPerPage {
+		return renderSettings(sc, map[string]interface{}{
+			"preferencesError": "Bookmarks per page must be a number between 1 and 100.",
+		})
+	}
+	sort, ok := data.ParseBookmarkSort(sc.FormValue("sort"))
+	if !ok || sort == data.BookmarkSortRelevance {
+		return renderSettings(sc, map[string]interface{}{
+			"preferencesError": "Sort order is invalid.",
+		})
+	}
+
+	repo := data.NewSettingsRepository(sc.DB)
+	err = repo.Upsert(data.SettingsUpsert{
+		PerPage: perPage,
+		Sort:    sort,
 	})
+	if err != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"preferencesError": "Failed to save preferences.",
+		})
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
+
+// SettingsPasswordHandler changes the password, all other sessions are
+// logged out so that anyone who knew the old password loses access.
+func SettingsPasswordHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	req := data.PasswordChange{
+		Current:      sc.FormValue("current_password"),
+		New:          sc.FormValue("new_password"),
+		Confirmation: sc.FormValue("new_password_confirmation"),
+	}
+	validationErr := req.IsValid()
+	if validationErr != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"passwordValidationErrors": validationErr.Fields,
+		})
+	}
+
+	repo := data.NewSettingsRepository(sc.DB)
+	settings, err := repo.Get()
+	if err != nil || settings == nil {
+		return renderSettings(sc, map[string]interface{}{
+			"passwordError": "Failed to change password.",
+		})
+	}
+	if !util.ComparePassword(req.Current, settings.Password) {
+		return renderSettings(sc, map[string]interface{}{
+			"passwordValidationErrors": map[string]string{
+				"Current": "Current password is wrong",
+			},
+		})
+	}
+
+	err = repo.Upsert(data.SettingsUpsert{Password: req.New})
+	if err != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"passwordError": "Failed to change password.",
+		})
+	}
+	err = data.NewSessionRepository(sc.DB).DeleteOthers(sc.SessionID())
+	if err != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"passwordError": "Changed password, but failed to log out other sessions.",
+		})
+	}
+
+	return renderSettings(sc, map[string]interface{}{
+		"passwordChanged": true,
+	})
+}
+
+func renderSettings(sc *middleware.SubmarineContext, tplData map[string]interface{}) error {
+	perPage := data.DefaultPerPage
+	sort := data.BookmarkSortNewest
+	settings, err := data.NewSettingsRepository(sc.DB).Get()
+	if err == nil && settings != nil {
+		if settings.PerPage != 0 {
+			perPage = settings.PerPage
+		}
+		if settings.Sort != "" {
+			sort = settings.Sort
+		}
+		tplData["twoFactorEnabled"] = settings.TwoFactorEnabled()
+		tplData["recoveryCodeCount"] = settings.RecoveryCodeCount()
+	}
+
+	tplData["scheme"] = sc.Scheme()
+	tplData["host"] = sc.Request().Host
+	tplData["perPage"] = perPage
+	tplData["maxPerPage"] = data.MaxPerPage
+	tplData["sort"] = sort
+	tplData["sorts"] = data.BookmarkSorts
+	tplData["minPasswordLength"] = data.MinPasswordLength
+
+	tokens, err := data.NewTokenRepository(sc.DB).List()
+	if err != nil {
+		tplData["tokenError"] = "Failed to fetch tokens."
+	}
+	tplData["tokens"] = tokens
+	tplData["tokenScopes"] = data.TokenScopes
+	tplData["tokenExpiries"] = tokenExpiries
+
+	feedTokens, err := data.NewFeedTokenRepository(sc.DB).List()
+	if err != nil {
+		tplData["feedTokenError"] = "Failed to fetch feed tokens."
+	}
+	tplData["feedTokens"] = feedTokens
+
+	followers, err := data.NewActivityPubRepository(sc.DB).Followers()
+	if err != nil {
+		tplData["followersError"] = "Failed to fetch followers."
+	}
+	tplData["followers"] = followers
+	tplData["fediverseHandle"] = fmt.Sprintf("@%s@%s", activitypub.Username, sc.Request().Host)
+
+	webhookRepo := data.NewWebhookRepository(sc.DB)
+	webhooks, err := webhookRepo.List()
+	if err != nil {
+		tplData["webhookError"] = "Failed to fetch webhooks."
+	}
+	tplData["webhooks"] = webhooks
+	deliveries, err := webhookRepo.Deliveries(webhookDeliveryLogSize)
+	if err != nil {
+		tplData["webhookError"] = "Failed to fetch webhook deliveries."
+	}
+	tplData["webhookDeliveries"] = deliveries
+
+	return sc.Render(http.StatusOK, "settings.html", tplData)
 }
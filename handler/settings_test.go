@@ -3,12 +3,15 @@ package handler_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/handler"
 	"github.com/chdorner/submarine/router"
 	"github.com/chdorner/submarine/test"
+	"github.com/chdorner/submarine/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,3 +26,145 @@ func TestSettingsHandler(t *testing.T) {
 	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
 	require.True(t, strings.HasPrefix(rec.Result().Header.Get("Location"), "/login?next="))
 }
+
+func TestSettingsPreferencesHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+
+	contentType := "application/x-www-form-urlencoded"
+	e := router.NewBaseApp(db)
+
+	// success
+	form := url.Values{}
+	form.Add("per_page", "25")
+	form.Add("sort", "title")
+	req := httptest.NewRequest(http.MethodPost, "/settings/preferences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsPreferencesHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+
+	settings, err := repo.Get()
+	require.NoError(t, err)
+	require.Equal(t, 25, settings.PerPage)
+	require.Equal(t, data.BookmarkSortTitle, settings.Sort)
+
+	// page size above cap
+	form = url.Values{}
+	form.Add("per_page", "1000")
+	form.Add("sort", "title")
+	req = httptest.NewRequest(http.MethodPost, "/settings/preferences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsPreferencesHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "Bookmarks per page must be a number between 1 and 100.")
+
+	// invalid sort order
+	form = url.Values{}
+	form.Add("per_page", "10")
+	form.Add("sort", "relevance")
+	req = httptest.NewRequest(http.MethodPost, "/settings/preferences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsPreferencesHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.Contains(t, rec.Body.String(), "Sort order is invalid.")
+
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.Equal(t, 25, settings.PerPage)
+
+	// unauthenticated
+	req = httptest.NewRequest(http.MethodPost, "/settings/preferences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsPreferencesHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Result().StatusCode)
+	require.Equal(t, "/login", rec.Header().Get("Location"))
+}
+
+func TestSettingsPasswordHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+	sessionRepo := data.NewSessionRepository(db)
+	current, err := sessionRepo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	other, err := sessionRepo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	changePassword := func(current *data.Session, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/settings/password", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+		sc.Set("SessionID", current.ID)
+		err := handler.SettingsPasswordHandler(sc)
+		require.NoError(t, err)
+		return rec
+	}
+
+	// wrong current password
+	rec := changePassword(current, url.Values{
+		"current_password":          {"wrong"},
+		"new_password":              {"correct horse"},
+		"new_password_confirmation": {"correct horse"},
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Current password is wrong")
+
+	// invalid new password
+	rec = changePassword(current, url.Values{
+		"current_password":          {"secret"},
+		"new_password":              {"short"},
+		"new_password_confirmation": {"shorter"},
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "New password must be at least 8 characters long")
+	require.Contains(t, rec.Body.String(), "Passwords don&#39;t match")
+
+	settings, err := repo.Get()
+	require.NoError(t, err)
+	require.True(t, util.ComparePassword("secret", settings.Password))
+	sessions, err := sessionRepo.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	// success
+	rec = changePassword(current, url.Values{
+		"current_password":          {"secret"},
+		"new_password":              {"correct horse"},
+		"new_password_confirmation": {"correct horse"},
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Changed password, all other sessions were logged out.")
+
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.True(t, util.ComparePassword("correct horse", settings.Password))
+
+	session, err := sessionRepo.GetByToken(other.Token)
+	require.NoError(t, err)
+	require.Nil(t, session)
+	session, err = sessionRepo.GetByToken(current.Token)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+}
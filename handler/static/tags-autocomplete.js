@@ -0,0 +1,72 @@
+(function () {
+    var input = document.getElementById('bookmark-tags');
+    var list = document.getElementById('bookmark-tags-suggestions');
+    if (!input || !list) {
+        return;
+    }
+
+    var pending = null;
+
+    function currentTerm() {
+        var parts = input.value.split(',');
+        return parts[parts.length - 1].trim();
+    }
+
+    function choose(displayName) {
+        var parts = input.value.split(',');
+        parts[parts.length - 1] = ' ' + displayName;
+        input.value = parts.join(',').replace(/^\s+/, '') + ', ';
+        hide();
+        input.focus();
+    }
+
+    function hide() {
+        list.innerHTML = '';
+        list.hidden = true;
+    }
+
+    function render(tags) {
+        list.innerHTML = '';
+        if (tags.length === 0) {
+            hide();
+            return;
+        }
+        tags.forEach(function (tag) {
+            var item = document.createElement('li');
+            var link = document.createElement('a');
+            link.href = '#';
+            link.textContent = tag.displayName;
+            link.addEventListener('mousedown', function (event) {
+                event.preventDefault();
+                choose(tag.displayName);
+            });
+            item.appendChild(link);
+            list.appendChild(item);
+        });
+        list.hidden = false;
+    }
+
+    input.setAttribute('autocomplete', 'off');
+    input.addEventListener('input', function () {
+        var term = currentTerm();
+        if (pending) {
+            clearTimeout(pending);
+        }
+        if (term === '') {
+            hide();
+            return;
+        }
+        pending = setTimeout(function () {
+            fetch('/api/tags/suggest?q=' + encodeURIComponent(term), { credentials: 'same-origin' })
+                .then(function (response) { return response.ok ? response.json() : []; })
+                .then(render)
+                .catch(hide);
+        }, 150);
+    });
+    input.addEventListener('blur', hide);
+    input.addEventListener('keydown', function (event) {
+        if (event.key === 'Escape') {
+            hide();
+        }
+    });
+})();
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	statsPeriods = 12
+	statsTop     = 10
+)
+
+type statsPeriod struct {
+	Label string
+	Count int64
+}
+
+type statsSeries struct {
+	Periods []statsPeriod
+	Max     int64
+}
+
+func (s *statsSeries) add(label string, count int64) {
+	s.Periods = append(s.Periods, statsPeriod{Label: label, Count: count})
+	if count > s.Max {
+		s.Max = count
+	}
+}
+
+type statsPrivacy struct {
+	Public         int64
+	Private        int64
+	PublicPercent  int64
+	PrivatePercent int64
+}
+
+func StatsHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	bookmarkRepo := data.NewBookmarkRepository(sc.DB)
+	tagRepo := data.NewTagRepository(sc.DB)
+	renderError := func() error {
+		return sc.Render(http.StatusOK, "stats.html", map[string]interface{}{
+			"error": "Failed to fetch statistics.",
+		})
+	}
+
+	now := time.Now()
+	weeks, err := weeklyStats(bookmarkRepo, now)
+	if err != nil {
+		return renderError()
+	}
+	months, err := monthlyStats(bookmarkRepo, now)
+	if err != nil {
+		return renderError()
+	}
+
+	privacyCounts, err := bookmarkRepo.PrivacyCounts()
+	if err != nil {
+		return renderError()
+	}
+	privacy := statsPrivacy{
+		Public:  privacyCounts[data.BookmarkPrivacyPublic],
+		Private: privacyCounts[data.BookmarkPrivacyPrivate],
+	}
+	if total := privacy.Public + privacy.Private; total > 0 {
+		privacy.PublicPercent = privacy.Public * 100 / total
+		privacy.PrivatePercent = 100 - privacy.PublicPercent
+	}
+
+	topTags, err := tagRepo.TopTags(statsTop)
+	if err != nil {
+		return renderError()
+	}
+	topDomains, err := bookmarkRepo.DomainCounts(data.BookmarkPrivacyQueryAll)
+	if err != nil {
+		return renderError()
+	}
+	if len(topDomains) > statsTop {
+		topDomains = topDomains[:statsTop]
+	}
+
+	untaggedCount, err := bookmarkRepo.CountUntagged()
+	if err != nil {
+		return renderError()
+	}
+	untagged, err := bookmarkRepo.List(data.BookmarkListRequest{
+		Privacy:  data.BookmarkPrivacyQueryAll,
+		Untagged: true,
+		PerPage:  statsTop,
+	})
+	if err != nil {
+		return renderError()
+	}
+
+	usedOnce, err := tagRepo.UsedOnce()
+	if err != nil {
+		return renderError()
+	}
+
+	return sc.Render(http.StatusOK, "stats.html", map[string]interface{}{
+		"weeks":         weeks,
+		"months":        months,
+		"privacy":       privacy,
+		"topTags":       topTags,
+		"topDomains":    topDomains,
+		"untaggedCount": untaggedCount,
+		"untagged":      untagged.Items,
+		"usedOnce":      usedOnce,
+	})
+}
+
+// weeklyStats counts the bookmarks of the last weeks, including weeks
+// without any bookmarks, most recent week first.
+func weeklyStats(repo *data.BookmarkRepository, now time.Time) (*statsSeries, error) {
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	week := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, time.Local)
+	since := week.AddDate(0, 0, -7*(statsPeriods-1))
+
+	counts, err := repo.WeekCounts(data.BookmarkPrivacyQueryAll, since)
+	if err != nil {
+		return nil, err
+	}
+	byWeek := map[string]int64{}
+	for _, count := range counts {
+		byWeek[count.Week.Format("2006-01-02")] = count.Count
+	}
+
+	series := &statsSeries{}
+	for i := 0; i < statsPeriods; i++ {
+		series.add(week.Format("_2 Jan 2006"), byWeek[week.Format("2006-01-02")])
+		week = week.AddDate(0, 0, -7)
+	}
+	return series, nil
+}
+
+// monthlyStats counts the bookmarks of the last months, including months
+// without any bookmarks, most recent month first.
+func monthlyStats(repo *data.BookmarkRepository, now time.Time) (*statsSeries, error) {
+	counts, err := repo.MonthCounts(data.BookmarkPrivacyQueryAll)
+	if err != nil {
+		return nil, err
+	}
+	byMonth := map[string]int64{}
+	for _, count := range counts {
+		month := time.Date(count.Year, count.Month, 1, 0, 0, 0, 0, time.Local)
+		byMonth[month.Format("2006-01")] = count.Count
+	}
+
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	series := &statsSeries{}
+	for i := 0; i < statsPeriods; i++ {
+		series.add(month.Format("January 2006"), byMonth[month.Format("2006-01")])
+		month = month.AddDate(0, -1, 0)
+	}
+	return series, nil
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	for _, form := range []data.BookmarkForm{
+		{URL: "https://example.com/one", Tags: "golang, databases", Public: true},
+		{URL: "https://example.com/two", Tags: "golang"},
+		{URL: "https://other.org", Title: "Untagged bookmark"},
+	} {
+		_, err := repo.Create(form)
+		require.NoError(t, err)
+	}
+
+	e := router.NewBaseApp(db)
+
+	// redirects to login when logged out
+	req := httptest.NewRequest(http.MethodGet, "/stats", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+
+	err := handler.StatsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Header().Get("Location"), "/login?next="))
+
+	// renders statistics when logged in
+	req = httptest.NewRequest(http.MethodGet, "/stats", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+
+	err = handler.StatsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, `href="/tags/golang"`)
+	require.Contains(t, body, `href="/domains/example.com"`)
+	require.Contains(t, body, "Untagged bookmark")
+	require.Contains(t, body, "33%")
+	require.Contains(t, body, "67%")
+
+	usedOnce := body[strings.Index(body, "Tags Used Once"):]
+	require.Contains(t, usedOnce, `href="/tags/databases"`)
+	require.NotContains(t, usedOnce, `href="/tags/golang"`)
+}
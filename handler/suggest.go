@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+const suggestLimit = 10
+
+type tagSuggestion struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type bookmarkSuggestion struct {
+	ID    uint   `json:"id"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+func TagsSuggestHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	repo := data.NewTagRepository(sc.DB)
+	tags, err := repo.Search(sc.QueryParam("q"))
+	if err != nil {
+		return sc.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch tags.",
+		})
+	}
+
+	suggestions := []tagSuggestion{}
+	for i, tag := range tags {
+		if i == suggestLimit {
+			break
+		}
+		suggestions = append(suggestions, tagSuggestion{
+			Name:        tag.Name,
+			DisplayName: tag.DisplayName,
+		})
+	}
+
+	return sc.JSON(http.StatusOK, suggestions)
+}
+
+func BookmarksSuggestHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.JSONUnauthorized()
+	}
+
+	repo := data.NewBookmarkRepository(sc.DB)
+	bookmarks, err := repo.Suggest(sc.QueryParam("q"), suggestLimit)
+	if err != nil {
+		return sc.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch bookmarks.",
+		})
+	}
+
+	suggestions := []bookmarkSuggestion{}
+	for _, bookmark := range bookmarks {
+		suggestions = append(suggestions, bookmarkSuggestion{
+			ID:    bookmark.ID,
+			URL:   bookmark.URL,
+			Title: bookmark.Title,
+		})
+	}
+
+	return sc.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,110 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagsSuggestHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	_, err := repo.Create(data.BookmarkForm{
+		URL:  "https://kubernetes.io",
+		Tags: "Kubernetes, kubectl, toRead",
+	})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	// prefix
+	q := make(url.Values)
+	q.Set("q", "kube")
+	req := httptest.NewRequest(http.MethodGet, "/api/tags/suggest?"+q.Encode(), nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.TagsSuggestHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+	var suggestions []map[string]string
+	err = json.Unmarshal(rec.Body.Bytes(), &suggestions)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	require.ElementsMatch(t, []string{"Kubernetes", "kubectl"}, []string{
+		suggestions[0]["displayName"],
+		suggestions[1]["displayName"],
+	})
+
+	// no query
+	req = httptest.NewRequest(http.MethodGet, "/api/tags/suggest", nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.TagsSuggestHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	require.JSONEq(t, "[]", rec.Body.String())
+
+	// unauthenticated
+	req = httptest.NewRequest(http.MethodGet, "/api/tags/suggest?"+q.Encode(), nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.TagsSuggestHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, rec.Result().StatusCode)
+}
+
+func TestBookmarksSuggestHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewBookmarkRepository(db)
+
+	bookmark, err := repo.Create(data.BookmarkForm{
+		URL:   "https://kubernetes.io/docs",
+		Title: "Kubernetes Documentation",
+	})
+	require.NoError(t, err)
+	_, err = repo.Create(data.BookmarkForm{
+		URL:         "https://example.com",
+		Title:       "Example",
+		Description: "kubernetes only mentioned in the description",
+	})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+
+	q := make(url.Values)
+	q.Set("q", "kubern")
+	req := httptest.NewRequest(http.MethodGet, "/api/bookmarks/suggest?"+q.Encode(), nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.BookmarksSuggestHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+	var suggestions []map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &suggestions)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	require.Equal(t, float64(bookmark.ID), suggestions[0]["id"])
+	require.Equal(t, bookmark.URL, suggestions[0]["url"])
+	require.Equal(t, bookmark.Title, suggestions[0]["title"])
+
+	// unauthenticated
+	req = httptest.NewRequest(http.MethodGet, "/api/bookmarks/suggest?"+q.Encode(), nil)
+	rec = httptest.NewRecorder()
+	sc = test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.BookmarksSuggestHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, rec.Result().StatusCode)
+}
@@ -3,7 +3,6 @@ package handler
 import (
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/chdorner/submarine/data"
 	"github.com/chdorner/submarine/middleware"
@@ -23,19 +22,17 @@ func TagHandler(c echo.Context) error {
 	if sc.IsAuthenticated() {
 		privacy = data.BookmarkPrivacyQueryAll
 	}
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
-	if err != nil {
-		offset = 0
-	}
 
 	bookmarksRepo := data.NewBookmarkRepository(sc.DB)
+	opts := parseListOptions(sc, fmt.Sprintf("/tags/%s", tag.Name))
 	result, err := bookmarksRepo.List(data.BookmarkListRequest{
 		Privacy: privacy,
 		TagID:   tag.ID,
-		Order:   "created_at desc",
-		Offset:  offset,
+		Cursor:  sc.QueryParam("cursor"),
+		PerPage: opts.PerPage,
+		Sort:    opts.Sort,
 
-		PaginationPathPrefix: fmt.Sprintf("/tags/%s?", tag.Name),
+		PaginationPathPrefix: opts.PaginationPathPrefix(),
 	})
 	if err != nil {
 		return sc.Render(http.StatusOK, "tags_show.html", map[string]interface{}{
@@ -44,7 +41,8 @@ func TagHandler(c echo.Context) error {
 	}
 
 	return sc.Render(http.StatusOK, "tags_show.html", map[string]interface{}{
-		"tag":    tag,
-		"result": result,
+		"tag":     tag,
+		"result":  result,
+		"options": opts,
 	})
 }
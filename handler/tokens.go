@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+// tokenExpiries are the lifetimes in days offered when creating a token.
+var tokenExpiries = []int{30, 90, 365}
+
+func SettingsTokensCreateHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	req := data.TokenCreate{Name: sc.FormValue("name")}
+	form, err := sc.FormParams()
+	if err == nil {
+		for _, scope := range form["scopes"] {
+			req.Scopes = append(req.Scopes, data.TokenScope(scope))
+		}
+	}
+	if expires := sc.FormValue("expires"); expires != "" {
+		days, err := strconv.Atoi(expires)
+		if err != nil || days <= 0 {
+			return renderSettings(sc, map[string]interface{}{
+				"tokenError": "Expiry is invalid.",
+			})
+		}
+		expiresAt := time.Now().AddDate(0, 0, days)
+		req.ExpiresAt = &expiresAt
+	}
+
+	repo := data.NewTokenRepository(sc.DB)
+	token, secret, err := repo.Create(req)
+	if err != nil {
+		tplData := map[string]interface{}{
+			"tokenError": "Failed to create token.",
+		}
+		if validationErr, ok := err.(*data.ValidationError); ok {
+			tplData["tokenValidationErrors"] = validationErr.Fields
+		}
+		return renderSettings(sc, tplData)
+	}
+
+	// the secret can't be shown again, so render instead of redirecting
+	return renderSettings(sc, map[string]interface{}{
+		"newToken":       token,
+		"newTokenSecret": secret,
+	})
+}
+
+func SettingsTokenRevokeHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+	err = data.NewTokenRepository(sc.DB).Revoke(uint(id))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
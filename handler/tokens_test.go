@@ -0,0 +1,108 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestSettingsTokensCreateHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	contentType := "application/x-www-form-urlencoded"
+	e := router.NewBaseApp(db)
+
+	// unauthenticated
+	req := httptest.NewRequest(http.MethodPost, "/settings/tokens", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.SettingsTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	// success
+	form := url.Values{}
+	form.Add("name", "Backup script")
+	form.Add("scopes", "read")
+	form.Add("expires", "30")
+	req = httptest.NewRequest(http.MethodPost, "/settings/tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "won't be shown again")
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, "Backup script", tokens[0].Name)
+	require.Equal(t, "read", tokens[0].Scopes)
+	require.NotNil(t, tokens[0].ExpiresAt)
+
+	// validation errors
+	form = url.Values{}
+	form.Add("name", "")
+	req = httptest.NewRequest(http.MethodPost, "/settings/tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsTokensCreateHandler(sc)
+	require.NoError(t, err)
+	require.Contains(t, rec.Body.String(), "Name is required")
+	require.Contains(t, rec.Body.String(), "At least one scope is required")
+
+	tokens, err = repo.List()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+}
+
+func TestSettingsTokenRevokeHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewTokenRepository(db)
+
+	token, _, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	target := fmt.Sprintf("/settings/tokens/%d/revoke", token.ID)
+
+	// success
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(token.ID))
+	err = handler.SettingsTokenRevokeHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+
+	tokens, err := repo.List()
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	// not found
+	req = httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(token.ID))
+	err = handler.SettingsTokenRevokeHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/base64"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"rsc.io/qr"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+	"github.com/chdorner/submarine/util"
+)
+
+// totpIssuer is shown next to the account in authenticator apps.
+const totpIssuer = "submarine"
+
+// SettingsTwoFactorHandler starts the enrolment with a new secret, it is
+// only stored once a code from the authenticator app has been confirmed.
+func SettingsTwoFactorHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	settings, err := data.NewSettingsRepository(sc.DB).Get()
+	if err != nil {
+		return err
+	}
+	if settings != nil && settings.TwoFactorEnabled() {
+		return sc.Redirect(http.StatusFound, "/settings")
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return err
+	}
+	return renderTwoFactorEnrolment(sc, secret, map[string]interface{}{})
+}
+
+// SettingsTwoFactorEnableHandler enables two-factor authentication and shows
+// the recovery codes. All other sessions are logged out, as they might have
+// been started by someone who only knows the password.
+func SettingsTwoFactorEnableHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	secret := sc.FormValue("secret")
+	recoveryCodes, err := data.NewSettingsRepository(sc.DB).EnableTwoFactor(secret, sc.FormValue("code"))
+	if err != nil {
+		if validationErr, ok := err.(*data.ValidationError); ok {
+			return renderTwoFactorEnrolment(sc, secret, map[string]interface{}{
+				"validationErrors": validationErr.Fields,
+			})
+		}
+		return renderTwoFactorEnrolment(sc, secret, map[string]interface{}{
+			"error": "Failed to enable two-factor authentication.",
+		})
+	}
+
+	err = data.NewSessionRepository(sc.DB).DeleteOthers(sc.SessionID())
+	if err != nil {
+		return err
+	}
+
+	return sc.Render(http.StatusOK, "two_factor.html", map[string]interface{}{
+		"enabled":       true,
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func SettingsTwoFactorDisableHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	if !verifyTwoFactorPassword(sc) {
+		return renderSettings(sc, map[string]interface{}{
+			"twoFactorValidationErrors": map[string]string{
+				"Password": "Password is wrong",
+			},
+		})
+	}
+
+	err := data.NewSettingsRepository(sc.DB).DisableTwoFactor()
+	if err != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"twoFactorError": "Failed to disable two-factor authentication.",
+		})
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
+
+// SettingsRecoveryCodesHandler replaces the recovery codes, e.g. when most of
+// them have been used up.
+func SettingsRecoveryCodesHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	if !verifyTwoFactorPassword(sc) {
+		return renderSettings(sc, map[string]interface{}{
+			"twoFactorValidationErrors": map[string]string{
+				"Password": "Password is wrong",
+			},
+		})
+	}
+
+	recoveryCodes, err := data.NewSettingsRepository(sc.DB).RegenerateRecoveryCodes()
+	if err != nil {
+		return renderSettings(sc, map[string]interface{}{
+			"twoFactorError": "Failed to generate recovery codes.",
+		})
+	}
+
+	return sc.Render(http.StatusOK, "two_factor.html", map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// verifyTwoFactorPassword checks the password, which is required to weaken
+// or change two-factor authentication from an existing session.
+func verifyTwoFactorPassword(sc *middleware.SubmarineContext) bool {
+	settings, err := data.NewSettingsRepository(sc.DB).Get()
+	if err != nil || settings == nil {
+		return false
+	}
+	return util.ComparePassword(sc.FormValue("password"), settings.Password)
+}
+
+func renderTwoFactorEnrolment(sc *middleware.SubmarineContext, secret string, tplData map[string]interface{}) error {
+	code, err := qr.Encode(util.TOTPURI(secret, totpIssuer, sc.Request().Host), qr.M)
+	if err != nil {
+		return err
+	}
+	code.Scale = 4
+
+	tplData["secret"] = secret
+	// the image is generated by us, it's safe to use as a data URL
+	tplData["qrCode"] = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(code.PNG()))
+	return sc.Render(http.StatusOK, "two_factor.html", tplData)
+}
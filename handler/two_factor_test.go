@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+	"github.com/chdorner/submarine/util"
+)
+
+// parseRecoveryCodes returns the recovery codes shown on the page.
+func parseRecoveryCodes(t *testing.T, body string) []string {
+	match := regexp.MustCompile(`(?s)<pre>(.*)</pre>`).FindStringSubmatch(body)
+	require.Len(t, match, 2)
+	return strings.Fields(match[1])
+}
+
+func TestSettingsTwoFactorEnrolment(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+	sessionRepo := data.NewSessionRepository(db)
+	current, err := sessionRepo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+	other, err := sessionRepo.Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	rec := sessionRequest(t, db, current, http.MethodGet, "/settings", nil, handler.SettingsHandler)
+	require.Contains(t, rec.Body.String(), `href="/settings/2fa"`)
+
+	// enrolment page
+	rec = sessionRequest(t, db, current, http.MethodGet, "/settings/2fa", nil, handler.SettingsTwoFactorHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, `src="data:image/png;base64,`)
+	match := regexp.MustCompile(`name="secret" value="([A-Z2-7]+)"`).FindStringSubmatch(body)
+	require.Len(t, match, 2)
+	secret := match[1]
+
+	// wrong code
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/2fa", url.Values{
+		"secret": {secret},
+		"code":   {"000000"},
+	}, handler.SettingsTwoFactorEnableHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Code is wrong")
+	require.Contains(t, rec.Body.String(), secret)
+	settings, err := repo.Get()
+	require.NoError(t, err)
+	require.False(t, settings.TwoFactorEnabled())
+
+	// correct code
+	code, err := util.TOTPCode(secret, util.TOTPCounter(time.Now()))
+	require.NoError(t, err)
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/2fa", url.Values{
+		"secret": {secret},
+		"code":   {code},
+	}, handler.SettingsTwoFactorEnableHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body = rec.Body.String()
+	require.Contains(t, body, "Enabled two-factor authentication")
+	recoveryCodes := parseRecoveryCodes(t, body)
+	require.Len(t, recoveryCodes, data.RecoveryCodeCount)
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.True(t, settings.TwoFactorEnabled())
+	require.Equal(t, secret, settings.TOTPSecret)
+
+	// other sessions are logged out
+	session, err := sessionRepo.GetByToken(other.Token)
+	require.NoError(t, err)
+	require.Nil(t, session)
+	session, err = sessionRepo.GetByToken(current.Token)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+
+	// can't enrol twice
+	rec = sessionRequest(t, db, current, http.MethodGet, "/settings/2fa", nil, handler.SettingsTwoFactorHandler)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+
+	rec = sessionRequest(t, db, current, http.MethodGet, "/settings", nil, handler.SettingsHandler)
+	require.Contains(t, rec.Body.String(), "10 remaining recovery codes")
+
+	// requires a login
+	e := router.NewBaseApp(db)
+	req := httptest.NewRequest(http.MethodGet, "/settings/2fa", nil)
+	rec = httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsTwoFactorHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+}
+
+func TestSettingsTwoFactorManagement(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSettingsRepository(db)
+	err := repo.Upsert(data.SettingsUpsert{Password: "secret"})
+	require.NoError(t, err)
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	code, err := util.TOTPCode(secret, util.TOTPCounter(time.Now()))
+	require.NoError(t, err)
+	recoveryCodes, err := repo.EnableTwoFactor(secret, code)
+	require.NoError(t, err)
+	current, err := data.NewSessionRepository(db).Create(&data.SessionCreate{})
+	require.NoError(t, err)
+
+	// new recovery codes require the password
+	rec := sessionRequest(t, db, current, http.MethodPost, "/settings/2fa/recovery-codes", url.Values{
+		"password": {"wrong"},
+	}, handler.SettingsRecoveryCodesHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Password is wrong")
+
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/2fa/recovery-codes", url.Values{
+		"password": {"secret"},
+	}, handler.SettingsRecoveryCodesHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Generated new recovery codes")
+	regenerated := parseRecoveryCodes(t, rec.Body.String())
+	require.Len(t, regenerated, data.RecoveryCodeCount)
+	require.NotContains(t, regenerated, recoveryCodes[0])
+
+	// disabling requires the password
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/2fa/disable", url.Values{
+		"password": {"wrong"},
+	}, handler.SettingsTwoFactorDisableHandler)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Password is wrong")
+	settings, err := repo.Get()
+	require.NoError(t, err)
+	require.True(t, settings.TwoFactorEnabled())
+
+	rec = sessionRequest(t, db, current, http.MethodPost, "/settings/2fa/disable", url.Values{
+		"password": {"secret"},
+	}, handler.SettingsTwoFactorDisableHandler)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+	settings, err = repo.Get()
+	require.NoError(t, err)
+	require.False(t, settings.TwoFactorEnabled())
+}
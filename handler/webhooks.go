@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/middleware"
+)
+
+// webhookDeliveryLogSize is the number of deliveries shown in settings.
+const webhookDeliveryLogSize = 25
+
+func SettingsWebhooksCreateHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	repo := data.NewWebhookRepository(sc.DB)
+	_, err := repo.Create(data.WebhookForm{URL: sc.FormValue("url")})
+	if err != nil {
+		tplData := map[string]interface{}{
+			"webhookError": "Failed to create webhook.",
+			"webhookURL":   sc.FormValue("url"),
+		}
+		if validationErr, ok := err.(*data.ValidationError); ok {
+			tplData["webhookValidationErrors"] = validationErr.Fields
+		}
+		return renderSettings(sc, tplData)
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
+
+func SettingsWebhookDeleteHandler(c echo.Context) error {
+	sc := c.(*middleware.SubmarineContext)
+	if !sc.IsAuthenticated() {
+		return sc.RedirectToLogin()
+	}
+
+	id, err := strconv.Atoi(sc.Param("id"))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+	err = data.NewWebhookRepository(sc.DB).Delete(uint(id))
+	if err != nil {
+		return sc.RenderNotFound()
+	}
+
+	return sc.Redirect(http.StatusFound, "/settings")
+}
@@ -0,0 +1,124 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/handler"
+	"github.com/chdorner/submarine/router"
+	"github.com/chdorner/submarine/test"
+)
+
+func TestSettingsWebhooksCreateHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	contentType := "application/x-www-form-urlencoded"
+	e := router.NewBaseApp(db)
+
+	// unauthenticated
+	req := httptest.NewRequest(http.MethodPost, "/settings/webhooks", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewUnauthenticatedContext(e.NewContext(req, rec), db)
+	err := handler.SettingsWebhooksCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	// success
+	form := url.Values{}
+	form.Add("url", "https://example.com/hooks")
+	req = httptest.NewRequest(http.MethodPost, "/settings/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsWebhooksCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/settings", rec.Header().Get("Location"))
+
+	webhooks, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.Equal(t, "https://example.com/hooks", webhooks[0].URL)
+
+	// validation errors
+	form = url.Values{}
+	form.Add("url", "not a url")
+	req = httptest.NewRequest(http.MethodPost, "/settings/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", contentType)
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsWebhooksCreateHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "URL must be an http or https URL")
+
+	webhooks, err = repo.List()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+}
+
+func TestSettingsWebhookDeliveryLog(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	_, err := data.NewWebhookRepository(db).Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	req := httptest.NewRequest(http.MethodGet, "/settings", nil)
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	err = handler.SettingsHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "https://example.com/hooks")
+	require.Contains(t, rec.Body.String(), "Recent Deliveries")
+	require.Contains(t, rec.Body.String(), "bookmark.created")
+}
+
+func TestSettingsWebhookDeleteHandler(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	webhook, err := repo.Create(data.WebhookForm{URL: "https://example.com/hooks"})
+	require.NoError(t, err)
+
+	e := router.NewBaseApp(db)
+	target := fmt.Sprintf("/settings/webhooks/%d/delete", webhook.ID)
+
+	// success
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	sc := test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(webhook.ID))
+	err = handler.SettingsWebhookDeleteHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	webhooks, err := repo.List()
+	require.NoError(t, err)
+	require.Empty(t, webhooks)
+
+	// not found
+	req = httptest.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	sc = test.NewAuthenticatedContext(e.NewContext(req, rec), db)
+	sc.SetParamNames("id")
+	sc.SetParamValues(fmt.Sprint(webhook.ID))
+	err = handler.SettingsWebhookDeleteHandler(sc)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/chdorner/submarine/data"
@@ -12,11 +13,15 @@ func CookieAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		sc := c.(*SubmarineContext)
 
-		token := getCookieSessionToken(sc)
+		token := GetCookieSessionToken(sc)
 		if token != "" {
 			repo := data.NewSessionRepository(sc.DB)
 			session, err := repo.GetByToken(token)
-			if err == nil && session != nil {
+			if err == nil && session != nil && !session.IsExpired() {
+				// failing to record the usage shouldn't fail the request
+				if session.NeedsTouch() && repo.Touch(session) == nil {
+					SetCookieSessionToken(sc, session.Token, session.ExpiresAt)
+				}
 				sc.Set("SessionID", session.ID)
 				sc.Set("IsAuthenticated", true)
 			}
@@ -31,7 +36,120 @@ func CookieAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-func getCookieSessionToken(c echo.Context) string {
+// TokenAuthMiddleware authenticates requests with an API token passed as
+// "Authorization: Bearer <secret>", session cookies are ignored so that
+// routes using it don't need CSRF protection. Tokens need the read scope for
+// safe methods and the write scope for everything else.
+func TokenAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sc := c.(*SubmarineContext)
+
+		token := authenticateToken(sc, getBearerToken(sc))
+		if token != nil && !token.HasScope(requiredTokenScope(sc.Request().Method)) {
+			return sc.JSONError(http.StatusForbidden, "Token is missing the required scope")
+		}
+
+		return next(sc)
+	}
+}
+
+// PinboardAuthMiddleware authenticates requests with an API token passed as
+// Pinboard style "auth_token=<user>:<secret>" query parameter. Pinboard
+// clients use GET for every request, handlers need to check scopes
+// themselves.
+func PinboardAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sc := c.(*SubmarineContext)
+
+		_, secret, _ := strings.Cut(sc.QueryParam("auth_token"), ":")
+		authenticateToken(sc, secret)
+
+		return next(sc)
+	}
+}
+
+// MicropubAuthMiddleware authenticates requests with an API token passed as
+// "Authorization: Bearer <secret>" or, as allowed by the Micropub spec, as
+// "access_token" form field. Handlers need to check scopes themselves.
+func MicropubAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sc := c.(*SubmarineContext)
+
+		secret := getBearerToken(sc)
+		if secret == "" && sc.Request().Method == http.MethodPost {
+			secret = sc.FormValue("access_token")
+		}
+		authenticateToken(sc, secret)
+
+		return next(sc)
+	}
+}
+
+// FeedTokenAuthMiddleware authenticates requests with a feed token passed as
+// the "token" path parameter. Feed tokens only grant access to the private
+// feeds, unknown tokens are treated like unknown pages.
+func FeedTokenAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sc := c.(*SubmarineContext)
+		sc.Set("SessionID", nil)
+		sc.Set("IsAuthenticated", false)
+
+		repo := data.NewFeedTokenRepository(sc.DB)
+		token, err := repo.GetBySecret(sc.Param("token"))
+		if err != nil || token == nil {
+			return sc.RenderNotFound()
+		}
+
+		// failing to record the usage shouldn't fail the request
+		_ = repo.Touch(token.ID)
+
+		sc.Set("FeedToken", token)
+		return next(sc)
+	}
+}
+
+// authenticateToken replaces any session authentication with the token
+// matching secret, nil is returned when there is no valid token.
+func authenticateToken(sc *SubmarineContext, secret string) *data.Token {
+	sc.Set("SessionID", nil)
+	sc.Set("IsAuthenticated", false)
+	if secret == "" {
+		return nil
+	}
+
+	repo := data.NewTokenRepository(sc.DB)
+	token, err := repo.GetBySecret(secret)
+	if err != nil || token == nil || token.IsExpired() {
+		return nil
+	}
+
+	// failing to record the usage shouldn't fail the request
+	_ = repo.Touch(token.ID)
+
+	sc.Set("Token", token)
+	sc.Set("TokenID", token.ID)
+	sc.Set("IsAuthenticated", true)
+	return token
+}
+
+func requiredTokenScope(method string) data.TokenScope {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return data.TokenScopeRead
+	}
+	return data.TokenScopeWrite
+}
+
+func getBearerToken(c echo.Context) string {
+	scheme, token, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// GetCookieSessionToken returns the session token of the request, if any.
+func GetCookieSessionToken(c echo.Context) string {
 	cookie, err := c.Cookie("SubmarineSessionToken")
 	if err != nil {
 		return ""
@@ -40,11 +158,11 @@ func getCookieSessionToken(c echo.Context) string {
 	return cookie.Value
 }
 
-func SetCookieSessionToken(c echo.Context, token string) {
+func SetCookieSessionToken(c echo.Context, token string, expiresAt time.Time) {
 	setCookie(c, &http.Cookie{
 		Name:    "SubmarineSessionToken",
 		Value:   token,
-		Expires: time.Now().Add(14 * 24 * time.Hour),
+		Expires: expiresAt,
 	})
 }
 
@@ -56,7 +174,10 @@ func ClearCookieSessionToken(c echo.Context) {
 	})
 }
 
+// setCookie scopes cookies to the whole site, browsers would otherwise limit
+// them to the directory of the URL which set them, e.g. /login/2fa.
 func setCookie(c echo.Context, cookie *http.Cookie) {
+	cookie.Path = "/"
 	cookie.SameSite = http.SameSiteLaxMode
 	cookie.Secure = c.Scheme() == "https"
 	c.SetCookie(cookie)
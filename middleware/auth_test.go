@@ -47,6 +47,187 @@ func TestCookieAuthMiddleware(t *testing.T) {
 	require.Equal(t, session.ID, actualID)
 }
 
+func TestCookieAuthMiddlewareExpiry(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewSessionRepository(db)
+
+	e := echo.New()
+	var actualAuthenticated bool
+	handler := func(c echo.Context) error {
+		actualAuthenticated = c.(*middleware.SubmarineContext).IsAuthenticated()
+		return c.String(http.StatusOK, "OK")
+	}
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "SubmarineSessionToken", Value: token})
+		rec := httptest.NewRecorder()
+		sc := middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+		err := middleware.CookieAuthMiddleware(handler)(sc)
+		require.NoError(t, err)
+		return rec
+	}
+
+	// expired sessions aren't accepted
+	result := db.Create(&data.Session{Token: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, result.Error)
+	request("expired")
+	require.False(t, actualAuthenticated)
+
+	// using a session extends it and its cookie
+	lastSeen := time.Now().Add(-time.Hour)
+	result = db.Create(&data.Session{Token: "active", LastSeenAt: lastSeen, ExpiresAt: lastSeen.Add(data.SessionLifetime)})
+	require.NoError(t, result.Error)
+	rec := request("active")
+	require.True(t, actualAuthenticated)
+	session, err := repo.GetByToken("active")
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), session.LastSeenAt, time.Second)
+	cookie := test.ParseCookie(t, rec.Header().Get("Set-Cookie"))
+	require.Equal(t, "active", cookie["SubmarineSessionToken"])
+	require.WithinDuration(t, session.ExpiresAt, cookie["Expires"].(time.Time), time.Second)
+
+	// but not on every request
+	rec = request("active")
+	require.True(t, actualAuthenticated)
+	require.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestTokenAuthMiddleware(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	repo := data.NewTokenRepository(db)
+	token, secret, err := repo.Create(data.TokenCreate{Name: "scripts", Scopes: data.TokenScopes})
+	require.NoError(t, err)
+
+	e := echo.New()
+	var actualID interface{}
+	var actualAuthenticated bool
+	handler := func(c echo.Context) error {
+		sc := c.(*middleware.SubmarineContext)
+		actualID = sc.Get("TokenID")
+		actualAuthenticated = sc.IsAuthenticated()
+		return c.String(http.StatusOK, "OK")
+	}
+
+	// valid token
+	req := httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+	sc := middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.True(t, actualAuthenticated)
+	require.Equal(t, token.ID, actualID)
+	token, err = repo.GetBySecret(secret)
+	require.NoError(t, err)
+	require.NotNil(t, token.LastUsedAt)
+
+	// invalid token
+	req = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.False(t, actualAuthenticated)
+
+	// expired token
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, expiringSecret, err := repo.Create(data.TokenCreate{Name: "expiring", Scopes: data.TokenScopes, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	expiredAt := time.Now().Add(-time.Hour)
+	result := db.Model(expiring).UpdateColumn("expires_at", expiredAt)
+	require.NoError(t, result.Error)
+
+	req = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer "+expiringSecret)
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.False(t, actualAuthenticated)
+
+	// missing scope
+	_, readSecret, err := repo.Create(data.TokenCreate{Name: "read-only", Scopes: []data.TokenScope{data.TokenScopeRead}})
+	require.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer "+readSecret)
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.True(t, actualAuthenticated)
+
+	actualAuthenticated = false
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer "+readSecret)
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.False(t, actualAuthenticated)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// session cookies are ignored
+	req = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+	sc.Set("IsAuthenticated", true)
+
+	err = middleware.TokenAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.False(t, actualAuthenticated)
+}
+
+func TestPinboardAuthMiddleware(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+
+	repo := data.NewTokenRepository(db)
+	token, secret, err := repo.Create(data.TokenCreate{Name: "pinboard", Scopes: []data.TokenScope{data.TokenScopeRead}})
+	require.NoError(t, err)
+
+	e := echo.New()
+	var actualID interface{}
+	var actualAuthenticated bool
+	var actualWrite bool
+	handler := func(c echo.Context) error {
+		sc := c.(*middleware.SubmarineContext)
+		actualID = sc.Get("TokenID")
+		actualAuthenticated = sc.IsAuthenticated()
+		actualWrite = sc.HasTokenScope(data.TokenScopeWrite)
+		return c.String(http.StatusOK, "OK")
+	}
+
+	// valid token
+	req := httptest.NewRequest(http.MethodGet, "/?auth_token=user:"+secret, bytes.NewReader(nil))
+	rec := httptest.NewRecorder()
+	sc := middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.PinboardAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.True(t, actualAuthenticated)
+	require.False(t, actualWrite)
+	require.Equal(t, token.ID, actualID)
+
+	// missing user
+	req = httptest.NewRequest(http.MethodGet, "/?auth_token="+secret, bytes.NewReader(nil))
+	rec = httptest.NewRecorder()
+	sc = middleware.InitSubmarineContext(e.NewContext(req, rec), db)
+
+	err = middleware.PinboardAuthMiddleware(handler)(sc)
+	require.NoError(t, err)
+	require.False(t, actualAuthenticated)
+}
+
 func TestSetCookieSessionToken(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil))
@@ -54,7 +235,7 @@ func TestSetCookieSessionToken(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	handler := func(c echo.Context) error {
-		middleware.SetCookieSessionToken(c, "the-session-token")
+		middleware.SetCookieSessionToken(c, "the-session-token", time.Now().Add(time.Hour))
 		return c.String(http.StatusOK, "Successfully logged-in")
 	}
 
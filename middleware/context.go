@@ -4,9 +4,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
 )
 
 type SubmarineContext struct {
@@ -37,20 +40,44 @@ func (sc *SubmarineContext) IsAuthenticated() bool {
 	return isAuthenticated.(bool)
 }
 
+// SessionID returns the ID of the session the request was authenticated
+// with, 0 for requests authenticated otherwise.
+func (sc *SubmarineContext) SessionID() uint {
+	id, _ := sc.Get("SessionID").(uint)
+	return id
+}
+
+// HasTokenScope reports whether the request was authenticated with an API
+// token which has the given scope.
+func (sc *SubmarineContext) HasTokenScope(scope data.TokenScope) bool {
+	token, ok := sc.Get("Token").(*data.Token)
+	return ok && token.HasScope(scope)
+}
+
 func (sc *SubmarineContext) RedirectToLogin() error {
 	redirect := "/login"
 	if sc.Request().Method == http.MethodGet {
-		next := sc.Path()
-		query := sc.QueryString()
-		if query != "" {
-			next = fmt.Sprintf("%s?%s", next, query)
-		}
+		next := sc.Request().URL.RequestURI()
 		nextEncoded := base64.StdEncoding.EncodeToString([]byte(next))
-		redirect = fmt.Sprintf("%s?next=%s", redirect, nextEncoded)
+		redirect = fmt.Sprintf("%s?next=%s", redirect, url.QueryEscape(nextEncoded))
 	}
 	return sc.Redirect(http.StatusFound, redirect)
 }
 
+func (sc *SubmarineContext) JSONUnauthorized() error {
+	return sc.JSONError(http.StatusUnauthorized, "Unauthorized")
+}
+
+func (sc *SubmarineContext) JSONNotFound() error {
+	return sc.JSONError(http.StatusNotFound, "Not Found")
+}
+
+func (sc *SubmarineContext) JSONError(status int, msg string) error {
+	return sc.JSON(status, map[string]string{
+		"error": msg,
+	})
+}
+
 func (sc *SubmarineContext) RenderNotFound() error {
 	return sc.Render(http.StatusNotFound, "404.html", nil)
 }
@@ -2,6 +2,7 @@ package router
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
@@ -46,12 +47,28 @@ func NewBaseApp(db *gorm.DB) *echo.Echo {
 	e.Use(echomiddleware.CSRFWithConfig(echomiddleware.CSRFConfig{
 		TokenLookup:    "form:_csrf",
 		CookieSameSite: http.SameSiteLaxMode,
+		Skipper:        isTokenAuthenticated,
 	}))
 	e.Use(middleware.CookieAuthMiddleware)
 
 	return e
 }
 
+// isTokenAuthenticated skips CSRF protection for routes which ignore session
+// cookies and authenticate with API tokens, HTTP signatures or IndieAuth
+// codes instead.
+func isTokenAuthenticated(c echo.Context) bool {
+	path := c.Request().URL.Path
+	switch path {
+	case "/inbox", "/micropub", "/token", "/token/revoke":
+		return true
+	case "/auth":
+		// clients redeem codes with POST, the approval form posts to /auth/confirm
+		return c.Request().Method == http.MethodPost
+	}
+	return path == "/api/v1" || strings.HasPrefix(path, "/api/v1/")
+}
+
 func New(db *gorm.DB) *echo.Echo {
 	e := NewBaseApp(db)
 
@@ -60,18 +77,100 @@ func New(db *gorm.DB) *echo.Echo {
 	e.GET("/bookmarks/:id/edit", handler.BookmarkEditViewHandler)
 	e.POST("/bookmarks/:id/edit", handler.BookmarkEditHandler)
 	e.GET("/bookmarks/:id", handler.BookmarkShowHandler)
+	e.GET("/bookmarks/:id/visit", handler.BookmarkVisitHandler)
 	e.GET("/bookmarks/new", handler.BookmarksNewHandler)
 	e.POST("/bookmarks", handler.BookmarksCreateHandler)
 
+	e.GET("/feed.atom", handler.AtomFeedHandler)
+	e.GET("/feed.rss", handler.RSSFeedHandler)
+	e.GET("/feed.json", handler.JSONFeedHandler)
+
 	e.GET("/tags/:name", handler.TagHandler)
+	e.GET("/tags/:name/feed.atom", handler.AtomFeedHandler)
+	e.GET("/tags/:name/feed.rss", handler.RSSFeedHandler)
+	e.GET("/tags/:name/feed.json", handler.JSONFeedHandler)
+
+	feeds := e.Group("/feeds/:token", middleware.FeedTokenAuthMiddleware)
+	feeds.GET("/feed.atom", handler.PrivateAtomFeedHandler)
+	feeds.GET("/feed.json", handler.PrivateJSONFeedHandler)
+	feeds.GET("/tags/:name/feed.atom", handler.PrivateAtomFeedHandler)
+	feeds.GET("/tags/:name/feed.json", handler.PrivateJSONFeedHandler)
+
+	e.GET("/domains", handler.DomainsHandler)
+	e.GET("/domains/:host", handler.DomainHandler)
+
+	e.GET("/archive", handler.ArchiveHandler)
+	e.GET("/archive/:year", handler.ArchiveHandler)
+	e.GET("/archive/:year/:month", handler.ArchiveHandler)
 
 	e.GET("/search", handler.SearchHandler)
+	e.GET("/search/suggestions", handler.OpenSearchSuggestionsHandler)
+	e.GET("/opensearch.xml", handler.OpenSearchHandler)
+
+	e.GET("/stats", handler.StatsHandler)
 
 	e.GET("/settings", handler.SettingsHandler)
+	e.POST("/settings/preferences", handler.SettingsPreferencesHandler)
+	e.POST("/settings/password", handler.SettingsPasswordHandler)
+	e.GET("/settings/2fa", handler.SettingsTwoFactorHandler)
+	e.POST("/settings/2fa", handler.SettingsTwoFactorEnableHandler)
+	e.POST("/settings/2fa/disable", handler.SettingsTwoFactorDisableHandler)
+	e.POST("/settings/2fa/recovery-codes", handler.SettingsRecoveryCodesHandler)
+	e.POST("/settings/tokens", handler.SettingsTokensCreateHandler)
+	e.POST("/settings/tokens/:id/revoke", handler.SettingsTokenRevokeHandler)
+	e.POST("/settings/feeds", handler.SettingsFeedTokensCreateHandler)
+	e.POST("/settings/feeds/:id/revoke", handler.SettingsFeedTokenRevokeHandler)
+	e.POST("/settings/webhooks", handler.SettingsWebhooksCreateHandler)
+	e.POST("/settings/webhooks/:id/delete", handler.SettingsWebhookDeleteHandler)
+	e.GET("/settings/sessions", handler.SettingsSessionsHandler)
+	e.POST("/settings/sessions/revoke", handler.SettingsSessionsRevokeAllHandler)
+	e.POST("/settings/sessions/:id/revoke", handler.SettingsSessionRevokeHandler)
+
+	e.GET("/api/tags/suggest", handler.TagsSuggestHandler)
+	e.GET("/api/bookmarks/suggest", handler.BookmarksSuggestHandler)
+
+	e.GET("/api/openapi.json", handler.OpenAPIHandler)
+
+	api := e.Group("/api/v1", middleware.TokenAuthMiddleware)
+	api.GET("/bookmarks", handler.APIBookmarksListHandler)
+	api.POST("/bookmarks", handler.APIBookmarksCreateHandler)
+	api.GET("/bookmarks/search", handler.APIBookmarksSearchHandler)
+	api.GET("/bookmarks/:id", handler.APIBookmarkShowHandler)
+	api.PUT("/bookmarks/:id", handler.APIBookmarkUpdateHandler)
+	api.DELETE("/bookmarks/:id", handler.APIBookmarkDeleteHandler)
+	api.GET("/tags", handler.APITagsListHandler)
+	api.GET("/tags/search", handler.APITagsSearchHandler)
+
+	pinboard := e.Group("/v1", middleware.PinboardAuthMiddleware)
+	pinboard.GET("/posts/add", handler.PinboardPostsAddHandler)
+	pinboard.GET("/posts/delete", handler.PinboardPostsDeleteHandler)
+	pinboard.GET("/posts/get", handler.PinboardPostsGetHandler)
+	pinboard.GET("/posts/recent", handler.PinboardPostsRecentHandler)
+	pinboard.GET("/posts/all", handler.PinboardPostsAllHandler)
+	pinboard.GET("/tags/get", handler.PinboardTagsGetHandler)
+	pinboard.GET("/tags/rename", handler.PinboardTagsRenameHandler)
+	pinboard.GET("/user/api_token", handler.PinboardUserAPITokenHandler)
+
+	e.GET("/.well-known/oauth-authorization-server", handler.IndieAuthMetadataHandler)
+	e.GET("/auth", handler.AuthorizeViewHandler)
+	e.POST("/auth", handler.AuthorizationCodeHandler)
+	e.POST("/auth/confirm", handler.AuthorizeHandler)
+	e.POST("/token", handler.TokenHandler)
+	e.POST("/token/revoke", handler.TokenRevokeHandler)
+
+	e.GET("/micropub", handler.MicropubQueryHandler, middleware.MicropubAuthMiddleware)
+	e.POST("/micropub", handler.MicropubHandler, middleware.MicropubAuthMiddleware)
+
+	e.GET("/.well-known/webfinger", handler.WebFingerHandler)
+	e.GET("/actor", handler.ActorHandler)
+	e.POST("/inbox", handler.InboxHandler)
+	e.GET("/outbox", handler.OutboxHandler)
+	e.GET("/followers", handler.FollowersHandler)
 
 	e.GET("/login", handler.LoginViewHandler)
 	e.POST("/login", handler.LoginHandler)
-	e.GET("/logout", handler.LogoutHandler)
+	e.POST("/login/2fa", handler.LoginTwoFactorHandler)
+	e.POST("/logout", handler.LogoutHandler)
 
 	staticHandler, err := handler.NewStaticHandler()
 	if err != nil {
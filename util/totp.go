@@ -0,0 +1,92 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters as described in RFC 6238, these are the defaults every
+// authenticator app supports.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+
+	// totpSkew is the number of periods before and after the current one
+	// in which a code is still accepted, to allow for clock drift.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random base32 encoded secret.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPCounter returns the time step t falls into.
+func TOTPCounter(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode returns the code for secret at the given time step.
+func TOTPCode(secret string, counter int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%uint32(math.Pow10(TOTPDigits))), nil
+}
+
+// ValidateTOTP checks code against secret at time t and returns the time
+// step it matched, callers should reject steps that have been used before.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if secret == "" || len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPCounter(t)
+	for counter := current - totpSkew; counter <= current+totpSkew; counter++ {
+		expected, err := TOTPCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI returns the otpauth URI authenticator apps scan from a QR code.
+func TOTPURI(secret, issuer, account string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
@@ -0,0 +1,68 @@
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chdorner/submarine/util"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 test key from RFC 6238, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	other, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+}
+
+func TestTOTPCode(t *testing.T) {
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := util.TOTPCode(rfcSecret, util.TOTPCounter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, expected, code)
+	}
+
+	_, err := util.TOTPCode("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	counter := util.TOTPCounter(now)
+
+	matched, ok := util.ValidateTOTP(rfcSecret, "005924", now)
+	require.True(t, ok)
+	require.Equal(t, counter, matched)
+
+	matched, ok = util.ValidateTOTP(rfcSecret, "005 924", now.Add(util.TOTPPeriod))
+	require.True(t, ok)
+	require.Equal(t, counter, matched)
+
+	_, ok = util.ValidateTOTP(rfcSecret, "005924", now.Add(2*util.TOTPPeriod))
+	require.False(t, ok)
+
+	_, ok = util.ValidateTOTP(rfcSecret, "005925", now)
+	require.False(t, ok)
+
+	_, ok = util.ValidateTOTP(rfcSecret, "", now)
+	require.False(t, ok)
+
+	_, ok = util.ValidateTOTP("", "328482", now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := util.TOTPURI(rfcSecret, "submarine", "bookmarks.example.com")
+	require.Equal(t, "otpauth://totp/submarine:bookmarks.example.com?digits=6&issuer=submarine&period=30&secret="+rfcSecret, uri)
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/chdorner/submarine/data"
+)
+
+const (
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts = 8
+
+	initialBackoff = time.Minute
+	maxBackoff     = 6 * time.Hour
+	batchSize      = 50
+)
+
+// Worker sends pending webhook deliveries and reschedules failed attempts
+// with exponential backoff.
+type Worker struct {
+	repo     *data.WebhookRepository
+	client   *http.Client
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		repo:     data.NewWebhookRepository(db),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: 15 * time.Second,
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the clock used to schedule attempts.
+func (w *Worker) SetClock(now func() time.Time) {
+	w.now = now
+}
+
+// Run delivers due webhooks periodically until the context is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		err := w.DeliverDue()
+		if err != nil {
+			logrus.WithError(err).Error("failed to deliver webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery that is due.
+func (w *Worker) DeliverDue() error {
+	deliveries, err := w.repo.DueDeliveries(w.now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		w.attempt(delivery)
+		err = w.repo.SaveAttempt(delivery)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) attempt(delivery *data.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := w.send(delivery)
+	delivery.LastStatus = status
+
+	now := w.now()
+	if err == nil {
+		delivery.State = data.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	logrus.
+		WithError(err).
+		WithField("delivery", delivery.ID).
+		WithField("attempts", delivery.Attempts).
+		Warn("webhook delivery failed")
+
+	if delivery.Attempts >= MaxAttempts {
+		delivery.State = data.WebhookDeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+func (w *Worker) send(delivery *data.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "submarine-webhook")
+	req.Header.Set("X-Submarine-Event", string(delivery.Event))
+	req.Header.Set("X-Submarine-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Submarine-Signature", "sha256="+Sign(delivery.Webhook.Secret, payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload, receivers can
+// verify it against the X-Submarine-Signature header.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt, doubling from one
+// minute up to six hours.
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,120 @@
+package webhook_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/chdorner/submarine/data"
+	"github.com/chdorner/submarine/test"
+	"github.com/chdorner/submarine/webhook"
+)
+
+func TestWorkerDeliverDue(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook, err := repo.Create(data.WebhookForm{URL: server.URL})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+
+	err = webhook.NewWorker(db).DeliverDue()
+	require.NoError(t, err)
+
+	require.NotNil(t, received)
+	require.Equal(t, http.MethodPost, received.Method)
+	require.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	require.Equal(t, "bookmark.created", received.Header.Get("X-Submarine-Event"))
+	require.Equal(t, "1", received.Header.Get("X-Submarine-Delivery"))
+	require.Equal(t, "sha256="+webhook.Sign(hook.Secret, body), received.Header.Get("X-Submarine-Signature"))
+	require.Contains(t, string(body), `"url":"https://example.com"`)
+
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, data.WebhookDeliveryDelivered, deliveries[0].State)
+	require.Equal(t, 1, deliveries[0].Attempts)
+	require.Equal(t, http.StatusNoContent, deliveries[0].LastStatus)
+	require.NotNil(t, deliveries[0].DeliveredAt)
+
+	// delivered webhooks are not sent again
+	received = nil
+	err = webhook.NewWorker(db).DeliverDue()
+	require.NoError(t, err)
+	require.Nil(t, received)
+}
+
+func TestWorkerRetries(t *testing.T) {
+	db, cleanup := test.InitTestDB(t)
+	defer cleanup()
+	repo := data.NewWebhookRepository(db)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := repo.Create(data.WebhookForm{URL: server.URL})
+	require.NoError(t, err)
+	_, err = data.NewBookmarkRepository(db).Create(data.BookmarkForm{URL: "https://example.com"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	worker := webhook.NewWorker(db)
+	worker.SetClock(func() time.Time { return now })
+
+	require.NoError(t, worker.DeliverDue())
+	require.Equal(t, 1, requests)
+
+	deliveries, err := repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Equal(t, data.WebhookDeliveryPending, deliveries[0].State)
+	require.Equal(t, 1, deliveries[0].Attempts)
+	require.Equal(t, http.StatusInternalServerError, deliveries[0].LastStatus)
+	require.Equal(t, "unexpected status code 500", deliveries[0].LastError)
+	require.WithinDuration(t, now.Add(time.Minute), deliveries[0].NextAttemptAt, time.Second)
+
+	// not retried before the backoff passed
+	require.NoError(t, worker.DeliverDue())
+	require.Equal(t, 1, requests)
+
+	for i := 1; i < webhook.MaxAttempts; i++ {
+		now = now.Add(webhook.Backoff(i))
+		require.NoError(t, worker.DeliverDue())
+	}
+	require.Equal(t, webhook.MaxAttempts, requests)
+
+	deliveries, err = repo.Deliveries(10)
+	require.NoError(t, err)
+	require.Equal(t, data.WebhookDeliveryFailed, deliveries[0].State)
+	require.Equal(t, webhook.MaxAttempts, deliveries[0].Attempts)
+
+	// failed deliveries are given up
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, worker.DeliverDue())
+	require.Equal(t, webhook.MaxAttempts, requests)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, time.Minute, webhook.Backoff(1))
+	require.Equal(t, 2*time.Minute, webhook.Backoff(2))
+	require.Equal(t, 4*time.Minute, webhook.Backoff(3))
+	require.Equal(t, 6*time.Hour, webhook.Backoff(20))
+}